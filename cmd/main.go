@@ -1,12 +1,14 @@
 package main
 
 import (
+	"context"
 	"log"
 	"stock-management/config"
 	"stock-management/internal/domain/repositories"
 	"stock-management/internal/domain/services"
 	"stock-management/internal/domain/usecases"
 	"stock-management/internal/infrastructure/database"
+	"stock-management/internal/infrastructure/labels"
 	"stock-management/internal/infrastructure/server"
 )
 
@@ -25,15 +27,78 @@ func main() {
 
 	// Initialize repositories
 	userRepo := repositories.NewUserRepository(db)
-	stockRepo := repositories.NewStockRepository(db)
+	refreshTokenRepo := repositories.NewRefreshTokenRepository(db)
+	stockRepo := repositories.NewStockRepository(db, cfg.CostingMethod)
+	transferRepo := repositories.NewTransferRepository(db, cfg.CostingMethod)
+	idempotencyRepo := repositories.NewIdempotencyRepository(db)
+	reservationRepo := repositories.NewReservationRepository(db)
+	alertRepo := repositories.NewAlertRepository(db)
+	purchaseRepo := repositories.NewPurchaseRepository(db, cfg.CostingMethod)
+	salesRepo := repositories.NewSalesRepository(db, cfg.CostingMethod)
+	returnRepo := repositories.NewReturnRepository(db, cfg.CostingMethod)
+	countRepo := repositories.NewCountRepository(db, cfg.CostingMethod)
+	adjustmentRepo := repositories.NewAdjustmentRepository(db, cfg.CostingMethod)
+	valuationRepo := repositories.NewValuationRepository(db, cfg.CostingMethod)
+	periodRepo := repositories.NewPeriodRepository(db)
+	variantRepo := repositories.NewVariantRepository(db)
+	kitRepo := repositories.NewKitRepository(db, cfg.CostingMethod)
+	barcodeRepo := repositories.NewBarcodeRepository(db)
 
 	// Initialize services
-	jwtService, err := services.NewJWTService(cfg.JWTSecret, "stock", 6400)
-	authService := usecases.NewAuthService(userRepo, jwtService)
-	stockService := usecases.NewStockService(stockRepo)
+	jwtService, err := services.NewJWTService(cfg.JWTSecret, "stock", cfg.AccessTokenTTL)
+	if err != nil {
+		log.Fatalf("Failed to initialize JWT service: %v", err)
+	}
+	webhookService := services.NewWebhookService(cfg.AlertWebhookURL, cfg.AlertWebhookSecret)
+	labelService := labels.NewLabelService()
+	authService := usecases.NewAuthService(userRepo, refreshTokenRepo, jwtService, cfg.RefreshTokenTTL)
+	if admin, err := authService.EnsureAdmin(context.Background(), cfg.AdminUsername); err != nil {
+		log.Fatalf("Failed to set up admin user: %v", err)
+	} else if admin != nil {
+		log.Printf("Promoted %s to admin", admin.Username)
+	}
+	alertService := usecases.NewAlertService(alertRepo, stockRepo, webhookService)
+	stockService := usecases.NewStockService(stockRepo, periodRepo, alertService)
+	transferService := usecases.NewTransferService(transferRepo, stockRepo, alertService)
+	idempotencyService := usecases.NewIdempotencyService(idempotencyRepo)
+	reservationService := usecases.NewReservationService(reservationRepo, stockRepo, alertService)
+	purchaseService := usecases.NewPurchaseService(purchaseRepo, stockRepo, alertService)
+	salesService := usecases.NewSalesService(salesRepo, stockRepo, cfg.SalesReservationTTL, alertService)
+	returnService := usecases.NewReturnService(returnRepo, stockRepo, alertService)
+	countService := usecases.NewCountService(countRepo, stockRepo, alertService)
+	adjustmentService := usecases.NewAdjustmentService(adjustmentRepo, stockRepo, usecases.AdjustmentThresholds{
+		Quantity: cfg.AdjustmentApprovalQuantity,
+		Value:    cfg.AdjustmentApprovalValue,
+	}, alertService)
+	valuationService := usecases.NewValuationService(valuationRepo)
+	periodService := usecases.NewPeriodService(periodRepo)
+	variantService := usecases.NewVariantService(variantRepo, stockRepo)
+	kitService := usecases.NewKitService(kitRepo, stockRepo, alertService)
+	barcodeService := usecases.NewBarcodeService(barcodeRepo, stockRepo, labelService)
+
+	// Start background jobs
+	go reservationService.RunExpirySweeper(context.Background(), cfg.ReservationSweepInterval)
+	go periodService.RunSnapshotJob(context.Background(), cfg.SnapshotInterval)
 
 	// Initialize and start the server
-	srv := server.NewServer(db, authService, stockService, jwtService)
+	srv := server.NewServer(db, jwtService, server.Services{
+		Auth:        authService,
+		Stock:       stockService,
+		Transfer:    transferService,
+		Idempotency: idempotencyService,
+		Reservation: reservationService,
+		Alert:       alertService,
+		Purchase:    purchaseService,
+		Sales:       salesService,
+		Return:      returnService,
+		Count:       countService,
+		Adjustment:  adjustmentService,
+		Valuation:   valuationService,
+		Period:      periodService,
+		Variant:     variantService,
+		Kit:         kitService,
+		Barcode:     barcodeService,
+	})
 	if err := srv.Start(); err != nil {
 		log.Fatalf("Failed to start server: %v", err)
 	}
@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"stock-management/internal/domain/models"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,6 +16,28 @@ type Config struct {
 	DBUser    string
 	DBPass    string
 	DBName    string
+
+	// AdminUsername is promoted to admin at startup when no admin exists,
+	// and must be set then unless there are no users yet
+	AdminUsername string
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	ReservationSweepInterval time.Duration
+	SalesReservationTTL      time.Duration
+	SnapshotInterval         time.Duration
+
+	AlertWebhookURL    string
+	AlertWebhookSecret string
+
+	// Adjustments above either threshold wait for manager approval; with a
+	// threshold of zero every adjustment does
+	AdjustmentApprovalQuantity models.Quantity
+	AdjustmentApprovalValue    models.Money
+
+	// CostingMethod values products that do not choose one: fifo or average
+	CostingMethod string
 }
 
 func LoadConfig() (*Config, error) {
@@ -24,7 +49,101 @@ func LoadConfig() (*Config, error) {
 		DBUser:    os.Getenv("DB_USER"),
 		DBPass:    os.Getenv("DB_PASS"),
 		DBName:    os.Getenv("DB_NAME"),
+
+		AdminUsername: os.Getenv("ADMIN_USERNAME"),
+
+		AlertWebhookURL:    os.Getenv("ALERT_WEBHOOK_URL"),
+		AlertWebhookSecret: os.Getenv("ALERT_WEBHOOK_SECRET"),
+
+		CostingMethod: os.Getenv("COSTING_METHOD"),
+	}
+	switch cfg.CostingMethod {
+	case "":
+		cfg.CostingMethod = models.CostingFIFO
+	case models.CostingFIFO, models.CostingAverage:
+	default:
+		return nil, fmt.Errorf("invalid COSTING_METHOD: must be %s or %s", models.CostingFIFO, models.CostingAverage)
+	}
+
+	var err error
+	if cfg.AccessTokenTTL, err = durationEnv("ACCESS_TOKEN_TTL", 15*time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.RefreshTokenTTL, err = durationEnv("REFRESH_TOKEN_TTL", 7*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.ReservationSweepInterval, err = durationEnv("RESERVATION_SWEEP_INTERVAL", time.Minute); err != nil {
+		return nil, err
+	}
+	if cfg.SalesReservationTTL, err = durationEnv("SALES_RESERVATION_TTL", 30*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.SnapshotInterval, err = durationEnv("SNAPSHOT_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.SnapshotInterval > 24*time.Hour {
+		// A longer interval would skip days' snapshots
+		return nil, fmt.Errorf("invalid SNAPSHOT_INTERVAL: must be at most 24h")
+	}
+	if cfg.AdjustmentApprovalQuantity, err = quantityEnv("ADJUSTMENT_APPROVAL_QUANTITY", models.Units(100)); err != nil {
+		return nil, err
+	}
+	if cfg.AdjustmentApprovalQuantity < 0 {
+		return nil, fmt.Errorf("invalid ADJUSTMENT_APPROVAL_QUANTITY: must not be negative")
+	}
+	// 1000.00 in ten-thousandths
+	if cfg.AdjustmentApprovalValue, err = moneyEnv("ADJUSTMENT_APPROVAL_VALUE", 1000_0000); err != nil {
+		return nil, err
+	}
+	if cfg.AdjustmentApprovalValue < 0 {
+		return nil, fmt.Errorf("invalid ADJUSTMENT_APPROVAL_VALUE: must not be negative")
 	}
 
 	return cfg, nil
 }
+
+// durationEnv reads a Go duration string such as "15m" or "168h" from the
+// environment, falling back to def when the variable is unset. Durations
+// must be positive; intervals of zero would make tickers panic.
+func durationEnv(key string, def time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s: must be positive", key)
+	}
+	return d, nil
+}
+
+// quantityEnv reads a quantity from the environment, falling back to def
+// when the variable is unset.
+func quantityEnv(key string, def models.Quantity) (models.Quantity, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	q, err := models.ParseQuantity(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return q, nil
+}
+
+// moneyEnv reads an amount of money from the environment, falling back to
+// def when the variable is unset.
+func moneyEnv(key string, def models.Money) (models.Money, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return def, nil
+	}
+	m, err := models.ParseMoney(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return m, nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"stock-management/internal/domain/models"
+	"testing"
+)
+
+// TestAdjustmentApprovalThresholds checks that approval is on by default
+// and that the quantity threshold may be fractional.
+func TestAdjustmentApprovalThresholds(t *testing.T) {
+	t.Setenv("ADJUSTMENT_APPROVAL_QUANTITY", "")
+	t.Setenv("ADJUSTMENT_APPROVAL_VALUE", "")
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AdjustmentApprovalQuantity != models.Units(100) || cfg.AdjustmentApprovalValue != 1000_0000 {
+		t.Errorf("defaults are %s and %s, want 100 and 1000", cfg.AdjustmentApprovalQuantity, cfg.AdjustmentApprovalValue)
+	}
+
+	t.Setenv("ADJUSTMENT_APPROVAL_QUANTITY", "2.5")
+	t.Setenv("ADJUSTMENT_APPROVAL_VALUE", "0")
+	if cfg, err = LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AdjustmentApprovalQuantity != 2500 || cfg.AdjustmentApprovalValue != 0 {
+		t.Errorf("thresholds are %s and %s, want 2.5 and 0", cfg.AdjustmentApprovalQuantity, cfg.AdjustmentApprovalValue)
+	}
+
+	for _, invalid := range []string{"-1", "0.0001", "many"} {
+		t.Setenv("ADJUSTMENT_APPROVAL_QUANTITY", invalid)
+		if _, err := LoadConfig(); err == nil {
+			t.Errorf("ADJUSTMENT_APPROVAL_QUANTITY=%s was accepted", invalid)
+		}
+	}
+}
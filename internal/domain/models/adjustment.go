@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+const (
+	AdjustmentStatusPending  = "pending"
+	AdjustmentStatusApproved = "approved"
+	AdjustmentStatusRejected = "rejected"
+)
+
+// AdjustmentRequest is a manual correction of the stock of a product at a
+// location. Quantity is signed: positive adds stock, negative removes it.
+// Small adjustments are approved and posted straight away; those above the
+// approval thresholds wait for a manager, and only approval writes the
+// movement.
+type AdjustmentRequest struct {
+	ID            uint               `gorm:"primaryKey" json:"id"`
+	ProductID     uint               `gorm:"column:product_id;not null;index" json:"productId"`
+	Product       *Product           `json:"product,omitempty"`
+	LocationID    uint               `gorm:"column:location_id;not null" json:"locationId"`
+	Location      *Location          `json:"location,omitempty"`
+	Quantity      Quantity           `gorm:"not null" json:"quantity"`
+	ReasonCodeID  uint               `gorm:"column:reason_code_id;not null" json:"reasonCodeId"`
+	ReasonCode    *ReasonCode        `json:"reasonCode,omitempty"`
+	Notes         string             `gorm:"column:notes" json:"notes"`
+	LotNumber     string             `gorm:"column:lot_number" json:"lotNumber,omitempty"`
+	ExpiryDate    *time.Time         `json:"expiryDate,omitempty"`
+	Serials       []AdjustmentSerial `gorm:"foreignKey:AdjustmentRequestID" json:"serials,omitempty"`
+	Value         Money              `gorm:"not null;default:0" json:"value"`
+	Status        string             `gorm:"type:varchar(20);not null;index" json:"status"`
+	RequestedByID uint               `gorm:"column:requested_by_id" json:"requestedById"`
+	ReviewedByID  *uint              `gorm:"column:reviewed_by_id" json:"reviewedById"`
+	ReviewedAt    *time.Time         `json:"reviewedAt"`
+	ReviewNotes   string             `gorm:"column:review_notes" json:"reviewNotes"`
+	MovementID    *uint              `gorm:"column:movement_id" json:"movementId"`
+	CreatedAt     time.Time          `json:"createdAt"`
+	UpdatedAt     time.Time          `json:"updatedAt"`
+}
+
+// AdjustmentSerial names a unit adjusted in or out for serial-tracked products.
+type AdjustmentSerial struct {
+	ID                  uint   `gorm:"primaryKey" json:"-"`
+	AdjustmentRequestID uint   `gorm:"column:adjustment_request_id;not null;index" json:"-"`
+	Serial              string `gorm:"column:serial;not null" json:"serial"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// ReorderRule sets the min/max levels of a product, either at one location or,
+// when LocationID is nil, summed over all locations. Falling to MinQuantity
+// (the reorder point) raises an alert suggesting to order up to MaxQuantity.
+// A product has at most one rule per location and one for all locations.
+type ReorderRule struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProductID   uint      `gorm:"column:product_id;not null;index;uniqueIndex:idx_reorder_rule_location,where:location_id IS NOT NULL;uniqueIndex:idx_reorder_rule_all_locations,where:location_id IS NULL" json:"productId"`
+	Product     *Product  `json:"product,omitempty"`
+	LocationID  *uint     `gorm:"column:location_id;index;uniqueIndex:idx_reorder_rule_location,where:location_id IS NOT NULL" json:"locationId"`
+	Location    *Location `json:"location,omitempty"`
+	MinQuantity Quantity  `gorm:"not null" json:"minQuantity"`
+	MaxQuantity Quantity  `gorm:"not null" json:"maxQuantity"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+const (
+	AlertStatusOpen     = "open"
+	AlertStatusResolved = "resolved"
+)
+
+// StockAlert is raised once when a reorder rule is breached and resolved when
+// stock is back above the reorder point.
+type StockAlert struct {
+	ID               uint        `gorm:"primaryKey" json:"id"`
+	RuleID           uint        `gorm:"column:rule_id;not null;uniqueIndex:idx_stock_alert_open_rule,where:status = 'open'" json:"ruleId"`
+	Rule             ReorderRule `gorm:"foreignKey:RuleID" json:"-"`
+	ProductID        uint        `gorm:"column:product_id;not null;index" json:"productId"`
+	LocationID       *uint       `gorm:"column:location_id" json:"locationId"`
+	Status           string      `gorm:"type:varchar(20);not null;index" json:"status"`
+	Quantity         Quantity    `gorm:"not null" json:"quantity"`
+	MinQuantity      Quantity    `gorm:"not null" json:"minQuantity"`
+	SuggestedOrder   Quantity    `gorm:"not null" json:"suggestedOrder"`
+	DeliveredAt      *time.Time  `json:"deliveredAt"`
+	DeliveryAttempts int         `gorm:"not null;default:0" json:"deliveryAttempts"`
+	LastError        string      `json:"lastError,omitempty"`
+	ResolvedAt       *time.Time  `json:"resolvedAt"`
+	CreatedAt        time.Time   `json:"createdAt"`
+	UpdatedAt        time.Time   `json:"updatedAt"`
+}
+
+// LowStockDTO is a product currently at or below its reorder point.
+type LowStockDTO struct {
+	RuleID         uint     `json:"ruleId"`
+	ProductID      uint     `json:"productId"`
+	Name           string   `json:"name"`
+	SKU            string   `json:"sku"`
+	LocationID     *uint    `json:"locationId"`
+	LocationCode   string   `json:"locationCode,omitempty"`
+	Available      Quantity `json:"available"`
+	MinQuantity    Quantity `json:"minQuantity"`
+	MaxQuantity    Quantity `json:"maxQuantity"`
+	SuggestedOrder Quantity `json:"suggestedOrder"`
+}
+
+// AlertEvent is the payload delivered to the alert webhook.
+type AlertEvent struct {
+	Event string      `json:"event"` // "low_stock" or "low_stock_resolved"
+	Alert StockAlert  `json:"alert"`
+	Stock LowStockDTO `json:"stock"`
+}
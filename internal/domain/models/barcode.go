@@ -0,0 +1,106 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Barcode symbologies a product's barcodes can have. Internal codes are
+// printed as Code 128 but are only meaningful inside the company.
+const (
+	SymbologyEAN13    = "ean13"
+	SymbologyUPC      = "upc"
+	SymbologyCode128  = "code128"
+	SymbologyInternal = "internal"
+)
+
+// maxBarcodeLength keeps codes short enough to fit on a label.
+const maxBarcodeLength = 48
+
+// Barcode is one of the codes a product can be scanned by. A code belongs
+// to a single product. GTINs are stored as 13 digits, see Normalize.
+type Barcode struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	ProductID uint      `gorm:"column:product_id;not null;index" json:"productId"`
+	Code      string    `gorm:"column:code;not null;uniqueIndex" json:"code"`
+	Symbology string    `gorm:"type:varchar(10);not null" json:"symbology"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Validate checks the code against its symbology, including the check
+// digit of EAN-13 and UPC-A codes.
+func (b *Barcode) Validate() error {
+	if b.Code == "" {
+		return errors.New("barcode is required")
+	}
+	switch b.Symbology {
+	case SymbologyEAN13:
+		return validateGTIN(b.Code, 13, "EAN-13")
+	case SymbologyUPC:
+		return validateGTIN(b.Code, 12, "UPC-A")
+	case SymbologyCode128, SymbologyInternal:
+		if len(b.Code) > maxBarcodeLength {
+			return fmt.Errorf("barcode may have at most %d characters", maxBarcodeLength)
+		}
+		for _, r := range b.Code {
+			// Code 128 code set B covers printable ASCII
+			if r < ' ' || r > '~' {
+				return fmt.Errorf("barcode may only contain printable ASCII characters, not %q", r)
+			}
+		}
+		return nil
+	}
+	return errors.New("invalid symbology " + b.Symbology)
+}
+
+// Normalize brings a validated code into the form it is stored in. UPC-A
+// codes are kept as the equivalent EAN-13 with a leading zero, so one GTIN
+// has a single stored form whichever way it was entered.
+func (b *Barcode) Normalize() {
+	if b.Symbology == SymbologyUPC && len(b.Code) == 12 {
+		b.Code = "0" + b.Code
+	}
+}
+
+func validateGTIN(code string, length int, name string) error {
+	if len(code) != length || strings.Trim(code, "0123456789") != "" {
+		return fmt.Errorf("%s barcodes have %d digits", name, length)
+	}
+	if check := gtinCheckDigit(code[:length-1]); code[length-1] != check {
+		return fmt.Errorf("invalid %s check digit, expected %c", name, check)
+	}
+	return nil
+}
+
+// gtinCheckDigit computes the GS1 check digit of the digits before it:
+// counting from the right, digits are weighted 3, 1, 3, 1, ...
+func gtinCheckDigit(digits string) byte {
+	sum := 0
+	for i := len(digits) - 1; i >= 0; i-- {
+		digit := int(digits[i] - '0')
+		if (len(digits)-1-i)%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+// BarcodeCandidates returns the codes a scanned value may be stored as. A
+// UPC-A code is stored as a 13-digit EAN with a leading zero and scanners
+// read it either way, so both forms are tried.
+func BarcodeCandidates(scanned string) []string {
+	code := strings.TrimSpace(scanned)
+	candidates := []string{code}
+	if strings.Trim(code, "0123456789") == "" {
+		switch {
+		case len(code) == 12:
+			candidates = append(candidates, "0"+code)
+		case len(code) == 13 && code[0] == '0':
+			candidates = append(candidates, code[1:])
+		}
+	}
+	return candidates
+}
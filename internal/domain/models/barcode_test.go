@@ -0,0 +1,44 @@
+package models_test
+
+import (
+	"stock-management/internal/domain/models"
+	"strings"
+	"testing"
+)
+
+// TestBarcodeCheckDigit validates GS1 codes whose check digits are known.
+func TestBarcodeCheckDigit(t *testing.T) {
+	tests := []struct {
+		code, symbology, wantErr string
+	}{
+		{"4006381333931", models.SymbologyEAN13, ""},
+		{"4006381333932", models.SymbologyEAN13, "expected 1"},
+		{"036000291452", models.SymbologyUPC, ""},
+		{"036000291453", models.SymbologyUPC, "expected 2"},
+		{"36000291452", models.SymbologyUPC, "12 digits"},
+	}
+	for _, tt := range tests {
+		barcode := models.Barcode{Code: tt.code, Symbology: tt.symbology}
+		err := barcode.Validate()
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: %v", tt.code, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("%s: error %v, want one containing %q", tt.code, err, tt.wantErr)
+		}
+	}
+}
+
+func TestBarcodeNormalize(t *testing.T) {
+	upc := models.Barcode{Code: "036000291452", Symbology: models.SymbologyUPC}
+	upc.Normalize()
+	if upc.Code != "0036000291452" {
+		t.Errorf("UPC-A stored as %s, want 0036000291452", upc.Code)
+	}
+
+	ean := models.Barcode{Code: "4006381333931", Symbology: models.SymbologyEAN13}
+	ean.Normalize()
+	if ean.Code != "4006381333931" {
+		t.Errorf("EAN-13 stored as %s, want it unchanged", ean.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package models
+
+import "time"
+
+const (
+	CountStatusCounting  = "counting"
+	CountStatusReview    = "review"
+	CountStatusPosted    = "posted"
+	CountStatusCancelled = "cancelled"
+)
+
+// CountSession is a physical stock count of one location, one category or
+// both. Opening it snapshots the expected quantities; counters then record
+// what they find, which refreshes the expected quantity from the books, and
+// after review the approved variances are posted as count corrections. Only
+// products without lot or serial tracking are counted this way; tracked
+// stock within the scope is listed as excluded.
+type CountSession struct {
+	ID          uint        `gorm:"primaryKey" json:"id"`
+	LocationID  *uint       `gorm:"column:location_id" json:"locationId"`
+	Location    *Location   `json:"location,omitempty"`
+	CategoryID  *uint       `gorm:"column:category_id" json:"categoryId"`
+	Category    *Category   `json:"category,omitempty"`
+	Status      string      `gorm:"type:varchar(20);not null;index" json:"status"`
+	Notes       string      `gorm:"column:notes" json:"notes"`
+	CreatedByID uint        `gorm:"column:created_by_id" json:"createdById"`
+	PostedByID  *uint       `gorm:"column:posted_by_id" json:"postedById"`
+	PostedAt    *time.Time  `json:"postedAt"`
+	Lines       []CountLine `gorm:"foreignKey:SessionID" json:"lines"`
+	// Excluded is the lot- and serial-tracked stock within the scope of an
+	// open count, which has to be counted by lot or serial instead
+	Excluded  []CountExclusion `gorm:"-" json:"excluded,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// CountLine is one product at one location within a count. The latest count
+// entry is the counted quantity; earlier entries are kept as recount history.
+type CountLine struct {
+	ID               uint         `gorm:"primaryKey" json:"id"`
+	SessionID        uint         `gorm:"column:session_id;not null;uniqueIndex:idx_count_line_stock" json:"sessionId"`
+	ProductID        uint         `gorm:"column:product_id;not null;uniqueIndex:idx_count_line_stock" json:"productId"`
+	Product          *Product     `json:"product,omitempty"`
+	LocationID       uint         `gorm:"column:location_id;not null;uniqueIndex:idx_count_line_stock" json:"locationId"`
+	Location         *Location    `json:"location,omitempty"`
+	ExpectedQuantity Quantity     `gorm:"not null" json:"expectedQuantity"`
+	CountedQuantity  *Quantity    `json:"countedQuantity"`
+	Approved         bool         `gorm:"not null;default:false" json:"approved"`
+	Entries          []CountEntry `gorm:"foreignKey:CountLineID" json:"entries,omitempty"`
+}
+
+// Variance is counted minus expected, zero while the line is uncounted.
+func (l *CountLine) Variance() Quantity {
+	if l.CountedQuantity == nil {
+		return 0
+	}
+	return *l.CountedQuantity - l.ExpectedQuantity
+}
+
+// CountExclusion is stock of a lot- or serial-tracked product at one
+// location that a count leaves out.
+type CountExclusion struct {
+	ProductID    uint     `json:"productId"`
+	LocationID   uint     `json:"locationId"`
+	TrackingMode string   `json:"trackingMode"`
+	Quantity     Quantity `json:"quantity"`
+}
+
+// CountEntry is one counter's count of a line.
+type CountEntry struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	CountLineID uint      `gorm:"column:count_line_id;not null;index" json:"countLineId"`
+	UserID      uint      `gorm:"column:user_id;not null" json:"userId"`
+	Quantity    Quantity  `gorm:"not null" json:"quantity"`
+	CountedAt   time.Time `json:"countedAt"`
+}
+
+// CountVarianceDTO is a counted line whose quantity differs from the books.
+type CountVarianceDTO struct {
+	LineID           uint     `json:"lineId"`
+	ProductID        uint     `json:"productId"`
+	ProductName      string   `json:"productName"`
+	SKU              string   `json:"sku"`
+	LocationID       uint     `json:"locationId"`
+	LocationCode     string   `json:"locationCode"`
+	ExpectedQuantity Quantity `json:"expectedQuantity"`
+	CountedQuantity  Quantity `json:"countedQuantity"`
+	Variance         Quantity `json:"variance"`
+	Approved         bool     `json:"approved"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers the outcome of a request sent with an
+// Idempotency-Key header so a retry of the same request replays the original
+// response instead of being applied twice.
+type IdempotencyKey struct {
+	ID             uint   `gorm:"primaryKey"`
+	UserID         uint   `gorm:"not null;uniqueIndex:idx_idempotency_user_key"`
+	Key            string `gorm:"type:varchar(255);not null;uniqueIndex:idx_idempotency_user_key"`
+	Endpoint       string `gorm:"not null"`
+	RequestHash    string `gorm:"type:varchar(64);not null"`
+	Completed      bool   `gorm:"not null;default:false"`
+	ResponseStatus int
+	ResponseBody   []byte
+	// ExpiresAt ends the lease of a request still in progress, which the
+	// request renews while it runs, and the life of the stored response
+	// once completed
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -0,0 +1,71 @@
+package models
+
+import "time"
+
+// KitComponent is a line of a kit's bill of materials: Quantity units of
+// the component go into one unit of the kit.
+type KitComponent struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	KitID       uint      `gorm:"column:kit_id;not null;uniqueIndex:idx_kit_component" json:"kitId"`
+	ComponentID uint      `gorm:"column:component_id;not null;uniqueIndex:idx_kit_component;index" json:"componentId"`
+	Component   *Product  `json:"component,omitempty"`
+	Quantity    Quantity  `gorm:"not null" json:"quantity"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+const (
+	KitBuildAssembly    = "assembly"
+	KitBuildDisassembly = "disassembly"
+)
+
+// KitBuild is one assembly or disassembly of a kit at a location. Assembly
+// consumes the components and produces the kit; disassembly does the
+// reverse. The movements it made link back to it.
+type KitBuild struct {
+	ID         uint            `gorm:"primaryKey" json:"id"`
+	KitID      uint            `gorm:"column:kit_id;not null;index" json:"kitId"`
+	Kit        *Product        `json:"kit,omitempty"`
+	LocationID uint            `gorm:"column:location_id;not null" json:"locationId"`
+	Location   *Location       `json:"location,omitempty"`
+	Type       string          `gorm:"type:varchar(12);not null" json:"type"`
+	Quantity   Quantity        `gorm:"not null" json:"quantity"`
+	UserID     uint            `gorm:"column:user_id" json:"userId"`
+	Notes      string          `gorm:"column:notes" json:"notes"`
+	Movements  []StockMovement `gorm:"foreignKey:KitBuildID" json:"-"`
+	CreatedAt  time.Time       `json:"createdAt"`
+}
+
+// KitComponentDTO is a bill of materials line with the component's
+// available stock.
+type KitComponentDTO struct {
+	ComponentID uint     `json:"componentId"`
+	Name        string   `json:"name"`
+	SKU         string   `json:"sku"`
+	Quantity    Quantity `json:"quantity"`
+	Available   Quantity `json:"available"`
+	// Buildable is how many kits the component's available stock suffices for
+	Buildable Quantity `json:"buildable"`
+}
+
+// KitDTO is a kit's bill of materials. Buildable is how many kits can be
+// assembled from the available components, at one location or over all.
+type KitDTO struct {
+	KitID      uint              `json:"kitId"`
+	Name       string            `json:"name"`
+	SKU        string            `json:"sku"`
+	LocationID *uint             `json:"locationId,omitempty"`
+	Components []KitComponentDTO `json:"components"`
+	Buildable  Quantity          `json:"buildable"`
+}
+
+type KitBuildDTO struct {
+	ID         uint          `json:"id"`
+	KitID      uint          `json:"kitId"`
+	LocationID uint          `json:"locationId"`
+	Type       string        `json:"type"`
+	Quantity   Quantity      `json:"quantity"`
+	Notes      string        `json:"notes"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	Movements  []MovementDTO `json:"movements"`
+}
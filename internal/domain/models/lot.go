@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Lot is a production batch of a lot-tracked product.
+type Lot struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	ProductID  uint       `gorm:"column:product_id;not null;uniqueIndex:idx_lot_product_number" json:"productId"`
+	LotNumber  string     `gorm:"column:lot_number;not null;uniqueIndex:idx_lot_product_number" json:"lotNumber"`
+	ExpiryDate *time.Time `gorm:"column:expiry_date;index" json:"expiryDate"`
+	Stocks     []LotStock `gorm:"foreignKey:LotID" json:"stocks,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+}
+
+// LotStock is the quantity of one lot held at one location. Per location the
+// lot quantities of a product always add up to its Stock quantity.
+type LotStock struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	LotID      uint      `gorm:"column:lot_id;not null;uniqueIndex:idx_lot_stock_lot_location" json:"lotId"`
+	Lot        *Lot      `json:"lot,omitempty"`
+	LocationID uint      `gorm:"column:location_id;not null;uniqueIndex:idx_lot_stock_lot_location" json:"locationId"`
+	Location   *Location `json:"location,omitempty"`
+	Quantity   Quantity  `gorm:"not null" json:"quantity"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// StockMovementLot is the part of a movement that touched a single lot.
+type StockMovementLot struct {
+	ID              uint `gorm:"primaryKey"`
+	StockMovementID uint `gorm:"not null;index"`
+	LotID           uint `gorm:"not null;index"`
+	Lot             *Lot
+	Quantity        Quantity `gorm:"not null"`
+}
+
+type MovementLotDTO struct {
+	LotNumber  string     `json:"lotNumber"`
+	ExpiryDate *time.Time `json:"expiryDate"`
+	Quantity   Quantity   `json:"quantity"`
+}
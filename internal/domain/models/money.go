@@ -0,0 +1,144 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+)
+
+// Money is an amount of money in fixed point with MoneyDecimals decimal
+// places. It counts ten-thousandths of the currency unit in an int64, so
+// costs and values add up exactly. Money is a number in JSON and
+// numeric(19,4) in the database.
+type Money int64
+
+// MoneyDecimals is the precision unit costs and values are kept in.
+const MoneyDecimals = 4
+
+const moneyScale = 10000
+
+// ErrOutOfRange is returned when arithmetic on quantities or money would
+// overflow.
+var ErrOutOfRange = errors.New("amount is out of range")
+
+// ParseMoney reads a decimal such as "12", "-3" or "2.7499". More decimals
+// than MoneyDecimals are rejected rather than rounded away.
+func ParseMoney(s string) (Money, error) {
+	n, exact, err := parseDecimal(s, "amount", MoneyDecimals, math.MaxInt64)
+	if err != nil {
+		return 0, err
+	}
+	if !exact {
+		return 0, fmt.Errorf("amount %s has more than %d decimals", s, MoneyDecimals)
+	}
+	return Money(n), nil
+}
+
+// String formats the amount without trailing zeros, e.g. "2.75" or "12".
+func (m Money) String() string {
+	return formatDecimal(int64(m), MoneyDecimals)
+}
+
+// MulQuantity returns what quantity costs at the unit cost m, rounded half
+// away from zero.
+func (m Money) MulQuantity(quantity Quantity) (Money, error) {
+	n, err := mulDiv(int64(m), int64(quantity), quantityScale, math.MaxInt64)
+	return Money(n), err
+}
+
+// DivQuantity returns the unit cost of quantity costing m in total, rounded
+// half away from zero.
+func (m Money) DivQuantity(quantity Quantity) (Money, error) {
+	if quantity == 0 {
+		return 0, errors.New("cannot divide by a zero quantity")
+	}
+	n, err := mulDiv(int64(m), quantityScale, int64(quantity), math.MaxInt64)
+	return Money(n), err
+}
+
+// Share returns the part of m that weight makes up of total, rounded half
+// away from zero; it spreads a cost in proportion to weights.
+func (m Money) Share(weight, total int64) (Money, error) {
+	if total == 0 {
+		return 0, errors.New("cannot share by a zero total")
+	}
+	n, err := mulDiv(int64(m), weight, total, math.MaxInt64)
+	return Money(n), err
+}
+
+// mulDiv returns a*b/divisor rounded half away from zero, computed without
+// intermediate overflow. Results beyond limit are ErrOutOfRange.
+func mulDiv(a, b, divisor, limit int64) (int64, error) {
+	product := new(big.Int).Mul(big.NewInt(a), big.NewInt(b))
+	quotient, remainder := new(big.Int).QuoRem(product, big.NewInt(divisor), new(big.Int))
+
+	remainder.Abs(remainder).Lsh(remainder, 1)
+	if remainder.CmpAbs(big.NewInt(divisor)) >= 0 {
+		if product.Sign()*sign(divisor) < 0 {
+			quotient.Sub(quotient, big.NewInt(1))
+		} else {
+			quotient.Add(quotient, big.NewInt(1))
+		}
+	}
+
+	if !quotient.IsInt64() || quotient.CmpAbs(big.NewInt(limit)) > 0 {
+		return 0, ErrOutOfRange
+	}
+	return quotient.Int64(), nil
+}
+
+func sign(n int64) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding one.
+func (m *Money) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+
+	parsed, err := ParseMoney(text)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Value stores the amount as an exact decimal.
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// Scan reads a numeric column; products and aggregates with more decimals
+// are rounded.
+func (m *Money) Scan(src any) error {
+	n, err := scanDecimal(src, "amount", MoneyDecimals, math.MaxInt64)
+	if err != nil {
+		return err
+	}
+	*m = Money(n)
+	return nil
+}
+
+// GormDataType makes money columns numeric(19,4).
+func (Money) GormDataType() string {
+	return "numeric(19,4)"
+}
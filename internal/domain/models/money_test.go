@@ -0,0 +1,154 @@
+package models_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"stock-management/internal/domain/models"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    models.Money
+		wantErr bool
+	}{
+		{in: "12", want: 120000},
+		{in: "-3", want: -30000},
+		{in: "2.7499", want: 27499},
+		{in: "0.0001", want: 1},
+		{in: "922337203685477.5807", want: math.MaxInt64},
+		{in: "2.74999", wantErr: true},
+		{in: "922337203685477.5808", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "1,50", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := models.ParseMoney(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseMoney(%q) = %d, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// TestMoneyMulQuantity checks the cost of a quantity at a unit cost,
+// rounded half away from zero to a ten-thousandth.
+func TestMoneyMulQuantity(t *testing.T) {
+	tests := []struct {
+		cost     models.Money
+		quantity models.Quantity
+		want     models.Money
+		wantErr  error
+	}{
+		{cost: 12345, quantity: models.Units(3), want: 37035},
+		{cost: 12345, quantity: models.Units(-3), want: -37035},
+		{cost: 1, quantity: 500, want: 1},
+		{cost: -1, quantity: 500, want: -1},
+		{cost: 1, quantity: 499, want: 0},
+		{cost: 25000, quantity: 1333, want: 33325},
+		{cost: math.MaxInt64, quantity: models.Units(1), want: math.MaxInt64},
+		{cost: math.MaxInt64, quantity: models.Units(2), wantErr: models.ErrOutOfRange},
+		{cost: math.MinInt64, quantity: models.Units(1), wantErr: models.ErrOutOfRange},
+	}
+	for _, tt := range tests {
+		got, err := tt.cost.MulQuantity(tt.quantity)
+		if !errors.Is(err, tt.wantErr) || err == nil && got != tt.want {
+			t.Errorf("%s.MulQuantity(%s) = %s, %v, want %s, %v", tt.cost, tt.quantity, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMoneyDivQuantity(t *testing.T) {
+	tests := []struct {
+		total    models.Money
+		quantity models.Quantity
+		want     models.Money
+		wantErr  bool
+	}{
+		{total: 100000, quantity: models.Units(3), want: 33333},
+		{total: 200000, quantity: models.Units(3), want: 66667},
+		{total: -200000, quantity: models.Units(3), want: -66667},
+		{total: 200000, quantity: models.Units(-3), want: -66667},
+		{total: 10000, quantity: 500, want: 20000},
+		{total: 10000, quantity: 0, wantErr: true},
+		{total: math.MaxInt64, quantity: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.total.DivQuantity(tt.quantity)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s.DivQuantity(%s) = %s, want an error", tt.total, tt.quantity, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s.DivQuantity(%s) = %s, %v, want %s", tt.total, tt.quantity, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyShare(t *testing.T) {
+	tests := []struct {
+		total         models.Money
+		weight, whole int64
+		want          models.Money
+		wantErr       bool
+	}{
+		{total: 1000000, weight: 1, whole: 3, want: 333333},
+		{total: 1000000, weight: 2, whole: 3, want: 666667},
+		{total: -1000000, weight: 2, whole: 3, want: -666667},
+		{total: 2000000, weight: 1, whole: -3, want: -666667},
+		{total: 5, weight: 1, whole: 2, want: 3},
+		{total: -5, weight: 1, whole: 2, want: -3},
+		{total: math.MaxInt64, weight: math.MaxInt64, whole: math.MaxInt64, want: math.MaxInt64},
+		{total: math.MaxInt64, weight: 2, whole: 1, wantErr: true},
+		{total: 1000000, weight: 1, whole: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.total.Share(tt.weight, tt.whole)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s.Share(%d, %d) = %s, want an error", tt.total, tt.weight, tt.whole, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s.Share(%d, %d) = %s, %v, want %s", tt.total, tt.weight, tt.whole, got, err, tt.want)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	type cost struct {
+		UnitCost models.Money `json:"unitCost"`
+	}
+	data, err := json.Marshal(cost{UnitCost: -27499})
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(data) != `{"unitCost":-2.7499}` {
+		t.Errorf("marshal = %s", data)
+	}
+	var out cost
+	if err := json.Unmarshal(data, &out); err != nil || out.UnitCost != -27499 {
+		t.Errorf("round trip = %s, %v, want -2.7499", out.UnitCost, err)
+	}
+	if err := json.Unmarshal([]byte(`{"unitCost":"3.5"}`), &out); err != nil || out.UnitCost != 35000 {
+		t.Errorf("unmarshal string = %s, %v, want 3.5", out.UnitCost, err)
+	}
+	if err := json.Unmarshal([]byte(`{"unitCost":0.00001}`), &out); err == nil {
+		t.Errorf("unmarshal 0.00001 = %s, want an error", out.UnitCost)
+	}
+
+	var scanned models.Money
+	if err := scanned.Scan("1.23455"); err != nil || scanned != 12346 {
+		t.Errorf("Scan(1.23455) = %s, %v, want 1.2346", scanned, err)
+	}
+}
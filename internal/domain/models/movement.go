@@ -0,0 +1,77 @@
+package models
+
+import "time"
+
+// MovementType is the kind of change a stock movement records.
+type MovementType string
+
+const (
+	MovementReceipt         MovementType = "receipt"
+	MovementIssue           MovementType = "issue"
+	MovementAdjustment      MovementType = "adjustment"
+	MovementTransfer        MovementType = "transfer"
+	MovementReturn          MovementType = "return"
+	MovementWriteOff        MovementType = "write_off"
+	MovementCountCorrection MovementType = "count_correction"
+	MovementAssembly        MovementType = "assembly"
+	MovementDisassembly     MovementType = "disassembly"
+)
+
+var movementTypes = map[MovementType]bool{
+	MovementReceipt:         true,
+	MovementIssue:           true,
+	MovementAdjustment:      true,
+	MovementTransfer:        true,
+	MovementReturn:          true,
+	MovementWriteOff:        true,
+	MovementCountCorrection: true,
+	MovementAssembly:        true,
+	MovementDisassembly:     true,
+}
+
+// Valid reports whether the type is one of the known movement types.
+func (t MovementType) Valid() bool {
+	return movementTypes[t]
+}
+
+// RequiresReason reports whether movements of this type must carry a reason
+// code. Corrections to the books have to say why they were made.
+func (t MovementType) RequiresReason() bool {
+	return t == MovementAdjustment || t == MovementWriteOff || t == MovementCountCorrection
+}
+
+// Movement directions say whether a movement added stock to or took stock
+// out of its location.
+const (
+	MovementIn  = "in"
+	MovementOut = "out"
+)
+
+// ReasonCode explains why a movement was made. A code can be limited to one
+// movement type; codes without a type apply to every type. Retired codes are
+// deactivated rather than deleted so old movements keep their reason.
+type ReasonCode struct {
+	ID           uint         `gorm:"primaryKey" json:"id"`
+	Code         string       `gorm:"uniqueIndex;not null" json:"code"`
+	Description  string       `gorm:"column:description" json:"description"`
+	MovementType MovementType `gorm:"column:movement_type;type:varchar(20)" json:"movementType"`
+	Active       bool         `gorm:"not null;default:true" json:"active"`
+	CreatedAt    time.Time    `json:"createdAt"`
+	UpdatedAt    time.Time    `json:"updatedAt"`
+}
+
+// AppliesTo reports whether the code may be used on movements of type t.
+func (r *ReasonCode) AppliesTo(t MovementType) bool {
+	return r.MovementType == "" || r.MovementType == t
+}
+
+// MovementFilter narrows down a movement query. Unset fields do not filter.
+type MovementFilter struct {
+	StartDate  time.Time
+	EndDate    time.Time
+	ProductID  *uint
+	CategoryID *uint
+	LocationID *uint
+	Type       *MovementType
+	ReasonCode *string
+}
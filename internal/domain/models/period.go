@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// StockSnapshot is the quantity and value of a product at a location at the
+// moment AsOf. Snapshots are taken daily so historical stock can be rebuilt
+// from the nearest snapshot instead of from the start of the ledger.
+type StockSnapshot struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	SnapshotDate time.Time `gorm:"type:date;not null;uniqueIndex:idx_snapshot_date_product_location" json:"snapshotDate"`
+	AsOf         time.Time `gorm:"not null;index" json:"asOf"`
+	ProductID    uint      `gorm:"column:product_id;not null;uniqueIndex:idx_snapshot_date_product_location" json:"productId"`
+	LocationID   uint      `gorm:"column:location_id;not null;uniqueIndex:idx_snapshot_date_product_location" json:"locationId"`
+	Quantity     Quantity  `gorm:"not null" json:"quantity"`
+	Value        Money     `gorm:"not null;default:0" json:"value"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// ClosedPeriod marks a calendar month as closed: no movement may be dated
+// inside it any more.
+type ClosedPeriod struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	Year       int       `gorm:"not null;uniqueIndex:idx_closed_period_month" json:"year"`
+	Month      int       `gorm:"not null;uniqueIndex:idx_closed_period_month" json:"month"`
+	Notes      string    `gorm:"column:notes" json:"notes"`
+	ClosedByID uint      `gorm:"column:closed_by_id" json:"closedById"`
+	ClosedAt   time.Time `gorm:"not null" json:"closedAt"`
+}
+
+// Start returns the first instant of the period in loc.
+func (p *ClosedPeriod) Start(loc *time.Location) time.Time {
+	return time.Date(p.Year, time.Month(p.Month), 1, 0, 0, 0, 0, loc)
+}
+
+// End returns the first instant after the period in loc.
+func (p *ClosedPeriod) End(loc *time.Location) time.Time {
+	return p.Start(loc).AddDate(0, 1, 0)
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+type Supplier struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	Code        string    `gorm:"uniqueIndex;not null" json:"code"`
+	Name        string    `gorm:"column:name;not null" json:"name"`
+	ContactName string    `gorm:"column:contact_name" json:"contactName"`
+	Email       string    `gorm:"column:email" json:"email"`
+	Phone       string    `gorm:"column:phone" json:"phone"`
+	Address     string    `gorm:"column:address" json:"address"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+const (
+	PurchaseOrderStatusDraft             = "draft"
+	PurchaseOrderStatusApproved          = "approved"
+	PurchaseOrderStatusPartiallyReceived = "partially_received"
+	PurchaseOrderStatusClosed            = "closed"
+)
+
+// PurchaseOrder is what we ordered from a supplier for delivery to one
+// location. Only approved orders can be received against; the order closes
+// once every line is fully received or it is closed short by hand.
+type PurchaseOrder struct {
+	ID           uint                `gorm:"primaryKey" json:"id"`
+	SupplierID   uint                `gorm:"column:supplier_id;not null;index" json:"supplierId"`
+	Supplier     *Supplier           `json:"supplier,omitempty"`
+	LocationID   uint                `gorm:"column:location_id;not null" json:"locationId"`
+	Location     *Location           `json:"location,omitempty"`
+	Reference    string              `gorm:"column:reference" json:"reference"`
+	Status       string              `gorm:"type:varchar(20);not null;index" json:"status"`
+	Notes        string              `gorm:"column:notes" json:"notes"`
+	ExpectedAt   *time.Time          `json:"expectedAt"`
+	CreatedByID  uint                `gorm:"column:created_by_id" json:"createdById"`
+	ApprovedByID *uint               `gorm:"column:approved_by_id" json:"approvedById"`
+	ApprovedAt   *time.Time          `json:"approvedAt"`
+	ClosedAt     *time.Time          `json:"closedAt"`
+	Lines        []PurchaseOrderLine `gorm:"foreignKey:PurchaseOrderID" json:"lines"`
+	CreatedAt    time.Time           `json:"createdAt"`
+	UpdatedAt    time.Time           `json:"updatedAt"`
+}
+
+type PurchaseOrderLine struct {
+	ID               uint     `gorm:"primaryKey" json:"id"`
+	PurchaseOrderID  uint     `gorm:"column:purchase_order_id;not null;index" json:"purchaseOrderId"`
+	ProductID        uint     `gorm:"column:product_id;not null" json:"productId"`
+	Product          *Product `json:"product,omitempty"`
+	Quantity         Quantity `gorm:"not null" json:"quantity"`
+	ReceivedQuantity Quantity `gorm:"not null;default:0" json:"receivedQuantity"`
+	UnitCost         Money    `gorm:"not null;default:0" json:"unitCost"`
+}
+
+// Outstanding is the quantity ordered but not yet received.
+func (l *PurchaseOrderLine) Outstanding() Quantity {
+	return l.Quantity - l.ReceivedQuantity
+}
+
+// PurchaseReceipt is the quantity of one purchase order line booked in a
+// receipt, with the lots or units it arrived as for tracked products.
+type PurchaseReceipt struct {
+	Quantity Quantity
+	Lots     []StockMovementLot
+	Serials  []StockMovementSerial
+}
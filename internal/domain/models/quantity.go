@@ -0,0 +1,251 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Quantity is an amount of stock in fixed point with QuantityDecimals
+// decimal places. It counts thousandths in an int64, so sums and
+// differences stay exact: quantities are added, subtracted and compared like
+// integers, and scaled with Mul. Quantities are numbers in JSON and
+// numeric(18,3) in the database.
+type Quantity int64
+
+// QuantityDecimals is the finest precision any product can be stocked in.
+const QuantityDecimals = 3
+
+const quantityScale = 1000
+
+// Units returns the quantity of n whole units.
+func Units(n int) Quantity {
+	return Quantity(n) * quantityScale
+}
+
+// ParseQuantity reads a decimal such as "12", "-3" or "2.75". More decimals
+// than QuantityDecimals are rejected rather than rounded away.
+func ParseQuantity(s string) (Quantity, error) {
+	q, exact, err := parseQuantity(s)
+	if err != nil {
+		return 0, err
+	}
+	if !exact {
+		return 0, fmt.Errorf("quantity %s has more than %d decimals", s, QuantityDecimals)
+	}
+	return q, nil
+}
+
+// parseQuantity reads a plain decimal, rounding half away from zero past
+// QuantityDecimals. exact reports whether nothing was rounded off.
+func parseQuantity(s string) (Quantity, bool, error) {
+	n, exact, err := parseDecimal(s, "quantity", QuantityDecimals, maxQuantity)
+	return Quantity(n), exact, err
+}
+
+// maxQuantity keeps quantities inside numeric(18,3).
+const maxQuantity = 999_999_999_999_999_999
+
+// parseDecimal reads a plain decimal such as "-3" or "2.75" as a count of
+// units of the given number of decimals, rounding half away from zero past
+// them. exact reports whether nothing was rounded off; what names the value
+// in errors.
+func parseDecimal(s, what string, decimals int, limit int64) (int64, bool, error) {
+	text := strings.TrimSpace(s)
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "-"), "+")
+
+	whole, fraction, _ := strings.Cut(text, ".")
+	if whole == "" && fraction == "" || !digitsOnly(whole) || !digitsOnly(fraction) {
+		return 0, false, fmt.Errorf("invalid %s %q", what, s)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+
+	exact, roundUp := true, false
+	if len(fraction) > decimals {
+		exact = strings.Trim(fraction[decimals:], "0") == ""
+		roundUp = fraction[decimals] >= '5'
+		fraction = fraction[:decimals]
+	}
+	fraction += strings.Repeat("0", decimals-len(fraction))
+
+	n, err := strconv.ParseInt(whole+fraction, 10, 64)
+	if err != nil || n > limit || roundUp && n == limit {
+		return 0, false, fmt.Errorf("%s %q is out of range", what, s)
+	}
+	if roundUp {
+		n++
+	}
+	if negative {
+		n = -n
+	}
+	return n, exact, nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// String formats the quantity without trailing zeros, e.g. "2.75" or "12".
+func (q Quantity) String() string {
+	return formatDecimal(int64(q), QuantityDecimals)
+}
+
+// formatDecimal writes n units of the given number of decimals without
+// trailing zeros.
+func formatDecimal(n int64, decimals int) string {
+	sign := ""
+	if n < 0 {
+		sign = "-"
+	}
+	digits := strconv.FormatUint(absUint64(n), 10)
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return sign + whole
+	}
+	return sign + whole + "." + fraction
+}
+
+func absUint64(n int64) uint64 {
+	if n < 0 {
+		return uint64(-(n + 1)) + 1
+	}
+	return uint64(n)
+}
+
+// Whole returns the number of whole units, dropping any fraction.
+func (q Quantity) Whole() int {
+	return int(q / quantityScale)
+}
+
+// IsWhole reports whether the quantity has no fractional part.
+func (q Quantity) IsWhole() bool {
+	return q%quantityScale == 0
+}
+
+// Decimals returns the number of decimals needed to write the quantity.
+func (q Quantity) Decimals() int {
+	fraction := int64(q.Abs()) % quantityScale
+	decimals := QuantityDecimals
+	for decimals > 0 && fraction%10 == 0 {
+		fraction /= 10
+		decimals--
+	}
+	return decimals
+}
+
+func (q Quantity) Abs() Quantity {
+	if q < 0 {
+		return -q
+	}
+	return q
+}
+
+// Mul multiplies two quantities, rounding half away from zero to the
+// precision of a quantity; it converts between units with a factor.
+// Products beyond the range of a quantity are ErrOutOfRange.
+func (q Quantity) Mul(factor Quantity) (Quantity, error) {
+	n, err := mulDiv(int64(q), int64(factor), quantityScale, maxQuantity)
+	return Quantity(n), err
+}
+
+// Div divides the quantity by another, truncating towards zero to the
+// precision of a quantity; it tells how often divisor fits into q.
+func (q Quantity) Div(divisor Quantity) (Quantity, error) {
+	if divisor == 0 {
+		return 0, errors.New("cannot divide by a zero quantity")
+	}
+	quotient := new(big.Int).Mul(big.NewInt(int64(q)), big.NewInt(quantityScale))
+	quotient.Quo(quotient, big.NewInt(int64(divisor)))
+	if !quotient.IsInt64() || quotient.CmpAbs(big.NewInt(maxQuantity)) > 0 {
+		return 0, ErrOutOfRange
+	}
+	return Quantity(quotient.Int64()), nil
+}
+
+// Truncate drops the decimals beyond the given number, towards zero.
+func (q Quantity) Truncate(decimals int) Quantity {
+	step := int64(1)
+	for i := decimals; i < QuantityDecimals; i++ {
+		step *= 10
+	}
+	return Quantity(int64(q) / step * step)
+}
+
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON accepts a JSON number or a string holding one.
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	text := string(data)
+	if text == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+
+	parsed, err := ParseQuantity(text)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// Value stores the quantity as an exact decimal.
+func (q Quantity) Value() (driver.Value, error) {
+	return q.String(), nil
+}
+
+// Scan reads a numeric column; aggregates with more decimals are rounded.
+func (q *Quantity) Scan(src any) error {
+	n, err := scanDecimal(src, "quantity", QuantityDecimals, maxQuantity)
+	if err != nil {
+		return err
+	}
+	*q = Quantity(n)
+	return nil
+}
+
+// scanDecimal reads a numeric column as a count of units of the given
+// number of decimals, rounding any further decimals.
+func scanDecimal(src any, what string, decimals int, limit int64) (int64, error) {
+	var text string
+	switch value := src.(type) {
+	case nil:
+		return 0, nil
+	case int64:
+		text = strconv.FormatInt(value, 10)
+	case float64:
+		text = strconv.FormatFloat(value, 'f', -1, 64)
+	case []byte:
+		text = string(value)
+	case string:
+		text = value
+	default:
+		return 0, fmt.Errorf("cannot scan %T into a %s", src, what)
+	}
+
+	n, _, err := parseDecimal(text, what, decimals, limit)
+	return n, err
+}
+
+// GormDataType makes quantity columns numeric(18,3).
+func (Quantity) GormDataType() string {
+	return "numeric(18,3)"
+}
@@ -0,0 +1,235 @@
+package models_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"stock-management/internal/domain/models"
+	"testing"
+)
+
+// maxQuantity is the largest quantity numeric(18,3) holds.
+const maxQuantity = models.Quantity(999_999_999_999_999_999)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    models.Quantity
+		wantErr bool
+	}{
+		{in: "12", want: 12000},
+		{in: "-3", want: -3000},
+		{in: "2.75", want: 2750},
+		{in: ".5", want: 500},
+		{in: "+1.5", want: 1500},
+		{in: " 4 ", want: 4000},
+		{in: "1.2340", want: 1234},
+		{in: "-0.001", want: -1},
+		{in: "999999999999999.999", want: maxQuantity},
+		{in: "-999999999999999.999", want: -maxQuantity},
+		{in: "1.2345", wantErr: true},
+		{in: "0.0005", wantErr: true},
+		{in: "1000000000000000", wantErr: true},
+		{in: "99999999999999999999", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "abc", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "--1", wantErr: true},
+		{in: "1e3", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := models.ParseQuantity(tt.in)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseQuantity(%q) = %d, want an error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseQuantity(%q) = %d, %v, want %d", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+// TestQuantityScan checks that values from the database with more decimals
+// than a quantity holds are rounded half away from zero.
+func TestQuantityScan(t *testing.T) {
+	tests := []struct {
+		src     any
+		want    models.Quantity
+		wantErr bool
+	}{
+		{src: "2.7495", want: 2750},
+		{src: "2.7494", want: 2749},
+		{src: "-2.7495", want: -2750},
+		{src: "-2.7494", want: -2749},
+		{src: []byte("0.0005"), want: 1},
+		{src: "999999999999999.9994", want: maxQuantity},
+		{src: "999999999999999.9995", wantErr: true},
+		{src: int64(3), want: 3000},
+		{src: 1.5, want: 1500},
+		{src: nil, want: 0},
+		{src: "many", wantErr: true},
+		{src: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got models.Quantity
+		err := got.Scan(tt.src)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Scan(%v) = %d, want an error", tt.src, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Scan(%v) = %d, %v, want %d", tt.src, got, err, tt.want)
+		}
+	}
+}
+
+func TestQuantityString(t *testing.T) {
+	tests := []struct {
+		in   models.Quantity
+		want string
+	}{
+		{0, "0"},
+		{5, "0.005"},
+		{-5, "-0.005"},
+		{2750, "2.75"},
+		{12000, "12"},
+		{-3000, "-3"},
+		{-1250, "-1.25"},
+		{maxQuantity, "999999999999999.999"},
+		{math.MinInt64, "-9223372036854775.808"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Quantity(%d).String() = %s, want %s", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestQuantityMul(t *testing.T) {
+	tests := []struct {
+		q, factor models.Quantity
+		want      models.Quantity
+		wantErr   error
+	}{
+		{q: models.Units(3), factor: 2500, want: 7500},
+		{q: models.Units(-2), factor: 1500, want: -3000},
+		{q: 1, factor: 500, want: 1},
+		{q: -1, factor: 500, want: -1},
+		{q: 1, factor: -500, want: -1},
+		{q: 1, factor: 499, want: 0},
+		{q: 3, factor: 1500, want: 5},
+		{q: maxQuantity, factor: models.Units(1), want: maxQuantity},
+		{q: maxQuantity, factor: models.Units(2), wantErr: models.ErrOutOfRange},
+		{q: math.MaxInt64, factor: math.MaxInt64, wantErr: models.ErrOutOfRange},
+	}
+	for _, tt := range tests {
+		got, err := tt.q.Mul(tt.factor)
+		if !errors.Is(err, tt.wantErr) || err == nil && got != tt.want {
+			t.Errorf("%s.Mul(%s) = %s, %v, want %s, %v", tt.q, tt.factor, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQuantityDiv(t *testing.T) {
+	tests := []struct {
+		q, divisor models.Quantity
+		want       models.Quantity
+		wantErr    bool
+	}{
+		{q: models.Units(7), divisor: models.Units(2), want: 3500},
+		{q: models.Units(10), divisor: models.Units(3), want: 3333},
+		{q: models.Units(-10), divisor: models.Units(3), want: -3333},
+		{q: models.Units(20), divisor: models.Units(-3), want: -6666},
+		{q: 1, divisor: models.Units(2), want: 0},
+		{q: models.Units(1), divisor: 0, wantErr: true},
+		{q: maxQuantity, divisor: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.q.Div(tt.divisor)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s.Div(%s) = %s, want an error", tt.q, tt.divisor, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s.Div(%s) = %s, %v, want %s", tt.q, tt.divisor, got, err, tt.want)
+		}
+	}
+}
+
+func TestQuantityTruncate(t *testing.T) {
+	tests := []struct {
+		q        models.Quantity
+		decimals int
+		want     models.Quantity
+	}{
+		{2759, 3, 2759},
+		{2759, 2, 2750},
+		{2759, 1, 2700},
+		{2999, 0, 2000},
+		{-2759, 2, -2750},
+		{-2999, 0, -2000},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Truncate(tt.decimals); got != tt.want {
+			t.Errorf("%s.Truncate(%d) = %s, want %s", tt.q, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestQuantityJSON(t *testing.T) {
+	type line struct {
+		Quantity models.Quantity  `json:"quantity"`
+		Optional *models.Quantity `json:"optional"`
+	}
+	half := models.Quantity(-500)
+	in := line{Quantity: 2750, Optional: &half}
+
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(data) != `{"quantity":2.75,"optional":-0.5}` {
+		t.Errorf("marshal = %s", data)
+	}
+	var out line
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if out.Quantity != in.Quantity || out.Optional == nil || *out.Optional != half {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+
+	tests := []struct {
+		in      string
+		want    models.Quantity
+		wantErr bool
+	}{
+		{in: `{"quantity":"1.5"}`, want: 1500},
+		{in: `{"quantity":12}`, want: 12000},
+		{in: `{"quantity":null}`, want: 0},
+		{in: `{"quantity":1.2345}`, wantErr: true},
+		{in: `{"quantity":"lots"}`, wantErr: true},
+		{in: `{"quantity":1e3}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		var got line
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("unmarshal %s = %s, want an error", tt.in, got.Quantity)
+			}
+			continue
+		}
+		if err != nil || got.Quantity != tt.want {
+			t.Errorf("unmarshal %s = %s, %v, want %s", tt.in, got.Quantity, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RefreshToken is a single-use token handed out next to an access token.
+// Every refresh rotates it into a new token of the same family; the family
+// identifies one login session and is what logout and reuse detection revoke.
+type RefreshToken struct {
+	gorm.Model
+	UserID       uint      `gorm:"not null;index"`
+	User         User      `json:"-"`
+	FamilyID     string    `gorm:"type:varchar(64);not null;index"`
+	TokenHash    string    `gorm:"type:varchar(64);uniqueIndex;not null"`
+	ExpiresAt    time.Time `gorm:"not null"`
+	RevokedAt    *time.Time
+	ReplacedByID *uint
+}
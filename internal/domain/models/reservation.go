@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+const (
+	ReservationStatusActive   = "active"
+	ReservationStatusConsumed = "consumed"
+	ReservationStatusReleased = "released"
+	ReservationStatusExpired  = "expired"
+)
+
+// Reservation holds stock of a product at a location for a pending order or
+// quote. Reserved stock stays on hand but is not available to other exports.
+type Reservation struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	ProductID   uint      `gorm:"column:product_id;not null;index:idx_reservation_stock" json:"productId"`
+	Product     *Product  `json:"product,omitempty"`
+	LocationID  uint      `gorm:"column:location_id;not null;index:idx_reservation_stock" json:"locationId"`
+	Location    *Location `json:"location,omitempty"`
+	Quantity    Quantity  `gorm:"not null" json:"quantity"`
+	Reference   string    `gorm:"column:reference" json:"reference"`
+	Status      string    `gorm:"type:varchar(20);not null;index" json:"status"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expiresAt"`
+	CreatedByID uint      `gorm:"column:created_by_id" json:"createdById"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+
+	// SalesOrderLineID is set on reservations made by confirming a sales order
+	SalesOrderLineID *uint `gorm:"column:sales_order_line_id;index" json:"salesOrderLineId,omitempty"`
+}
+
+// ReservedQuantity is the quantity held by live reservations for one product
+// at one location.
+type ReservedQuantity struct {
+	ProductID  uint
+	LocationID uint
+	Quantity   Quantity
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+const (
+	ReturnStatusAuthorized = "authorized"
+	ReturnStatusReceived   = "received"
+	ReturnStatusClosed     = "closed"
+	ReturnStatusCancelled  = "cancelled"
+)
+
+// ReturnAuthorization (RMA) allows a customer to send back goods that left
+// with an earlier export movement or sales order. Returned goods wait in
+// quarantine, outside the sellable stock, until inspection either restocks
+// them at the return's location or writes them off.
+type ReturnAuthorization struct {
+	ID                uint         `gorm:"primaryKey" json:"id"`
+	SalesOrderID      *uint        `gorm:"column:sales_order_id;index" json:"salesOrderId"`
+	MovementID        *uint        `gorm:"column:movement_id;index" json:"movementId"`
+	CustomerReference string       `gorm:"column:customer_reference" json:"customerReference"`
+	Reason            string       `gorm:"column:reason" json:"reason"`
+	LocationID        uint         `gorm:"column:location_id;not null" json:"locationId"`
+	Location          *Location    `json:"location,omitempty"`
+	Status            string       `gorm:"type:varchar(20);not null;index" json:"status"`
+	CreatedByID       uint         `gorm:"column:created_by_id" json:"createdById"`
+	Lines             []ReturnLine `gorm:"foreignKey:ReturnAuthorizationID" json:"lines"`
+	ClosedAt          *time.Time   `json:"closedAt"`
+	CreatedAt         time.Time    `json:"createdAt"`
+	UpdatedAt         time.Time    `json:"updatedAt"`
+}
+
+type ReturnLine struct {
+	ID                    uint     `gorm:"primaryKey" json:"id"`
+	ReturnAuthorizationID uint     `gorm:"column:return_authorization_id;not null;index" json:"returnAuthorizationId"`
+	SalesOrderLineID      *uint    `gorm:"column:sales_order_line_id;index" json:"salesOrderLineId"`
+	ProductID             uint     `gorm:"column:product_id;not null" json:"productId"`
+	Product               *Product `json:"product,omitempty"`
+	Quantity              Quantity `gorm:"not null" json:"quantity"`
+	ReceivedQuantity      Quantity `gorm:"not null;default:0" json:"receivedQuantity"`
+	RestockedQuantity     Quantity `gorm:"not null;default:0" json:"restockedQuantity"`
+	WrittenOffQuantity    Quantity `gorm:"not null;default:0" json:"writtenOffQuantity"`
+	InspectionNotes       string   `gorm:"column:inspection_notes" json:"inspectionNotes"`
+}
+
+// Quarantined is the quantity received but not yet inspected.
+func (l *ReturnLine) Quarantined() Quantity {
+	return l.ReceivedQuantity - l.RestockedQuantity - l.WrittenOffQuantity
+}
+
+// Expected is the quantity authorized but not yet received.
+func (l *ReturnLine) Expected() Quantity {
+	return l.Quantity - l.ReceivedQuantity
+}
+
+// ReturnInspection is the outcome of inspecting quarantined goods of one
+// return line. Restocked goods carry their lot or units for tracked products.
+type ReturnInspection struct {
+	RestockQuantity  Quantity
+	WriteOffQuantity Quantity
+	Notes            string
+	Lots             []StockMovementLot
+	Serials          []StockMovementSerial
+}
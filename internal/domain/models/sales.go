@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+const (
+	SalesOrderStatusDraft            = "draft"
+	SalesOrderStatusConfirmed        = "confirmed"
+	SalesOrderStatusPartiallyShipped = "partially_shipped"
+	SalesOrderStatusShipped          = "shipped"
+	SalesOrderStatusCancelled        = "cancelled"
+)
+
+// SalesOrder is an order placed by a customer. Confirming it reserves the
+// ordered goods, optionally only in one warehouse; shipping turns the
+// reservations into exports linked back to the order lines.
+type SalesOrder struct {
+	ID                uint             `gorm:"primaryKey" json:"id"`
+	CustomerReference string           `gorm:"column:customer_reference;not null;index" json:"customerReference"`
+	CustomerName      string           `gorm:"column:customer_name" json:"customerName"`
+	WarehouseID       *uint            `gorm:"column:warehouse_id" json:"warehouseId"`
+	Warehouse         *Warehouse       `json:"warehouse,omitempty"`
+	Status            string           `gorm:"type:varchar(20);not null;index" json:"status"`
+	Notes             string           `gorm:"column:notes" json:"notes"`
+	CreatedByID       uint             `gorm:"column:created_by_id" json:"createdById"`
+	ConfirmedAt       *time.Time       `json:"confirmedAt"`
+	ClosedAt          *time.Time       `json:"closedAt"`
+	Lines             []SalesOrderLine `gorm:"foreignKey:SalesOrderID" json:"lines"`
+	CreatedAt         time.Time        `json:"createdAt"`
+	UpdatedAt         time.Time        `json:"updatedAt"`
+}
+
+type SalesOrderLine struct {
+	ID              uint          `gorm:"primaryKey" json:"id"`
+	SalesOrderID    uint          `gorm:"column:sales_order_id;not null;index" json:"salesOrderId"`
+	ProductID       uint          `gorm:"column:product_id;not null" json:"productId"`
+	Product         *Product      `json:"product,omitempty"`
+	Quantity        Quantity      `gorm:"not null" json:"quantity"`
+	ShippedQuantity Quantity      `gorm:"not null;default:0" json:"shippedQuantity"`
+	Reservations    []Reservation `gorm:"foreignKey:SalesOrderLineID" json:"reservations,omitempty"`
+}
+
+// Outstanding is the quantity ordered but not yet shipped.
+func (l *SalesOrderLine) Outstanding() Quantity {
+	return l.Quantity - l.ShippedQuantity
+}
+
+// ShipmentLine is a quantity of one sales order line leaving the warehouse.
+// LocationID limits the shipment to the stock reserved at that location and
+// is needed when naming serial numbers; zero ships from any reservation.
+type ShipmentLine struct {
+	LineID     uint
+	LocationID uint
+	Quantity   Quantity
+	Serials    []StockMovementSerial
+}
+
+// PickListDTO lists what to pick in one warehouse for a sales order.
+type PickListDTO struct {
+	WarehouseID   uint          `json:"warehouseId"`
+	WarehouseCode string        `json:"warehouseCode"`
+	WarehouseName string        `json:"warehouseName"`
+	Lines         []PickLineDTO `json:"lines"`
+}
+
+type PickLineDTO struct {
+	LineID        uint     `json:"lineId"`
+	ReservationID uint     `json:"reservationId"`
+	ProductID     uint     `json:"productId"`
+	ProductName   string   `json:"productName"`
+	SKU           string   `json:"sku"`
+	LocationID    uint     `json:"locationId"`
+	LocationCode  string   `json:"locationCode"`
+	Quantity      Quantity `json:"quantity"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+const (
+	SerialStatusInStock   = "in_stock"
+	SerialStatusInTransit = "in_transit"
+	SerialStatusOut       = "out"
+)
+
+// SerialNumber is one individually tracked unit of a serial-tracked product.
+// LocationID is only set while the unit is in stock.
+type SerialNumber struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProductID  uint      `gorm:"column:product_id;not null;uniqueIndex:idx_serial_product_serial" json:"productId"`
+	Serial     string    `gorm:"column:serial;not null;uniqueIndex:idx_serial_product_serial" json:"serial"`
+	Status     string    `gorm:"type:varchar(20);not null;index" json:"status"`
+	LocationID *uint     `gorm:"column:location_id;index" json:"locationId"`
+	Location   *Location `json:"location,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// StockMovementSerial links a movement to a serial number it moved.
+type StockMovementSerial struct {
+	ID              uint `gorm:"primaryKey"`
+	StockMovementID uint `gorm:"not null;index"`
+	SerialNumberID  uint `gorm:"not null;index"`
+	SerialNumber    *SerialNumber
+}
+
+// SerialHistoryDTO is a serial number with every movement that touched it.
+type SerialHistoryDTO struct {
+	SerialNumber SerialNumber  `json:"serialNumber"`
+	Movements    []MovementDTO `json:"movements"`
+}
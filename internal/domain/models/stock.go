@@ -15,60 +15,154 @@ type Category struct {
 	UpdatedAt   time.Time `json:"updatedAt"`
 }
 
+// Tracking modes decide how individual units of a product are identified.
+const (
+	TrackingNone   = "none"
+	TrackingLot    = "lot"
+	TrackingSerial = "serial"
+)
+
 type Product struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"column:name;not null" json:"name"`
-	ImageURL    string    `gorm:"imageurl" json:"imageURL"`
-	Description string    `gorm:"column:description" json:"description"`
-	CategoryID  uint      `gorm:"column:category_id;not null" json:"categoryId"`
-	Category    *Category `json:"category"`
-	SKU         string    `gorm:"uniqueIndex;not null json:"sku"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"column:name;not null" json:"name"`
+	ImageURL     string    `gorm:"imageurl" json:"imageURL"`
+	Description  string    `gorm:"column:description" json:"description"`
+	CategoryID   uint      `gorm:"column:category_id;not null" json:"categoryId"`
+	Category     *Category `json:"category"`
+	SKU          string    `gorm:"uniqueIndex:idx_products_live_sku,where:deleted_at IS NULL;not null" json:"sku"`
+	TrackingMode string    `gorm:"column:tracking_mode;type:varchar(10);not null;default:none" json:"trackingMode"`
+	// CostingMethod is CostingFIFO or CostingAverage, empty for the default
+	CostingMethod string `gorm:"column:costing_method;type:varchar(10)" json:"costingMethod"`
+	// QuantityDecimals is how many decimals quantities of the product may
+	// have, 0 for products stocked in whole units
+	QuantityDecimals int `gorm:"column:quantity_decimals;not null;default:0" json:"quantityDecimals"`
+	// BaseUnitID is the unit stock quantities are kept in
+	BaseUnitID *uint          `gorm:"column:base_unit_id" json:"baseUnitId"`
+	BaseUnit   *UnitOfMeasure `json:"baseUnit,omitempty"`
+	// ParentID is set on variants and points to the product they are a
+	// variant of; a product with variants keeps no stock of its own
+	ParentID          *uint              `gorm:"column:parent_id;index" json:"parentId"`
+	VariantAttributes []VariantAttribute `gorm:"foreignKey:VariantID" json:"-"`
+	CreatedAt         time.Time          `json:"createdAt"`
+	UpdatedAt         time.Time          `json:"updatedAt"`
+
+	// Deleted products are only archived so their movements keep a product
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 }
 
+// Stock is the on-hand quantity of a product at a single location.
 type Stock struct {
 	gorm.Model
-	ProductID uint
-	Product   Product
-	Quantity  int `gorm:"not null"`
+	ProductID  uint `gorm:"uniqueIndex:idx_stock_product_location"`
+	Product    Product
+	LocationID uint `gorm:"uniqueIndex:idx_stock_product_location"`
+	Location   Location
+	Quantity   Quantity `gorm:"not null"`
 }
 
 type StockMovement struct {
 	gorm.Model
-	ProductID uint
-	Product   Product
-	UserID    uint
-	User      User
-	Type      string    `gorm:"not null"` // "import" or "export"
-	Quantity  int       `gorm:"not null"`
-	Date      time.Time `gorm:"not null"`
-	Notes     string
+	ProductID  uint
+	Product    Product
+	LocationID uint `gorm:"index"`
+	Location   Location
+	UserID     uint
+	User       User
+	Type       MovementType `gorm:"type:varchar(20);not null;index"`
+	Direction  string       `gorm:"type:varchar(3);not null;default:in"` // MovementIn or MovementOut
+	Quantity   Quantity     `gorm:"not null"`
+	Date       time.Time    `gorm:"not null"`
+	Notes      string
+
+	// EnteredUnitID and EnteredQuantity keep what the user booked when it
+	// was not in the base unit; Quantity is always in the base unit
+	EnteredUnitID   *uint
+	EnteredUnit     *UnitOfMeasure
+	EnteredQuantity Quantity `gorm:"not null;default:0"`
+
+	// UnitCost is what one unit was received at, or for outgoing movements
+	// the average cost of the units consumed; TotalCost is the movement's
+	// value, the cost of goods for outgoing movements
+	UnitCost  Money `gorm:"not null;default:0"`
+	TotalCost Money `gorm:"not null;default:0"`
+
+	ReasonCodeID *uint `gorm:"index"`
+	ReasonCode   *ReasonCode
+
+	TransferLineID      *uint `gorm:"index"`
+	ReservationID       *uint `gorm:"index"`
+	PurchaseOrderLineID *uint `gorm:"index"`
+	SalesOrderLineID    *uint `gorm:"index"`
+	ReturnLineID        *uint `gorm:"index"`
+	CountLineID         *uint `gorm:"index"`
+	KitBuildID          *uint `gorm:"index"`
+
+	// ReversalOfID is set on the compensating movement that reverses
+	// another; the unique index keeps a movement from being reversed twice
+	ReversalOfID *uint `gorm:"uniqueIndex"`
+
+	// Lots records which lots a movement of a lot-tracked product touched
+	Lots []StockMovementLot `gorm:"foreignKey:StockMovementID"`
+	// Serials records which units a movement of a serial-tracked product moved
+	Serials []StockMovementSerial `gorm:"foreignKey:StockMovementID"`
+}
+
+// AllowsQuantity reports whether q respects the product's precision.
+func (p *Product) AllowsQuantity(q Quantity) bool {
+	return q.Decimals() <= p.QuantityDecimals
 }
 
 type ProductDTO struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"column:name;not null" json:"name"`
-	ImageURL    string    `gorm:"imageurl" json:"imageURL"`
-	Description string    `gorm:"column:description" json:"description"`
-	CategoryID  uint      `gorm:"column:category_id;not null" json:"categoryId"`
-	Category    *Category `json:"category"`
-	SKU         string    `gorm:"uniqueIndex;not null"`
-	CreatedAt   time.Time `json:"createdAt"`
-	UpdatedAt   time.Time `json:"updatedAt"`
-	Quantity    int       `json:"quantity"`
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	Name         string    `gorm:"column:name;not null" json:"name"`
+	ImageURL     string    `gorm:"imageurl" json:"imageURL"`
+	Description  string    `gorm:"column:description" json:"description"`
+	CategoryID   uint      `gorm:"column:category_id;not null" json:"categoryId"`
+	Category     *Category `json:"category"`
+	SKU          string    `gorm:"uniqueIndex;not null"`
+	TrackingMode string    `json:"trackingMode"`
+	// QuantityDecimals is the precision of the product's quantities
+	QuantityDecimals int       `json:"quantityDecimals"`
+	CreatedAt        time.Time `json:"createdAt"`
+	UpdatedAt        time.Time `json:"updatedAt"`
+	Quantity         Quantity  `json:"quantity"`
+	Reserved         Quantity  `json:"reserved"`
+	Available        Quantity  `json:"available"`
+	// ParentID and Attributes describe a variant; Attributes maps attribute
+	// names to the values the variant takes
+	ParentID   *uint             `json:"parentId,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	// Variants of a parent product; the parent's quantities are their sums
+	Variants []ProductDTO `json:"variants,omitempty"`
 }
 
 type MovementDTO struct {
-	Type     string    `json:"type"`
-	Quantity int       `json:"quantity"`
-	Date     time.Time `json:"date"`
-	Notes    string    `json:"notes"`
-	Product  struct {
+	ID         uint         `json:"id"`
+	Type       MovementType `json:"type"`
+	Direction  string       `json:"direction"`
+	ReasonCode string       `json:"reasonCode,omitempty"`
+	Quantity   Quantity     `json:"quantity"`
+	Unit       string       `json:"unit,omitempty"`
+	// EnteredQuantity is the quantity as booked in Unit
+	EnteredQuantity Quantity         `json:"enteredQuantity,omitempty"`
+	Date            time.Time        `json:"date"`
+	Notes           string           `json:"notes"`
+	UnitCost        Money            `json:"unitCost"`
+	TotalCost       Money            `json:"totalCost"`
+	ReversalOf      *uint            `json:"reversalOf,omitempty"`
+	Lots            []MovementLotDTO `json:"lots,omitempty"`
+	Serials         []string         `json:"serials,omitempty"`
+	Product         struct {
 		Name     string `json:"name"`
 		ImageURL string `json:"imageURL"`
 		SKU      string `json:"SKU"`
 	} `json:"product"`
+	Location struct {
+		ID        uint   `json:"id"`
+		Code      string `json:"code"`
+		Name      string `json:"name"`
+		Warehouse string `json:"warehouse"`
+	} `json:"location"`
 	User struct {
 		Username string `json:"username"`
 	} `json:"user"`
@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+const (
+	TransferStatusInTransit         = "in_transit"
+	TransferStatusPartiallyReceived = "partially_received"
+	TransferStatusReceived          = "received"
+	TransferStatusCancelled         = "cancelled"
+)
+
+// StockTransfer moves goods between two locations. Dispatching takes the
+// goods out of the source; until they are received or the transfer is
+// cancelled they sit in transit on the transfer lines.
+type StockTransfer struct {
+	ID                    uint                `gorm:"primaryKey" json:"id"`
+	SourceLocationID      uint                `gorm:"column:source_location_id;not null;index" json:"sourceLocationId"`
+	SourceLocation        *Location           `json:"sourceLocation,omitempty"`
+	DestinationLocationID uint                `gorm:"column:destination_location_id;not null;index" json:"destinationLocationId"`
+	DestinationLocation   *Location           `json:"destinationLocation,omitempty"`
+	Status                string              `gorm:"type:varchar(20);not null;index" json:"status"`
+	Notes                 string              `gorm:"column:notes" json:"notes"`
+	CreatedByID           uint                `gorm:"column:created_by_id" json:"createdById"`
+	Lines                 []StockTransferLine `gorm:"foreignKey:TransferID" json:"lines"`
+	DispatchedAt          time.Time           `json:"dispatchedAt"`
+	ClosedAt              *time.Time          `json:"closedAt"`
+	CreatedAt             time.Time           `json:"createdAt"`
+	UpdatedAt             time.Time           `json:"updatedAt"`
+}
+
+type StockTransferLine struct {
+	ID                uint     `gorm:"primaryKey" json:"id"`
+	TransferID        uint     `gorm:"column:transfer_id;not null;index" json:"transferId"`
+	ProductID         uint     `gorm:"column:product_id;not null" json:"productId"`
+	Product           *Product `json:"product,omitempty"`
+	Quantity          Quantity `gorm:"not null" json:"quantity"`
+	ReceivedQuantity  Quantity `gorm:"not null;default:0" json:"receivedQuantity"`
+	CancelledQuantity Quantity `gorm:"not null;default:0" json:"cancelledQuantity"`
+
+	// Serials lists the units to dispatch for serial-tracked products
+	Serials []string `gorm:"-" json:"serials,omitempty"`
+}
+
+// TransferReceipt is the quantity of one transfer line booked in a receipt.
+// Serials optionally names the units received for serial-tracked products.
+type TransferReceipt struct {
+	Quantity Quantity
+	Serials  []string
+}
+
+// InTransit is the quantity dispatched but neither received nor returned.
+func (l *StockTransferLine) InTransit() Quantity {
+	return l.Quantity - l.ReceivedQuantity - l.CancelledQuantity
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// DefaultUnitCode is the unit products are counted in unless they name
+// another base unit.
+const DefaultUnitCode = "EA"
+
+// UnitOfMeasure is an entry of the unit catalogue, e.g. EA, CTN or KG.
+type UnitOfMeasure struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Code      string    `gorm:"uniqueIndex;not null" json:"code"`
+	Name      string    `gorm:"column:name" json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ProductUnit is an alternate unit a product is handled in. Factor is the
+// number of base units in one alternate unit, e.g. 24 for a carton of 24.
+type ProductUnit struct {
+	ID        uint           `gorm:"primaryKey" json:"id"`
+	ProductID uint           `gorm:"column:product_id;not null;uniqueIndex:idx_product_unit" json:"productId"`
+	UnitID    uint           `gorm:"column:unit_id;not null;uniqueIndex:idx_product_unit" json:"unitId"`
+	Unit      *UnitOfMeasure `json:"unit,omitempty"`
+	Factor    Quantity       `gorm:"not null" json:"factor"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
@@ -6,17 +6,48 @@ import (
 	"gorm.io/gorm"
 )
 
+// Role is the access level granted to a user. Roles are ordered, so a user
+// holding a higher role is allowed everything a lower role can do.
+type Role string
+
+const (
+	RoleViewer  Role = "viewer"
+	RoleClerk   Role = "clerk"
+	RoleManager Role = "manager"
+	RoleAdmin   Role = "admin"
+)
+
+var roleLevels = map[Role]int{
+	RoleViewer:  1,
+	RoleClerk:   2,
+	RoleManager: 3,
+	RoleAdmin:   4,
+}
+
+// Valid reports whether the role is one of the known roles.
+func (r Role) Valid() bool {
+	_, ok := roleLevels[r]
+	return ok
+}
+
+// AtLeast reports whether r grants at least the permissions of min.
+func (r Role) AtLeast(min Role) bool {
+	return roleLevels[r] >= roleLevels[min] && r.Valid()
+}
+
 type User struct {
 	gorm.Model
 	Username    string `gorm:"uniqueIndex;not null"`
 	Password    string `gorm:"not null"`
 	Email       string `gorm:"uniqueIndex;not null"`
+	Role        Role   `gorm:"type:varchar(20);not null;default:viewer"`
 	LastLoginAt time.Time
 }
 
 type UserDTO struct {
 	Username    string `gorm:"uniqueIndex;not null"`
 	Email       string `gorm:"uniqueIndex;not null"`
+	Role        Role
 	LastLoginAt time.Time
 }
 
@@ -24,6 +55,7 @@ func (user *User) ToDTO() *UserDTO {
 	return &UserDTO{
 		Username:    user.Username,
 		Email:       user.Email,
+		Role:        user.Role,
 		LastLoginAt: user.LastLoginAt,
 	}
 }
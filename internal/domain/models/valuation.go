@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// Costing methods decide what an outgoing movement costs. A product without
+// a costing method of its own uses the configured default.
+const (
+	CostingFIFO    = "fifo"
+	CostingAverage = "average"
+)
+
+// CostLayer is a quantity of a product received at a location at one unit
+// cost. FIFO consumes layers oldest first; under weighted average a location
+// holds a single layer whose cost is re-averaged on every receipt.
+type CostLayer struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	ProductID  uint      `gorm:"column:product_id;not null;index:idx_cost_layer_product_location" json:"productId"`
+	LocationID uint      `gorm:"column:location_id;not null;index:idx_cost_layer_product_location" json:"locationId"`
+	MovementID *uint     `gorm:"column:movement_id;index" json:"movementId"`
+	ReceivedAt time.Time `gorm:"not null" json:"receivedAt"`
+	Quantity   Quantity  `gorm:"not null" json:"quantity"`
+	Remaining  Quantity  `gorm:"not null" json:"remaining"`
+	UnitCost   Money     `gorm:"not null" json:"unitCost"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ValuationFilter narrows the valuation report.
+type ValuationFilter struct {
+	LocationID  *uint
+	WarehouseID *uint
+	CategoryID  *uint
+}
+
+// ValuationReport is the value of the stock on hand, broken down by product,
+// category and location.
+type ValuationReport struct {
+	TotalQuantity Quantity            `json:"totalQuantity"`
+	TotalValue    Money               `json:"totalValue"`
+	Products      []ProductValuation  `json:"products"`
+	Categories    []CategoryValuation `json:"categories"`
+	Locations     []LocationValuation `json:"locations"`
+	GeneratedAt   time.Time           `json:"generatedAt"`
+}
+
+type ProductValuation struct {
+	ProductID     uint     `json:"productId"`
+	SKU           string   `json:"sku"`
+	Name          string   `json:"name"`
+	CategoryID    uint     `json:"categoryId"`
+	CostingMethod string   `json:"costingMethod"`
+	Quantity      Quantity `json:"quantity"`
+	Value         Money    `json:"value"`
+	UnitCost      Money    `json:"unitCost"`
+}
+
+type CategoryValuation struct {
+	CategoryID uint     `json:"categoryId"`
+	Name       string   `json:"name"`
+	Quantity   Quantity `json:"quantity"`
+	Value      Money    `json:"value"`
+}
+
+type LocationValuation struct {
+	LocationID uint     `json:"locationId"`
+	Code       string   `json:"code"`
+	Name       string   `json:"name"`
+	Warehouse  string   `json:"warehouse"`
+	Quantity   Quantity `json:"quantity"`
+	Value      Money    `json:"value"`
+}
+
+// ValuationRow is the value held by one product at one location, the grain
+// the report is aggregated from.
+type ValuationRow struct {
+	ProductID     uint
+	SKU           string
+	ProductName   string
+	CostingMethod string
+	CategoryID    uint
+	CategoryName  string
+	LocationID    uint
+	LocationCode  string
+	LocationName  string
+	WarehouseName string
+	Quantity      Quantity
+	Value         Money
+}
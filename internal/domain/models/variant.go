@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// ProductAttribute is a dimension the variants of a parent product differ
+// in, such as size or colour.
+type ProductAttribute struct {
+	ID        uint                    `gorm:"primaryKey" json:"id"`
+	ProductID uint                    `gorm:"column:product_id;not null;uniqueIndex:idx_product_attribute" json:"productId"`
+	Name      string                  `gorm:"column:name;not null;uniqueIndex:idx_product_attribute" json:"name"`
+	Position  int                     `gorm:"not null;default:0" json:"position"`
+	Values    []ProductAttributeValue `gorm:"foreignKey:AttributeID" json:"values"`
+	CreatedAt time.Time               `json:"createdAt"`
+	UpdatedAt time.Time               `json:"updatedAt"`
+}
+
+// ProductAttributeValue is one option of an attribute, e.g. "Large" for the
+// size. Code is what the value adds to the SKU of its variants.
+type ProductAttributeValue struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	AttributeID uint      `gorm:"column:attribute_id;not null;uniqueIndex:idx_attribute_value" json:"attributeId"`
+	Value       string    `gorm:"column:value;not null;uniqueIndex:idx_attribute_value" json:"value"`
+	Code        string    `gorm:"column:code;not null" json:"code"`
+	Position    int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+// VariantAttribute records which value a variant takes for one attribute of
+// its parent product.
+type VariantAttribute struct {
+	ID          uint                   `gorm:"primaryKey" json:"id"`
+	VariantID   uint                   `gorm:"column:variant_id;not null;uniqueIndex:idx_variant_attribute" json:"variantId"`
+	AttributeID uint                   `gorm:"column:attribute_id;not null;uniqueIndex:idx_variant_attribute" json:"attributeId"`
+	Attribute   *ProductAttribute      `json:"attribute,omitempty"`
+	ValueID     uint                   `gorm:"column:value_id;not null;index" json:"valueId"`
+	Value       *ProductAttributeValue `json:"value,omitempty"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+type Warehouse struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	Code      string     `gorm:"uniqueIndex;not null" json:"code"`
+	Name      string     `gorm:"column:name;not null" json:"name"`
+	Address   string     `gorm:"column:address" json:"address"`
+	Locations []Location `json:"locations,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+}
+
+// Location is a place inside a warehouse (zone, aisle, bin) that holds stock.
+type Location struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	WarehouseID uint       `gorm:"column:warehouse_id;not null;uniqueIndex:idx_location_warehouse_code" json:"warehouseId"`
+	Warehouse   *Warehouse `json:"warehouse,omitempty"`
+	Code        string     `gorm:"column:code;not null;uniqueIndex:idx_location_warehouse_code" json:"code"`
+	Name        string     `gorm:"column:name" json:"name"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// LocationQuantityDTO is the quantity of one product held at one location.
+type LocationQuantityDTO struct {
+	LocationID    uint     `json:"locationId"`
+	LocationCode  string   `json:"locationCode"`
+	WarehouseID   uint     `json:"warehouseId"`
+	WarehouseName string   `json:"warehouseName"`
+	Quantity      Quantity `json:"quantity"`
+	Reserved      Quantity `json:"reserved"`
+	Available     Quantity `json:"available"`
+}
+
+// StockSummaryDTO rolls a product's stock up across locations while keeping
+// the per-location breakdown.
+type StockSummaryDTO struct {
+	ProductID uint                  `json:"productId"`
+	Name      string                `json:"name"`
+	SKU       string                `json:"sku"`
+	Category  *Category             `json:"category"`
+	Quantity  Quantity              `json:"quantity"`
+	Reserved  Quantity              `json:"reserved"`
+	Available Quantity              `json:"available"`
+	Locations []LocationQuantityDTO `json:"locations"`
+}
+
+// StockBalanceRow is the quantity of one product at one location at a past
+// moment, as reconstructed from the movement ledger.
+type StockBalanceRow struct {
+	ProductID     uint
+	SKU           string
+	ProductName   string
+	LocationID    uint
+	LocationCode  string
+	WarehouseID   uint
+	WarehouseName string
+	Quantity      Quantity
+}
+
+// LocationBalanceDTO is the quantity of one product held at one location at
+// a past moment.
+type LocationBalanceDTO struct {
+	LocationID    uint     `json:"locationId"`
+	LocationCode  string   `json:"locationCode"`
+	WarehouseID   uint     `json:"warehouseId"`
+	WarehouseName string   `json:"warehouseName"`
+	Quantity      Quantity `json:"quantity"`
+}
+
+// StockAsOfDTO is a product's stock at a past moment, rolled up across
+// locations.
+type StockAsOfDTO struct {
+	ProductID uint                 `json:"productId"`
+	Name      string               `json:"name"`
+	SKU       string               `json:"sku"`
+	Quantity  Quantity             `json:"quantity"`
+	Locations []LocationBalanceDTO `json:"locations"`
+}
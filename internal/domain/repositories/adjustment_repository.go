@@ -0,0 +1,188 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AdjustmentRepository interface {
+	CreateAdjustment(ctx context.Context, adjustment *models.AdjustmentRequest, post bool) error
+	GetAdjustment(ctx context.Context, id uint) (*models.AdjustmentRequest, error)
+	GetAdjustments(ctx context.Context, status *string) ([]models.AdjustmentRequest, error)
+	ApproveAdjustment(ctx context.Context, id uint, reviewerID uint, notes string) (*models.AdjustmentRequest, error)
+	RejectAdjustment(ctx context.Context, id uint, reviewerID uint, notes string) (*models.AdjustmentRequest, error)
+	GetUnitCost(ctx context.Context, productID, locationID uint) (models.Money, error)
+}
+
+type adjustmentRepository struct {
+	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
+}
+
+func NewAdjustmentRepository(db *gorm.DB, costingMethod string) AdjustmentRepository {
+	return &adjustmentRepository{db: db, costingMethod: costingMethod}
+}
+
+// CreateAdjustment stores an adjustment request. With post set it is
+// approved and its movement written in the same transaction, otherwise it
+// waits as pending.
+func (r *adjustmentRepository) CreateAdjustment(ctx context.Context, adjustment *models.AdjustmentRequest, post bool) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		adjustment.Status = models.AdjustmentStatusPending
+		if err := tx.Create(adjustment).Error; err != nil {
+			return err
+		}
+		if !post {
+			return nil
+		}
+
+		if err := postAdjustment(tx, adjustment, r.costingMethod); err != nil {
+			return err
+		}
+		adjustment.Status = models.AdjustmentStatusApproved
+		return tx.Omit(clause.Associations).Save(adjustment).Error
+	})
+	return translateStockError(err)
+}
+
+func (r *adjustmentRepository) GetAdjustment(ctx context.Context, id uint) (*models.AdjustmentRequest, error) {
+	var adjustment models.AdjustmentRequest
+	err := r.db.WithContext(ctx).
+		Preload("Product").
+		Preload("Location").
+		Preload("ReasonCode").
+		Preload("Serials").
+		First(&adjustment, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &adjustment, nil
+}
+
+func (r *adjustmentRepository) GetAdjustments(ctx context.Context, status *string) ([]models.AdjustmentRequest, error) {
+	query := r.db.WithContext(ctx).
+		Preload("Product").
+		Preload("Location").
+		Preload("ReasonCode")
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var adjustments []models.AdjustmentRequest
+	err := query.Order("id DESC").Find(&adjustments).Error
+	return adjustments, err
+}
+
+// ApproveAdjustment posts a pending adjustment. If the stock no longer
+// allows it, for example because the goods were issued meanwhile, the
+// request stays pending. The requester cannot approve their own adjustment.
+func (r *adjustmentRepository) ApproveAdjustment(ctx context.Context, id uint, reviewerID uint, notes string) (*models.AdjustmentRequest, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		adjustment, err := lockPendingAdjustment(tx, id)
+		if err != nil {
+			return err
+		}
+		if adjustment.RequestedByID == reviewerID {
+			return errors.New("an adjustment must be approved by someone other than its requester")
+		}
+		if err := tx.Where("adjustment_request_id = ?", id).Find(&adjustment.Serials).Error; err != nil {
+			return err
+		}
+
+		if err := postAdjustment(tx, adjustment, r.costingMethod); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		adjustment.Status = models.AdjustmentStatusApproved
+		adjustment.ReviewedByID = &reviewerID
+		adjustment.ReviewedAt = &now
+		adjustment.ReviewNotes = notes
+		return tx.Omit(clause.Associations).Save(adjustment).Error
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetAdjustment(ctx, id)
+}
+
+func (r *adjustmentRepository) RejectAdjustment(ctx context.Context, id uint, reviewerID uint, notes string) (*models.AdjustmentRequest, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		adjustment, err := lockPendingAdjustment(tx, id)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		adjustment.Status = models.AdjustmentStatusRejected
+		adjustment.ReviewedByID = &reviewerID
+		adjustment.ReviewedAt = &now
+		adjustment.ReviewNotes = notes
+		return tx.Omit(clause.Associations).Save(adjustment).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetAdjustment(ctx, id)
+}
+
+// GetUnitCost returns the cost the product is currently carried at, used to
+// value an adjustment before it is posted.
+func (r *adjustmentRepository) GetUnitCost(ctx context.Context, productID, locationID uint) (models.Money, error) {
+	return currentUnitCost(r.db.WithContext(ctx), productID, locationID)
+}
+
+// postAdjustment writes the adjustment's movement and links it back.
+func postAdjustment(tx *gorm.DB, adjustment *models.AdjustmentRequest, defaultCosting string) error {
+	quantity := adjustment.Quantity
+	if quantity < 0 {
+		quantity = -quantity
+	}
+
+	movement := &models.StockMovement{
+		ProductID:    adjustment.ProductID,
+		LocationID:   adjustment.LocationID,
+		UserID:       adjustment.RequestedByID,
+		Type:         models.MovementAdjustment,
+		Quantity:     quantity,
+		Date:         time.Now(),
+		Notes:        fmt.Sprintf("Adjustment #%d: %s", adjustment.ID, adjustment.Notes),
+		ReasonCodeID: &adjustment.ReasonCodeID,
+	}
+	if adjustment.LotNumber != "" {
+		movement.Lots = []models.StockMovementLot{{
+			Lot:      &models.Lot{LotNumber: adjustment.LotNumber, ExpiryDate: adjustment.ExpiryDate},
+			Quantity: quantity,
+		}}
+	}
+	for _, serial := range adjustment.Serials {
+		movement.Serials = append(movement.Serials, models.StockMovementSerial{
+			SerialNumber: &models.SerialNumber{Serial: serial.Serial},
+		})
+	}
+
+	if err := applyStockChange(tx, movement, adjustment.Quantity, defaultCosting); err != nil {
+		return err
+	}
+	adjustment.MovementID = &movement.ID
+	return nil
+}
+
+func lockPendingAdjustment(tx *gorm.DB, id uint) (*models.AdjustmentRequest, error) {
+	var adjustment models.AdjustmentRequest
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&adjustment, id).Error
+	if err != nil {
+		return nil, errors.New("adjustment not found")
+	}
+	if adjustment.Status != models.AdjustmentStatusPending {
+		return nil, errors.New("adjustment is already " + adjustment.Status)
+	}
+	return &adjustment, nil
+}
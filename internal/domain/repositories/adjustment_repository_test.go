@@ -0,0 +1,75 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"testing"
+)
+
+// TestApproveAdjustment keeps a pending adjustment from being approved by
+// its requester or posted when the stock is no longer there, and posts it
+// once someone else approves.
+func TestApproveAdjustment(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewAdjustmentRepository(db, models.CostingFIFO)
+	f := newStockFixture(t, db, "adjustment")
+	reviewer := f.newUser(t, "adjustment-reviewer")
+	reason := f.newReasonCode(t, models.MovementAdjustment)
+
+	f.book(t, models.MovementReceipt, models.Units(10), 0)
+	adjustment := models.AdjustmentRequest{
+		ProductID:     f.product.ID,
+		LocationID:    f.location.ID,
+		Quantity:      -models.Units(4),
+		ReasonCodeID:  reason.ID,
+		RequestedByID: f.user.ID,
+	}
+	if err := repo.CreateAdjustment(ctx, &adjustment, false); err != nil {
+		t.Fatalf("request adjustment: %v", err)
+	}
+	if adjustment.Status != models.AdjustmentStatusPending || f.onHand(t) != models.Units(10) {
+		t.Errorf("a pending adjustment is %s with %s on hand, want pending with 10", adjustment.Status, f.onHand(t))
+	}
+
+	if _, err := repo.ApproveAdjustment(ctx, adjustment.ID, f.user.ID, ""); err == nil {
+		t.Errorf("the requester approved their own adjustment")
+	}
+
+	approved, err := repo.ApproveAdjustment(ctx, adjustment.ID, reviewer.ID, "checked")
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if approved.Status != models.AdjustmentStatusApproved || approved.ReviewedByID == nil || *approved.ReviewedByID != reviewer.ID || approved.MovementID == nil {
+		t.Errorf("after approving: status %s, reviewed by %v, movement %v", approved.Status, approved.ReviewedByID, approved.MovementID)
+	}
+	if got := f.onHand(t); got != models.Units(6) {
+		t.Errorf("on hand = %s, want 6", got)
+	}
+	if _, err := repo.ApproveAdjustment(ctx, adjustment.ID, reviewer.ID, ""); err == nil {
+		t.Errorf("an approved adjustment was approved again")
+	}
+	if _, err := repo.RejectAdjustment(ctx, adjustment.ID, reviewer.ID, ""); err == nil {
+		t.Errorf("an approved adjustment was rejected")
+	}
+
+	large := models.AdjustmentRequest{
+		ProductID:     f.product.ID,
+		LocationID:    f.location.ID,
+		Quantity:      -models.Units(20),
+		ReasonCodeID:  reason.ID,
+		RequestedByID: f.user.ID,
+	}
+	if err := repo.CreateAdjustment(ctx, &large, false); err != nil {
+		t.Fatalf("request adjustment: %v", err)
+	}
+	var short *repositories.InsufficientStockError
+	if _, err := repo.ApproveAdjustment(ctx, large.ID, reviewer.ID, ""); !errors.As(err, &short) {
+		t.Errorf("approve removing 20 of 6: %v, want InsufficientStockError", err)
+	}
+	if pending, err := repo.GetAdjustment(ctx, large.ID); err != nil || pending.Status != models.AdjustmentStatusPending {
+		t.Errorf("a refused approval left the adjustment %v, %v, want pending", pending, err)
+	}
+}
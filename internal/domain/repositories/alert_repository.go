@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type AlertRepository interface {
+	CreateRule(ctx context.Context, rule *models.ReorderRule) error
+	GetRule(ctx context.Context, id uint) (*models.ReorderRule, error)
+	FindRule(ctx context.Context, productID uint, locationID *uint) (*models.ReorderRule, error)
+	GetRules(ctx context.Context, productID *uint) ([]models.ReorderRule, error)
+	GetRulesForStock(ctx context.Context, productID, locationID uint) ([]models.ReorderRule, error)
+	UpdateRule(ctx context.Context, rule *models.ReorderRule) error
+	DeleteRule(ctx context.Context, id uint) error
+
+	GetAvailableQuantity(ctx context.Context, productID uint, locationID *uint) (models.Quantity, error)
+
+	GetOpenAlert(ctx context.Context, ruleID uint) (*models.StockAlert, error)
+	GetAlerts(ctx context.Context, status *string) ([]models.StockAlert, error)
+	CreateAlert(ctx context.Context, alert *models.StockAlert) (bool, error)
+	UpdateAlert(ctx context.Context, alert *models.StockAlert) error
+	RecordDelivery(ctx context.Context, id uint, attempts int, deliveryErr error) error
+}
+
+type alertRepository struct {
+	db *gorm.DB
+}
+
+func NewAlertRepository(db *gorm.DB) AlertRepository {
+	return &alertRepository{db: db}
+}
+
+func (r *alertRepository) CreateRule(ctx context.Context, rule *models.ReorderRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *alertRepository) GetRule(ctx context.Context, id uint) (*models.ReorderRule, error) {
+	var rule models.ReorderRule
+	err := r.db.WithContext(ctx).Preload("Product").Preload("Location").First(&rule, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// FindRule returns the rule of a product for one location, or for all
+// locations when locationID is nil.
+func (r *alertRepository) FindRule(ctx context.Context, productID uint, locationID *uint) (*models.ReorderRule, error) {
+	query := r.db.WithContext(ctx).Where("product_id = ?", productID)
+	if locationID != nil {
+		query = query.Where("location_id = ?", *locationID)
+	} else {
+		query = query.Where("location_id IS NULL")
+	}
+
+	var rule models.ReorderRule
+	if err := query.First(&rule).Error; err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *alertRepository) GetRules(ctx context.Context, productID *uint) ([]models.ReorderRule, error) {
+	query := r.db.WithContext(ctx).Preload("Product").Preload("Location")
+	if productID != nil {
+		query = query.Where("product_id = ?", *productID)
+	}
+
+	var rules []models.ReorderRule
+	err := query.Order("product_id, id").Find(&rules).Error
+	return rules, err
+}
+
+// GetRulesForStock returns the rules affected by a stock change of a product
+// at a location: the rule for that location and the all-locations rule.
+func (r *alertRepository) GetRulesForStock(ctx context.Context, productID, locationID uint) ([]models.ReorderRule, error) {
+	var rules []models.ReorderRule
+	err := r.db.WithContext(ctx).Preload("Product").Preload("Location").
+		Where("product_id = ? AND (location_id = ? OR location_id IS NULL)", productID, locationID).
+		Find(&rules).Error
+	return rules, err
+}
+
+func (r *alertRepository) UpdateRule(ctx context.Context, rule *models.ReorderRule) error {
+	return r.db.WithContext(ctx).Omit("Product", "Location").Save(rule).Error
+}
+
+func (r *alertRepository) DeleteRule(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("rule_id = ?", id).Delete(&models.StockAlert{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.ReorderRule{}, id).Error
+	})
+}
+
+// GetAvailableQuantity returns on-hand minus live reserved stock of a product
+// at one location, or over all locations when locationID is nil.
+func (r *alertRepository) GetAvailableQuantity(ctx context.Context, productID uint, locationID *uint) (models.Quantity, error) {
+	return availableQuantity(r.db.WithContext(ctx), productID, locationID)
+}
+
+func availableQuantity(db *gorm.DB, productID uint, locationID *uint) (models.Quantity, error) {
+	onHandQuery := db.Model(&models.Stock{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("product_id = ?", productID)
+	reservedQuery := db.Model(&models.Reservation{}).
+		Select("COALESCE(SUM(quantity), 0)").
+		Where("product_id = ? AND status = ? AND expires_at > ?", productID, models.ReservationStatusActive, time.Now())
+	if locationID != nil {
+		onHandQuery = onHandQuery.Where("location_id = ?", *locationID)
+		reservedQuery = reservedQuery.Where("location_id = ?", *locationID)
+	}
+
+	var onHand, reserved models.Quantity
+	if err := onHandQuery.Scan(&onHand).Error; err != nil {
+		return 0, err
+	}
+	if err := reservedQuery.Scan(&reserved).Error; err != nil {
+		return 0, err
+	}
+	return onHand - reserved, nil
+}
+
+// GetOpenAlert returns the open alert of a rule, or nil when it has none.
+func (r *alertRepository) GetOpenAlert(ctx context.Context, ruleID uint) (*models.StockAlert, error) {
+	var alert models.StockAlert
+	err := r.db.WithContext(ctx).
+		Where("rule_id = ? AND status = ?", ruleID, models.AlertStatusOpen).
+		First(&alert).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (r *alertRepository) GetAlerts(ctx context.Context, status *string) ([]models.StockAlert, error) {
+	query := r.db.WithContext(ctx)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var alerts []models.StockAlert
+	err := query.Order("id DESC").Find(&alerts).Error
+	return alerts, err
+}
+
+// CreateAlert opens an alert. It reports false when the rule already has an
+// open alert, which a concurrent evaluation may have created first.
+func (r *alertRepository) CreateAlert(ctx context.Context, alert *models.StockAlert) (bool, error) {
+	result := r.db.WithContext(ctx).Omit("Rule").Clauses(clause.OnConflict{DoNothing: true}).Create(alert)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *alertRepository) UpdateAlert(ctx context.Context, alert *models.StockAlert) error {
+	return r.db.WithContext(ctx).Omit("Rule").Save(alert).Error
+}
+
+// RecordDelivery adds the webhook attempts made for an alert and stores their outcome.
+func (r *alertRepository) RecordDelivery(ctx context.Context, id uint, attempts int, deliveryErr error) error {
+	updates := map[string]interface{}{
+		"delivery_attempts": gorm.Expr("delivery_attempts + ?", attempts),
+		"last_error":        "",
+	}
+	if deliveryErr != nil {
+		updates["last_error"] = deliveryErr.Error()
+	} else {
+		updates["delivered_at"] = time.Now()
+	}
+	return r.db.WithContext(ctx).Model(&models.StockAlert{}).Where("id = ?", id).Updates(updates).Error
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"stock-management/internal/domain/models"
+
+	"gorm.io/gorm"
+)
+
+type BarcodeRepository interface {
+	AddBarcode(ctx context.Context, barcode *models.Barcode) error
+	GetBarcodes(ctx context.Context, productID uint) ([]models.Barcode, error)
+	DeleteBarcode(ctx context.Context, productID, id uint) error
+	FindProductByBarcode(ctx context.Context, codes []string) (*models.Product, error)
+}
+
+type barcodeRepository struct {
+	db *gorm.DB
+}
+
+func NewBarcodeRepository(db *gorm.DB) BarcodeRepository {
+	return &barcodeRepository{db: db}
+}
+
+// AddBarcode stores a barcode for a product. A code can only belong to one
+// product, and it may not be another product's SKU either, in any of the
+// forms it scans as.
+func (r *barcodeRepository) AddBarcode(ctx context.Context, barcode *models.Barcode) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		candidates := models.BarcodeCandidates(barcode.Code)
+		var taken int64
+		if err := tx.Model(&models.Barcode{}).Where("code IN ?", candidates).Count(&taken).Error; err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errors.New("barcode " + barcode.Code + " is already in use")
+		}
+		err := tx.Model(&models.Product{}).
+			Where("sku IN ? AND id <> ?", candidates, barcode.ProductID).
+			Count(&taken).Error
+		if err != nil {
+			return err
+		}
+		if taken > 0 {
+			return errors.New("barcode " + barcode.Code + " is the SKU of another product")
+		}
+		return tx.Create(barcode).Error
+	})
+}
+
+func (r *barcodeRepository) GetBarcodes(ctx context.Context, productID uint) ([]models.Barcode, error) {
+	var barcodes []models.Barcode
+	err := r.db.WithContext(ctx).Where("product_id = ?", productID).Order("id").Find(&barcodes).Error
+	return barcodes, err
+}
+
+func (r *barcodeRepository) DeleteBarcode(ctx context.Context, productID, id uint) error {
+	result := r.db.WithContext(ctx).Where("id = ? AND product_id = ?", id, productID).Delete(&models.Barcode{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("barcode not found")
+	}
+	return nil
+}
+
+// FindProductByBarcode returns the product one of the codes belongs to.
+// Products without barcodes are labelled with their SKU, so SKUs match too.
+// Codes matching several products are ErrAmbiguousBarcode.
+func (r *barcodeRepository) FindProductByBarcode(ctx context.Context, codes []string) (*models.Product, error) {
+	var products []models.Product
+	err := r.db.WithContext(ctx).
+		Preload("Category").
+		Preload("BaseUnit").
+		Preload("VariantAttributes.Attribute").
+		Preload("VariantAttributes.Value").
+		Where("id IN (?) OR sku IN ?", r.db.Model(&models.Barcode{}).Select("product_id").Where("code IN ?", codes), codes).
+		Limit(2).
+		Find(&products).Error
+	if err != nil {
+		return nil, err
+	}
+	switch len(products) {
+	case 0:
+		return nil, gorm.ErrRecordNotFound
+	case 1:
+		return &products[0], nil
+	}
+	return nil, ErrAmbiguousBarcode
+}
+
+// checkSKUNotBarcode refuses a SKU that scans as another product's barcode.
+func checkSKUNotBarcode(tx *gorm.DB, sku string, productID uint) error {
+	var taken int64
+	err := tx.Model(&models.Barcode{}).
+		Where("code IN ? AND product_id <> ?", models.BarcodeCandidates(sku), productID).
+		Count(&taken).Error
+	if err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errors.New("SKU " + sku + " is the barcode of another product")
+	}
+	return nil
+}
@@ -0,0 +1,306 @@
+package repositories
+
+import (
+	"errors"
+	"stock-management/internal/domain/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// costingMethod returns the costing method a product is valued with,
+// defaultMethod for products that do not choose one.
+func costingMethod(tx *gorm.DB, productID uint, defaultMethod string) (string, error) {
+	var product models.Product
+	if err := tx.Select("id", "costing_method").First(&product, productID).Error; err != nil {
+		return "", errors.New("product not found")
+	}
+	if product.CostingMethod == "" {
+		return defaultMethod, nil
+	}
+	return product.CostingMethod, nil
+}
+
+// priceMovement sets the cost of a movement before it is written. Outgoing
+// movements consume cost layers and carry the cost of the goods they took;
+// incoming movements keep the unit cost they were given, or when it is zero
+// take the cost the goods are already carried at.
+func priceMovement(tx *gorm.DB, movement *models.StockMovement, delta models.Quantity, defaultCosting string) error {
+	if delta == 0 {
+		return nil
+	}
+
+	if delta > 0 {
+		if movement.UnitCost <= 0 {
+			unitCost, err := incomingUnitCost(tx, movement)
+			if err != nil {
+				return err
+			}
+			movement.UnitCost = unitCost
+		}
+		totalCost, err := movement.UnitCost.MulQuantity(delta)
+		if err != nil {
+			return err
+		}
+		movement.TotalCost = totalCost
+		return nil
+	}
+
+	method, err := costingMethod(tx, movement.ProductID, defaultCosting)
+	if err != nil {
+		return err
+	}
+	cost, err := consumeCostLayers(tx, movement, -delta, method)
+	if err != nil {
+		return err
+	}
+	movement.TotalCost = cost
+	movement.UnitCost, err = cost.DivQuantity(-delta)
+	return err
+}
+
+// addCostLayer records the goods an incoming movement brought in. Under
+// weighted average they are merged into the location's single open layer.
+func addCostLayer(tx *gorm.DB, movement *models.StockMovement, quantity models.Quantity, defaultCosting string) error {
+	method, err := costingMethod(tx, movement.ProductID, defaultCosting)
+	if err != nil {
+		return err
+	}
+
+	if method == models.CostingAverage {
+		layers, err := lockOpenCostLayers(tx, movement.ProductID, movement.LocationID)
+		if err != nil {
+			return err
+		}
+		if len(layers) > 0 {
+			layers = append(layers, models.CostLayer{Remaining: quantity, UnitCost: movement.UnitCost})
+			_, err := mergeCostLayers(tx, layers)
+			return err
+		}
+	}
+
+	layer := &models.CostLayer{
+		ProductID:  movement.ProductID,
+		LocationID: movement.LocationID,
+		MovementID: &movement.ID,
+		ReceivedAt: movement.Date,
+		Quantity:   quantity,
+		Remaining:  quantity,
+		UnitCost:   movement.UnitCost,
+	}
+	return tx.Create(layer).Error
+}
+
+// consumeCostLayers takes quantity out of the open layers of the movement's
+// product and location and returns what the goods cost.
+func consumeCostLayers(tx *gorm.DB, movement *models.StockMovement, quantity models.Quantity, method string) (models.Money, error) {
+	layers, err := lockOpenCostLayers(tx, movement.ProductID, movement.LocationID)
+	if err != nil {
+		return 0, err
+	}
+
+	if method == models.CostingAverage && len(layers) > 1 {
+		// Left over from a time the product was valued FIFO
+		if layers, err = mergeCostLayers(tx, layers); err != nil {
+			return 0, err
+		}
+	}
+	if movement.ReversalOfID != nil {
+		reversedLayerFirst(layers, *movement.ReversalOfID)
+	}
+
+	before := make([]models.Quantity, len(layers))
+	for i := range layers {
+		before[i] = layers[i].Remaining
+	}
+	cost, remaining, err := drawCostLayers(layers, quantity)
+	if err != nil {
+		return 0, err
+	}
+	for i := range layers {
+		if layers[i].Remaining == before[i] {
+			continue
+		}
+		if err := tx.Model(&layers[i]).Update("remaining", layers[i].Remaining).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	if remaining > 0 {
+		// Stock without layers, e.g. booked before costs were tracked, goes
+		// at the last cost known for the product
+		unitCost, err := lastUnitCost(tx, movement.ProductID)
+		if err != nil {
+			return 0, err
+		}
+		value, err := unitCost.MulQuantity(remaining)
+		if err != nil {
+			return 0, err
+		}
+		cost += value
+	}
+	return cost, nil
+}
+
+// mergeCostLayers collapses layers into the first one at their weighted
+// average cost. Layers without an ID are incoming quantities being merged in.
+func mergeCostLayers(tx *gorm.DB, layers []models.CostLayer) ([]models.CostLayer, error) {
+	quantity, unitCost, err := averageCostLayers(layers)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := layers[0]
+	merged.Remaining = quantity
+	if quantity > 0 {
+		merged.UnitCost = unitCost
+	}
+	if err := tx.Model(&merged).Updates(map[string]any{"remaining": merged.Remaining, "unit_cost": merged.UnitCost}).Error; err != nil {
+		return nil, err
+	}
+	for _, layer := range layers[1:] {
+		if layer.ID == 0 {
+			continue
+		}
+		if err := tx.Model(&layer).Update("remaining", 0).Error; err != nil {
+			return nil, err
+		}
+	}
+	return []models.CostLayer{merged}, nil
+}
+
+// reversedLayerFirst moves the layer a receipt brought in to the front, so
+// that reversing the receipt takes back the very units it added. The other
+// layers keep their order.
+func reversedLayerFirst(layers []models.CostLayer, receiptID uint) {
+	for i, layer := range layers {
+		if layer.MovementID != nil && *layer.MovementID == receiptID {
+			copy(layers[1:i+1], layers[:i])
+			layers[0] = layer
+			return
+		}
+	}
+}
+
+// drawCostLayers takes quantity out of the layers in their order, lowering
+// their Remaining. It returns what the goods taken cost and the quantity the
+// layers could not cover.
+func drawCostLayers(layers []models.CostLayer, quantity models.Quantity) (models.Money, models.Quantity, error) {
+	var cost models.Money
+	for i := range layers {
+		if quantity == 0 {
+			break
+		}
+		take := min(layers[i].Remaining, quantity)
+		value, err := layers[i].UnitCost.MulQuantity(take)
+		if err != nil {
+			return 0, 0, err
+		}
+		layers[i].Remaining -= take
+		cost += value
+		quantity -= take
+	}
+	return cost, quantity, nil
+}
+
+// averageCostLayers returns the quantity left in the layers and its weighted
+// average unit cost, zero when nothing is left.
+func averageCostLayers(layers []models.CostLayer) (models.Quantity, models.Money, error) {
+	var quantity models.Quantity
+	var value models.Money
+	for _, layer := range layers {
+		layerValue, err := layer.UnitCost.MulQuantity(layer.Remaining)
+		if err != nil {
+			return 0, 0, err
+		}
+		quantity += layer.Remaining
+		value += layerValue
+	}
+	if quantity <= 0 {
+		return quantity, 0, nil
+	}
+	unitCost, err := value.DivQuantity(quantity)
+	return quantity, unitCost, err
+}
+
+func lockOpenCostLayers(tx *gorm.DB, productID, locationID uint) ([]models.CostLayer, error) {
+	var layers []models.CostLayer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND location_id = ? AND remaining > 0", productID, locationID).
+		Order("received_at, id").
+		Find(&layers).Error
+	return layers, err
+}
+
+// incomingUnitCost is the cost goods come in at when the movement names
+// none: transferred goods keep the cost they left the source at, a reversal
+// restores the original cost and anything else takes the current cost.
+func incomingUnitCost(tx *gorm.DB, movement *models.StockMovement) (models.Money, error) {
+	var source struct {
+		Quantity  models.Quantity
+		TotalCost models.Money
+	}
+	switch {
+	case movement.TransferLineID != nil:
+		err := tx.Model(&models.StockMovement{}).
+			Select("COALESCE(SUM(quantity), 0) AS quantity, COALESCE(SUM(total_cost), 0) AS total_cost").
+			Where("transfer_line_id = ? AND direction = ?", *movement.TransferLineID, models.MovementOut).
+			Scan(&source).Error
+		if err != nil {
+			return 0, err
+		}
+	case movement.ReversalOfID != nil:
+		err := tx.Model(&models.StockMovement{}).
+			Select("quantity, total_cost").
+			Where("id = ?", *movement.ReversalOfID).
+			Scan(&source).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	if source.Quantity > 0 {
+		return source.TotalCost.DivQuantity(source.Quantity)
+	}
+	return currentUnitCost(tx, movement.ProductID, movement.LocationID)
+}
+
+// currentUnitCost is the weighted average cost of the product's open layers
+// at the location, or across all locations when the location holds none,
+// falling back to the last known cost.
+func currentUnitCost(tx *gorm.DB, productID, locationID uint) (models.Money, error) {
+	for _, scope := range []func(*gorm.DB) *gorm.DB{
+		func(db *gorm.DB) *gorm.DB { return db.Where("location_id = ?", locationID) },
+		func(db *gorm.DB) *gorm.DB { return db },
+	} {
+		var open struct {
+			Quantity models.Quantity
+			Value    models.Money
+		}
+		err := tx.Model(&models.CostLayer{}).
+			Scopes(scope).
+			Select("COALESCE(SUM(remaining), 0) AS quantity, COALESCE(SUM(remaining * unit_cost), 0) AS value").
+			Where("product_id = ? AND remaining > 0", productID).
+			Scan(&open).Error
+		if err != nil {
+			return 0, err
+		}
+		if open.Quantity > 0 {
+			return open.Value.DivQuantity(open.Quantity)
+		}
+	}
+	return lastUnitCost(tx, productID)
+}
+
+// lastUnitCost is the cost of the product's most recent layer, or zero if it
+// was never costed.
+func lastUnitCost(tx *gorm.DB, productID uint) (models.Money, error) {
+	var layer models.CostLayer
+	err := tx.Where("product_id = ?", productID).Order("received_at DESC, id DESC").First(&layer).Error
+	if err == gorm.ErrRecordNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return layer.UnitCost, nil
+}
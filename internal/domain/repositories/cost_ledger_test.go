@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"stock-management/internal/domain/models"
+	"testing"
+)
+
+func costLayers(layers ...[2]int64) []models.CostLayer {
+	var result []models.CostLayer
+	for i, layer := range layers {
+		movementID := uint(i + 1)
+		result = append(result, models.CostLayer{
+			ID:         uint(i + 1),
+			MovementID: &movementID,
+			Remaining:  models.Quantity(layer[0]),
+			UnitCost:   models.Money(layer[1]),
+		})
+	}
+	return result
+}
+
+// TestDrawCostLayersFIFO takes goods out of layers oldest first, so the
+// cost is that of the oldest units still in stock.
+func TestDrawCostLayersFIFO(t *testing.T) {
+	tests := []struct {
+		name          string
+		quantity      models.Quantity
+		wantCost      models.Money
+		wantUncovered models.Quantity
+		wantRemaining []models.Quantity
+	}{
+		// 10 units at 2.00, 5 at 3.00, 5 at 4.00
+		{"within the first layer", models.Units(4), 80000, 0, []models.Quantity{6000, 5000, 5000}},
+		{"the whole first layer", models.Units(10), 200000, 0, []models.Quantity{0, 5000, 5000}},
+		{"across layers", models.Units(12), 260000, 0, []models.Quantity{0, 3000, 5000}},
+		{"all layers", models.Units(20), 550000, 0, []models.Quantity{0, 0, 0}},
+		{"more than the layers hold", models.Units(23), 550000, models.Units(3), []models.Quantity{0, 0, 0}},
+		{"a fraction", 1500, 30000, 0, []models.Quantity{8500, 5000, 5000}},
+	}
+	for _, tt := range tests {
+		layers := costLayers([2]int64{10000, 20000}, [2]int64{5000, 30000}, [2]int64{5000, 40000})
+		cost, uncovered, err := drawCostLayers(layers, tt.quantity)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if cost != tt.wantCost || uncovered != tt.wantUncovered {
+			t.Errorf("%s: cost %s, uncovered %s, want %s, %s", tt.name, cost, uncovered, tt.wantCost, tt.wantUncovered)
+		}
+		for i, layer := range layers {
+			if layer.Remaining != tt.wantRemaining[i] {
+				t.Errorf("%s: layer %d has %s left, want %s", tt.name, i+1, layer.Remaining, tt.wantRemaining[i])
+			}
+		}
+	}
+}
+
+// TestDrawCostLayersRounding rounds the cost of each layer's share half
+// away from zero.
+func TestDrawCostLayersRounding(t *testing.T) {
+	// Half a unit at 0.0003 is 0.00015 and half a unit at 0.0001 is
+	// 0.00005, each rounded up to the next ten-thousandth
+	layers := costLayers([2]int64{500, 3}, [2]int64{1000, 1})
+	cost, _, err := drawCostLayers(layers, models.Units(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost != 3 {
+		t.Errorf("cost %s, want 0.0003", cost)
+	}
+}
+
+// TestAverageCostLayers merges layers at their weighted average cost, as
+// weighted-average costing does on every receipt.
+func TestAverageCostLayers(t *testing.T) {
+	tests := []struct {
+		name         string
+		layers       []models.CostLayer
+		wantQuantity models.Quantity
+		wantUnitCost models.Money
+	}{
+		{"one layer", costLayers([2]int64{10000, 20000}), models.Units(10), 20000},
+		// 10 at 2.00 and 5 at 3.00 are 35.00 for 15 units
+		{"two layers", costLayers([2]int64{10000, 20000}, [2]int64{5000, 30000}), models.Units(15), 23333},
+		// 1 at 1.00 and 2 at 2.00 are 5.00 for 3 units, 1.66666... rounds up
+		{"rounded", costLayers([2]int64{1000, 10000}, [2]int64{2000, 20000}), models.Units(3), 16667},
+		{"fractional quantities", costLayers([2]int64{500, 40000}, [2]int64{1500, 20000}), models.Units(2), 25000},
+		{"nothing left", costLayers([2]int64{0, 20000}), 0, 0},
+	}
+	for _, tt := range tests {
+		quantity, unitCost, err := averageCostLayers(tt.layers)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if quantity != tt.wantQuantity || unitCost != tt.wantUnitCost {
+			t.Errorf("%s: %s at %s, want %s at %s", tt.name, quantity, unitCost, tt.wantQuantity, tt.wantUnitCost)
+		}
+	}
+}
+
+// TestReversedLayerFirst makes a reversed receipt take back its own units
+// first and the rest in FIFO order.
+func TestReversedLayerFirst(t *testing.T) {
+	// One unit each at 1.00, 2.00 and 3.00, the last brought in by movement 3
+	layers := costLayers([2]int64{1000, 10000}, [2]int64{1000, 20000}, [2]int64{1000, 30000})
+	reversedLayerFirst(layers, 3)
+
+	var order []uint
+	for _, layer := range layers {
+		order = append(order, *layer.MovementID)
+	}
+	if order[0] != 3 || order[1] != 1 || order[2] != 2 {
+		t.Fatalf("layer order %v, want [3 1 2]", order)
+	}
+
+	cost, _, err := drawCostLayers(layers, models.Units(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost != 40000 {
+		t.Errorf("cost %s, want 4", cost)
+	}
+
+	unchanged := costLayers([2]int64{1000, 10000})
+	reversedLayerFirst(unchanged, 9)
+	if *unchanged[0].MovementID != 1 {
+		t.Errorf("layers of other receipts were reordered")
+	}
+}
@@ -0,0 +1,349 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CountRepository interface {
+	CreateSession(ctx context.Context, session *models.CountSession) error
+	GetSession(ctx context.Context, id uint) (*models.CountSession, error)
+	GetSessions(ctx context.Context, status *string) ([]models.CountSession, error)
+	RecordCounts(ctx context.Context, id uint, lines []models.CountLine, userID uint) (*models.CountSession, error)
+	SetStatus(ctx context.Context, id uint, from, to string) (*models.CountSession, error)
+	ApproveLines(ctx context.Context, id uint, lineIDs []uint) (*models.CountSession, error)
+	PostSession(ctx context.Context, id uint, reasonCodeID uint, userID uint) (*models.CountSession, error)
+}
+
+type countRepository struct {
+	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
+}
+
+func NewCountRepository(db *gorm.DB, costingMethod string) CountRepository {
+	return &countRepository{db: db, costingMethod: costingMethod}
+}
+
+// CreateSession opens a count and snapshots the expected quantity of every
+// untracked product stocked within its scope. Lot- and serial-tracked stock
+// in the scope is returned as excluded rather than counted.
+func (r *countRepository) CreateSession(ctx context.Context, session *models.CountSession) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var stocks []models.Stock
+		err := countScope(tx, session).
+			Where("products.tracking_mode = ?", models.TrackingNone).
+			Order("stocks.location_id, stocks.product_id").
+			Find(&stocks).Error
+		if err != nil {
+			return err
+		}
+
+		session.Status = models.CountStatusCounting
+		session.Lines = make([]models.CountLine, 0, len(stocks))
+		for _, stock := range stocks {
+			session.Lines = append(session.Lines, models.CountLine{
+				ProductID:        stock.ProductID,
+				LocationID:       stock.LocationID,
+				ExpectedQuantity: stock.Quantity,
+			})
+		}
+		if err := tx.Create(session).Error; err != nil {
+			return err
+		}
+
+		session.Excluded, err = countExclusions(tx, session)
+		return err
+	})
+}
+
+func (r *countRepository) GetSession(ctx context.Context, id uint) (*models.CountSession, error) {
+	var session models.CountSession
+	err := r.db.WithContext(ctx).
+		Preload("Location").
+		Preload("Category").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("location_id, product_id") }).
+		Preload("Lines.Product").
+		Preload("Lines.Location").
+		Preload("Lines.Entries", func(db *gorm.DB) *gorm.DB { return db.Order("counted_at") }).
+		First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	if session.Status == models.CountStatusCounting || session.Status == models.CountStatusReview {
+		if session.Excluded, err = countExclusions(r.db.WithContext(ctx), &session); err != nil {
+			return nil, err
+		}
+	}
+	return &session, nil
+}
+
+// countScope selects the stock rows within a count's location and category,
+// joined with their products.
+func countScope(tx *gorm.DB, session *models.CountSession) *gorm.DB {
+	query := tx.Model(&models.Stock{}).
+		Joins("JOIN products ON products.id = stocks.product_id")
+	if session.LocationID != nil {
+		query = query.Where("stocks.location_id = ?", *session.LocationID)
+	}
+	if session.CategoryID != nil {
+		query = query.Where("products.category_id = ?", *session.CategoryID)
+	}
+	return query
+}
+
+// countExclusions lists the lot- and serial-tracked stock within a count's
+// scope, which the count cannot correct.
+func countExclusions(tx *gorm.DB, session *models.CountSession) ([]models.CountExclusion, error) {
+	var excluded []models.CountExclusion
+	err := countScope(tx, session).
+		Select("stocks.product_id, stocks.location_id, products.tracking_mode, stocks.quantity").
+		Where("products.tracking_mode <> ? AND stocks.quantity <> 0", models.TrackingNone).
+		Order("stocks.location_id, stocks.product_id").
+		Scan(&excluded).Error
+	return excluded, err
+}
+
+func (r *countRepository) GetSessions(ctx context.Context, status *string) ([]models.CountSession, error) {
+	query := r.db.WithContext(ctx).Preload("Location").Preload("Category")
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var sessions []models.CountSession
+	err := query.Order("id DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// RecordCounts stores what a counter found for each product and location.
+// The goods on the shelf already reflect every movement booked since the
+// count opened, so each count also takes the expected quantity from the
+// books at that moment. A count for a product the snapshot did not list,
+// for example goods found in the wrong bin, adds a line.
+func (r *countRepository) RecordCounts(ctx context.Context, id uint, lines []models.CountLine, userID uint) (*models.CountSession, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		session, err := lockCountSession(tx, id)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.CountStatusCounting {
+			return errors.New("count is in " + session.Status + ", counts can no longer be recorded")
+		}
+
+		now := time.Now()
+		for _, counted := range lines {
+			line, err := findCountLine(tx, session, counted.ProductID, counted.LocationID)
+			if err != nil {
+				return err
+			}
+
+			entry := &models.CountEntry{
+				CountLineID: line.ID,
+				UserID:      userID,
+				Quantity:    *counted.CountedQuantity,
+				CountedAt:   now,
+			}
+			if err := tx.Create(entry).Error; err != nil {
+				return err
+			}
+
+			stock, err := lockStock(tx, line.ProductID, line.LocationID, false)
+			if err != nil {
+				return err
+			}
+
+			// A recount replaces the earlier count and needs approving again
+			err = tx.Model(line).Updates(map[string]interface{}{
+				"expected_quantity": stock.Quantity,
+				"counted_quantity":  entry.Quantity,
+				"approved":          false,
+			}).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetSession(ctx, id)
+}
+
+// SetStatus moves a count from one status to the next, failing if the count
+// is not in the expected status.
+func (r *countRepository) SetStatus(ctx context.Context, id uint, from, to string) (*models.CountSession, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		session, err := lockCountSession(tx, id)
+		if err != nil {
+			return err
+		}
+		if session.Status != from {
+			return errors.New("count is in " + session.Status + ", expected " + from)
+		}
+		return tx.Model(session).Update("status", to).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetSession(ctx, id)
+}
+
+// ApproveLines approves the variances of the given counted lines; no line
+// IDs approves every counted line.
+func (r *countRepository) ApproveLines(ctx context.Context, id uint, lineIDs []uint) (*models.CountSession, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		session, err := lockCountSession(tx, id)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.CountStatusReview {
+			return errors.New("count is in " + session.Status + ", only counts under review can be approved")
+		}
+
+		query := tx.Model(&models.CountLine{}).Where("session_id = ? AND counted_quantity IS NOT NULL", id)
+		if len(lineIDs) > 0 {
+			query = query.Where("id IN ?", lineIDs)
+		}
+		result := query.Update("approved", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if len(lineIDs) > 0 && int(result.RowsAffected) != len(lineIDs) {
+			return fmt.Errorf("only counted lines of count %d can be approved", id)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetSession(ctx, id)
+}
+
+// PostSession books every approved variance as a count correction in one
+// transaction. The variance is measured against the books when the line was
+// counted, so movements made after the count are kept and movements made
+// before it are not booked a second time. If any correction fails, for
+// example because the stock has since been issued, nothing is posted.
+func (r *countRepository) PostSession(ctx context.Context, id uint, reasonCodeID uint, userID uint) (*models.CountSession, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		session, err := lockCountSession(tx, id)
+		if err != nil {
+			return err
+		}
+		if session.Status != models.CountStatusReview {
+			return errors.New("count is in " + session.Status + ", only reviewed counts can be posted")
+		}
+
+		var lines []models.CountLine
+		if err := tx.Where("session_id = ? AND approved", id).Order("id").Find(&lines).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := range lines {
+			line := &lines[i]
+			variance := line.Variance()
+			if variance == 0 {
+				continue
+			}
+
+			quantity := variance
+			if quantity < 0 {
+				quantity = -quantity
+			}
+			movement := &models.StockMovement{
+				ProductID:    line.ProductID,
+				LocationID:   line.LocationID,
+				UserID:       userID,
+				Type:         models.MovementCountCorrection,
+				Quantity:     quantity,
+				Date:         now,
+				Notes:        fmt.Sprintf("Count #%d: expected %s, counted %s", session.ID, line.ExpectedQuantity, *line.CountedQuantity),
+				ReasonCodeID: &reasonCodeID,
+				CountLineID:  &line.ID,
+			}
+			if err := applyStockChange(tx, movement, variance, r.costingMethod); err != nil {
+				return err
+			}
+		}
+
+		session.Status = models.CountStatusPosted
+		session.PostedByID = &userID
+		session.PostedAt = &now
+		return tx.Omit(clause.Associations).Save(session).Error
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetSession(ctx, id)
+}
+
+// findCountLine returns the session's line for a product at a location,
+// adding it when the product is within the count's scope but was not in the
+// snapshot.
+func findCountLine(tx *gorm.DB, session *models.CountSession, productID, locationID uint) (*models.CountLine, error) {
+	var line models.CountLine
+	err := tx.Where("session_id = ? AND product_id = ? AND location_id = ?", session.ID, productID, locationID).
+		First(&line).Error
+	if err == nil {
+		return &line, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	if session.LocationID != nil && *session.LocationID != locationID {
+		return nil, fmt.Errorf("location %d is not part of count %d", locationID, session.ID)
+	}
+	var product models.Product
+	if err := tx.First(&product, productID).Error; err != nil {
+		return nil, fmt.Errorf("product %d not found", productID)
+	}
+	if session.CategoryID != nil && *session.CategoryID != product.CategoryID {
+		return nil, fmt.Errorf("product %d is not part of count %d", productID, session.ID)
+	}
+	if product.TrackingMode != models.TrackingNone {
+		return nil, fmt.Errorf("product %d is lot or serial tracked and cannot be counted here", productID)
+	}
+	var location models.Location
+	if err := tx.First(&location, locationID).Error; err != nil {
+		return nil, fmt.Errorf("location %d not found", locationID)
+	}
+
+	stock, err := lockStock(tx, productID, locationID, false)
+	if err != nil {
+		return nil, err
+	}
+	line = models.CountLine{
+		SessionID:        session.ID,
+		ProductID:        productID,
+		LocationID:       locationID,
+		ExpectedQuantity: stock.Quantity,
+	}
+	if err := tx.Create(&line).Error; err != nil {
+		return nil, err
+	}
+	return &line, nil
+}
+
+// lockCountSession loads a count that is still open, holding a row lock so
+// status changes and postings are serialised.
+func lockCountSession(tx *gorm.DB, id uint) (*models.CountSession, error) {
+	var session models.CountSession
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&session, id).Error
+	if err != nil {
+		return nil, errors.New("count not found")
+	}
+	if session.Status == models.CountStatusPosted || session.Status == models.CountStatusCancelled {
+		return nil, errors.New("count is already " + session.Status)
+	}
+	return &session, nil
+}
@@ -0,0 +1,96 @@
+package repositories_test
+
+import (
+	"context"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"testing"
+	"time"
+)
+
+// TestPostCountSession counts a product short, reviews and approves the
+// variance and posts it. Goods issued after the count are left alone, and
+// lot-tracked stock in scope is listed as excluded instead of counted.
+func TestPostCountSession(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewCountRepository(db, models.CostingFIFO)
+	f := newStockFixture(t, db, "count")
+	reason := f.newReasonCode(t, models.MovementCountCorrection)
+
+	f.book(t, models.MovementReceipt, models.Units(10), 0)
+	lotTracked := f.newProduct(t, "count-lots", f.product.CategoryID, models.TrackingLot)
+	receipt := &models.StockMovement{
+		ProductID:  lotTracked.ID,
+		LocationID: f.location.ID,
+		UserID:     f.user.ID,
+		Type:       models.MovementReceipt,
+		Quantity:   models.Units(3),
+		Date:       time.Now(),
+		Lots:       []models.StockMovementLot{{Lot: &models.Lot{LotNumber: "L-" + f.suffix}, Quantity: models.Units(3)}},
+	}
+	if err := repositories.NewStockRepository(db, models.CostingFIFO).ApplyMovement(ctx, receipt, models.Units(3)); err != nil {
+		t.Fatalf("receive lot-tracked stock: %v", err)
+	}
+
+	// The fixture's category holds only its own products
+	session := models.CountSession{LocationID: &f.location.ID, CategoryID: &f.product.CategoryID, CreatedByID: f.user.ID}
+	if err := repo.CreateSession(ctx, &session); err != nil {
+		t.Fatalf("open count: %v", err)
+	}
+	if len(session.Lines) != 1 || session.Lines[0].ProductID != f.product.ID || session.Lines[0].ExpectedQuantity != models.Units(10) {
+		t.Fatalf("count lines = %+v, want the product expecting 10", session.Lines)
+	}
+	if len(session.Excluded) != 1 || session.Excluded[0].ProductID != lotTracked.ID || session.Excluded[0].Quantity != models.Units(3) {
+		t.Errorf("excluded = %+v, want the lot-tracked product's 3", session.Excluded)
+	}
+
+	counted := models.Units(7)
+	countLot := []models.CountLine{{ProductID: lotTracked.ID, LocationID: f.location.ID, CountedQuantity: &counted}}
+	if _, err := repo.RecordCounts(ctx, session.ID, countLot, f.user.ID); err == nil {
+		t.Errorf("a lot-tracked product was counted")
+	}
+	if _, err := repo.RecordCounts(ctx, session.ID, []models.CountLine{{ProductID: f.product.ID, LocationID: f.location.ID, CountedQuantity: &counted}}, f.user.ID); err != nil {
+		t.Fatalf("record count: %v", err)
+	}
+	f.book(t, models.MovementIssue, -models.Units(2), 0)
+
+	if _, err := repo.ApproveLines(ctx, session.ID, nil); err == nil {
+		t.Errorf("lines were approved while counting")
+	}
+	if _, err := repo.PostSession(ctx, session.ID, reason.ID, f.user.ID); err == nil {
+		t.Errorf("a count was posted while counting")
+	}
+	if _, err := repo.SetStatus(ctx, session.ID, models.CountStatusCounting, models.CountStatusReview); err != nil {
+		t.Fatalf("submit for review: %v", err)
+	}
+	if _, err := repo.RecordCounts(ctx, session.ID, []models.CountLine{{ProductID: f.product.ID, LocationID: f.location.ID, CountedQuantity: &counted}}, f.user.ID); err == nil {
+		t.Errorf("a count was recorded under review")
+	}
+	if _, err := repo.ApproveLines(ctx, session.ID, nil); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+
+	posted, err := repo.PostSession(ctx, session.ID, reason.ID, f.user.ID)
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	if posted.Status != models.CountStatusPosted || posted.PostedAt == nil {
+		t.Errorf("after posting: status %s, posted at %v", posted.Status, posted.PostedAt)
+	}
+	// 10 expected, 7 counted, 2 issued since: the 3 short come off the 8 left
+	if got := f.onHand(t); got != models.Units(5) {
+		t.Errorf("on hand = %s, want 5", got)
+	}
+	if _, err := repo.PostSession(ctx, session.ID, reason.ID, f.user.ID); err == nil {
+		t.Errorf("a count was posted twice")
+	}
+
+	var correction models.StockMovement
+	if err := db.Where("count_line_id = ?", posted.Lines[0].ID).First(&correction).Error; err != nil {
+		t.Fatalf("read correction: %v", err)
+	}
+	if correction.Type != models.MovementCountCorrection || correction.Direction != models.MovementOut || correction.Quantity != models.Units(3) {
+		t.Errorf("correction: %s %s of %s, want count_correction out of 3", correction.Type, correction.Direction, correction.Quantity)
+	}
+}
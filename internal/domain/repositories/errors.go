@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// ErrStockConflict is returned when a stock change lost a race against a
+// concurrent change (deadlock, lock timeout or serialization failure). The
+// change was rolled back and can safely be retried.
+var ErrStockConflict = errors.New("stock was changed concurrently, please retry")
+
+// ErrAmbiguousBarcode is returned when a scanned code matches more than one
+// product.
+var ErrAmbiguousBarcode = errors.New("barcode matches more than one product")
+
+// ErrAlreadyReversed is returned when reversing a movement that already has
+// a compensating movement.
+var ErrAlreadyReversed = errors.New("movement has already been reversed")
+
+// InsufficientStockError is returned when a stock change would drive the
+// on-hand quantity of a product at a location below zero. LocationID is zero
+// when the stock was looked for across locations.
+type InsufficientStockError struct {
+	ProductID  uint
+	LocationID uint
+	Available  models.Quantity
+	Requested  models.Quantity
+}
+
+func (e *InsufficientStockError) Error() string {
+	if e.LocationID == 0 {
+		return fmt.Sprintf("insufficient stock for product %d: %s available, %s requested",
+			e.ProductID, e.Available, e.Requested)
+	}
+	return fmt.Sprintf("insufficient stock for product %d at location %d: %s available, %s requested",
+		e.ProductID, e.LocationID, e.Available, e.Requested)
+}
+
+// translateStockError maps Postgres concurrency failures onto ErrStockConflict.
+func translateStockError(err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case "40001", "40P01", "55P03": // serialization_failure, deadlock_detected, lock_not_available
+			return ErrStockConflict
+		}
+	}
+	return err
+}
@@ -0,0 +1,81 @@
+package repositories
+
+import (
+	"context"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepository interface {
+	// Claim inserts the key unless a live record for it already exists. It
+	// returns true when the caller now owns the key, otherwise the existing record.
+	Claim(ctx context.Context, record *models.IdempotencyKey) (bool, *models.IdempotencyKey, error)
+	// Renew, Complete and Release act on a claim still in progress and
+	// report whether the caller held it. A claim whose lease ran out is
+	// deleted and claimed again under a new ID, so the ID fences off a
+	// holder that lost its claim.
+	Renew(ctx context.Context, id uint, expiresAt time.Time) (bool, error)
+	Complete(ctx context.Context, id uint, status int, body []byte, expiresAt time.Time) (bool, error)
+	Release(ctx context.Context, id uint) (bool, error)
+}
+
+type idempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) IdempotencyRepository {
+	return &idempotencyRepository{db: db}
+}
+
+func (r *idempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyKey) (bool, *models.IdempotencyKey, error) {
+	db := r.db.WithContext(ctx)
+
+	// Expired keys may be reused, as may claims whose lease ran out
+	err := db.Where("user_id = ? AND key = ? AND expires_at < ?", record.UserID, record.Key, time.Now()).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return false, nil, err
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	if result.Error != nil {
+		return false, nil, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return true, nil, nil
+	}
+
+	var existing models.IdempotencyKey
+	err = db.Where("user_id = ? AND key = ?", record.UserID, record.Key).First(&existing).Error
+	if err != nil {
+		return false, nil, err
+	}
+	return false, &existing, nil
+}
+
+func (r *idempotencyRepository) Renew(ctx context.Context, id uint, expiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("id = ? AND completed = ?", id, false).
+		Update("expires_at", expiresAt)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *idempotencyRepository) Complete(ctx context.Context, id uint, status int, body []byte, expiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&models.IdempotencyKey{}).
+		Where("id = ? AND completed = ?", id, false).
+		Updates(map[string]interface{}{
+			"completed":       true,
+			"response_status": status,
+			"response_body":   body,
+			"expires_at":      expiresAt,
+		})
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *idempotencyRepository) Release(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("id = ? AND completed = ?", id, false).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected == 1, result.Error
+}
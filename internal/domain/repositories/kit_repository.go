@@ -0,0 +1,267 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type KitRepository interface {
+	SetComponents(ctx context.Context, kitID uint, components []models.KitComponent) error
+	GetComponents(ctx context.Context, kitID uint) ([]models.KitComponent, error)
+	GetAvailableQuantity(ctx context.Context, productID uint, locationID *uint) (models.Quantity, error)
+	CreateKitBuild(ctx context.Context, build *models.KitBuild) error
+	GetKitBuild(ctx context.Context, id uint) (*models.KitBuild, error)
+}
+
+type kitRepository struct {
+	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
+}
+
+func NewKitRepository(db *gorm.DB, costingMethod string) KitRepository {
+	return &kitRepository{db: db, costingMethod: costingMethod}
+}
+
+// SetComponents replaces the bill of materials of a kit. Kits and their
+// components must not be lot or serial tracked, and a component may not
+// contain the kit itself at any depth.
+func (r *kitRepository) SetComponents(ctx context.Context, kitID uint, components []models.KitComponent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var kit models.Product
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&kit, kitID).Error; err != nil {
+			return errors.New("product not found")
+		}
+		if kit.TrackingMode != models.TrackingNone {
+			return errors.New("lot or serial tracked products cannot be kits")
+		}
+
+		for i := range components {
+			component := &components[i]
+			var product models.Product
+			if err := tx.First(&product, component.ComponentID).Error; err != nil {
+				return fmt.Errorf("component %d not found", component.ComponentID)
+			}
+			if product.TrackingMode != models.TrackingNone {
+				return fmt.Errorf("component %s is lot or serial tracked", product.SKU)
+			}
+			if !product.AllowsQuantity(component.Quantity) {
+				return fmt.Errorf("quantity %s of component %s has more than the %d decimals allowed", component.Quantity, product.SKU, product.QuantityDecimals)
+			}
+			if product.ID == kitID {
+				return errors.New("a kit cannot be its own component")
+			}
+			contains, err := kitContains(tx, product.ID, kitID, map[uint]bool{})
+			if err != nil {
+				return err
+			}
+			if contains {
+				return fmt.Errorf("component %s contains the kit", product.SKU)
+			}
+			component.KitID = kitID
+		}
+
+		if err := tx.Where("kit_id = ?", kitID).Delete(&models.KitComponent{}).Error; err != nil {
+			return err
+		}
+		if len(components) == 0 {
+			return nil
+		}
+		return tx.Create(&components).Error
+	})
+}
+
+// kitContains reports whether productID is in the bill of materials of
+// kitID, at any depth.
+func kitContains(tx *gorm.DB, kitID, productID uint, seen map[uint]bool) (bool, error) {
+	var componentIDs []uint
+	err := tx.Model(&models.KitComponent{}).Where("kit_id = ?", kitID).Pluck("component_id", &componentIDs).Error
+	if err != nil {
+		return false, err
+	}
+	for _, componentID := range componentIDs {
+		if componentID == productID {
+			return true, nil
+		}
+		if seen[componentID] {
+			continue
+		}
+		seen[componentID] = true
+		contains, err := kitContains(tx, componentID, productID, seen)
+		if err != nil || contains {
+			return contains, err
+		}
+	}
+	return false, nil
+}
+
+func (r *kitRepository) GetComponents(ctx context.Context, kitID uint) ([]models.KitComponent, error) {
+	var components []models.KitComponent
+	err := r.db.WithContext(ctx).
+		Preload("Component", unscoped).
+		Where("kit_id = ?", kitID).
+		Order("id").
+		Find(&components).Error
+	return components, err
+}
+
+func (r *kitRepository) GetAvailableQuantity(ctx context.Context, productID uint, locationID *uint) (models.Quantity, error) {
+	return availableQuantity(r.db.WithContext(ctx), productID, locationID)
+}
+
+// CreateKitBuild assembles or disassembles build.Quantity kits at a location
+// in one transaction. Assembly takes the components out and books the kit in
+// at their cost; disassembly takes the kit out and spreads its cost over the
+// components by what they are currently carried at.
+func (r *kitRepository) CreateKitBuild(ctx context.Context, build *models.KitBuild) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var components []models.KitComponent
+		if err := tx.Preload("Component").Where("kit_id = ?", build.KitID).Order("id").Find(&components).Error; err != nil {
+			return err
+		}
+		if len(components) == 0 {
+			return errors.New("product has no bill of materials")
+		}
+		if err := tx.Create(build).Error; err != nil {
+			return err
+		}
+
+		movementType := models.MovementAssembly
+		if build.Type == models.KitBuildDisassembly {
+			movementType = models.MovementDisassembly
+		}
+		notes := fmt.Sprintf("Kit build #%d: %s", build.ID, build.Type)
+		if build.Notes != "" {
+			notes += ", " + build.Notes
+		}
+		newMovement := func(productID uint) *models.StockMovement {
+			return &models.StockMovement{
+				ProductID:  productID,
+				LocationID: build.LocationID,
+				UserID:     build.UserID,
+				Type:       movementType,
+				Date:       time.Now(),
+				Notes:      notes,
+				KitBuildID: &build.ID,
+			}
+		}
+
+		quantities := make([]models.Quantity, len(components))
+		for i, component := range components {
+			quantity, err := build.Quantity.Mul(component.Quantity)
+			if err != nil {
+				return err
+			}
+			quantities[i] = quantity
+			if quantities[i] == 0 {
+				return fmt.Errorf("quantity %s is too small to use any of component %d", build.Quantity, component.ComponentID)
+			}
+		}
+
+		kitMovement := newMovement(build.KitID)
+		kitMovement.Quantity = build.Quantity
+		if build.Type == models.KitBuildAssembly {
+			var cost models.Money
+			for i, component := range components {
+				movement := newMovement(component.ComponentID)
+				movement.Quantity = quantities[i]
+				if err := applyStockChange(tx, movement, -quantities[i], r.costingMethod); err != nil {
+					return err
+				}
+				cost += movement.TotalCost
+			}
+			unitCost, err := cost.DivQuantity(build.Quantity)
+			if err != nil {
+				return err
+			}
+			kitMovement.UnitCost = unitCost
+			return applyStockChange(tx, kitMovement, build.Quantity, r.costingMethod)
+		}
+
+		if err := applyStockChange(tx, kitMovement, -build.Quantity, r.costingMethod); err != nil {
+			return err
+		}
+		unitCosts, err := componentUnitCosts(tx, build.LocationID, components, quantities, kitMovement.TotalCost)
+		if err != nil {
+			return err
+		}
+		for i, component := range components {
+			movement := newMovement(component.ComponentID)
+			movement.Quantity = quantities[i]
+			movement.UnitCost = unitCosts[i]
+			if err := applyStockChange(tx, movement, quantities[i], r.costingMethod); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return translateStockError(err)
+}
+
+// componentUnitCosts spreads the cost of a disassembled kit over its
+// components by what they are currently carried at, see spreadKitCost.
+func componentUnitCosts(tx *gorm.DB, locationID uint, components []models.KitComponent, quantities []models.Quantity, kitCost models.Money) ([]models.Money, error) {
+	values := make([]models.Money, len(components))
+	for i, component := range components {
+		unitCost, err := currentUnitCost(tx, component.ComponentID, locationID)
+		if err != nil {
+			return nil, err
+		}
+		if values[i], err = unitCost.MulQuantity(quantities[i]); err != nil {
+			return nil, err
+		}
+	}
+	return spreadKitCost(kitCost, values, quantities)
+}
+
+// spreadKitCost returns the unit cost of each component of a disassembled
+// kit. The kit's cost is shared in proportion to the components' values, or
+// to their quantities when none of them carries a cost yet.
+func spreadKitCost(kitCost models.Money, values []models.Money, quantities []models.Quantity) ([]models.Money, error) {
+	weights := make([]int64, len(values))
+	var total int64
+	for i, value := range values {
+		weights[i] = int64(value)
+		total += weights[i]
+	}
+	if total == 0 {
+		for i := range weights {
+			weights[i] = int64(quantities[i])
+			total += weights[i]
+		}
+	}
+
+	unitCosts := make([]models.Money, len(values))
+	for i := range values {
+		cost, err := kitCost.Share(weights[i], total)
+		if err != nil {
+			return nil, err
+		}
+		if unitCosts[i], err = cost.DivQuantity(quantities[i]); err != nil {
+			return nil, err
+		}
+	}
+	return unitCosts, nil
+}
+
+func (r *kitRepository) GetKitBuild(ctx context.Context, id uint) (*models.KitBuild, error) {
+	var build models.KitBuild
+	err := r.db.WithContext(ctx).
+		Preload("Kit", unscoped).
+		Preload("Location").
+		Preload("Movements", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Movements.Product", unscoped).
+		Preload("Movements.Location.Warehouse").
+		Preload("Movements.User").
+		First(&build, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &build, nil
+}
@@ -0,0 +1,66 @@
+package repositories
+
+import (
+	"stock-management/internal/domain/models"
+	"testing"
+)
+
+func TestSpreadKitCost(t *testing.T) {
+	tests := []struct {
+		name       string
+		kitCost    models.Money
+		values     []models.Money
+		quantities []models.Quantity
+		want       []models.Money
+	}{
+		{
+			// 2 units worth 6.00 and 4 worth 4.00: the first gets 60% of 20.00
+			name:       "by value",
+			kitCost:    200000,
+			values:     []models.Money{60000, 40000},
+			quantities: []models.Quantity{models.Units(2), models.Units(4)},
+			want:       []models.Money{60000, 20000},
+		},
+		{
+			name:       "by quantity without costs",
+			kitCost:    90000,
+			values:     []models.Money{0, 0},
+			quantities: []models.Quantity{models.Units(1), models.Units(2)},
+			want:       []models.Money{30000, 30000},
+		},
+		{
+			// A third of 10.00 is 3.33333..., rounded to 3.3333
+			name:       "rounded",
+			kitCost:    100000,
+			values:     []models.Money{10000, 10000, 10000},
+			quantities: []models.Quantity{models.Units(1), models.Units(1), models.Units(1)},
+			want:       []models.Money{33333, 33333, 33333},
+		},
+		{
+			name:       "fractional quantities",
+			kitCost:    30000,
+			values:     []models.Money{10000, 20000},
+			quantities: []models.Quantity{500, 250},
+			want:       []models.Money{20000, 80000},
+		},
+		{
+			name:       "a kit without cost",
+			kitCost:    0,
+			values:     []models.Money{10000},
+			quantities: []models.Quantity{models.Units(3)},
+			want:       []models.Money{0},
+		},
+	}
+	for _, tt := range tests {
+		got, err := spreadKitCost(tt.kitCost, tt.values, tt.quantities)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: unit costs %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// applyLotChange keeps the per-lot quantities of a lot-tracked product in
+// step with a stock change. Incoming movements must name their lots; outgoing
+// movements without lots are allocated first-expired-first-out. The lots
+// used are left on movement.Lots so they are recorded with the movement.
+func applyLotChange(tx *gorm.DB, movement *models.StockMovement, delta models.Quantity) error {
+	if delta < 0 && len(movement.Lots) == 0 {
+		lots, err := allocateLotsFEFO(tx, movement.ProductID, movement.LocationID, -delta)
+		if err != nil {
+			return err
+		}
+		movement.Lots = lots
+		return nil
+	}
+	if len(movement.Lots) == 0 {
+		return errors.New("lot number is required for lot-tracked products")
+	}
+
+	var total models.Quantity
+	for i := range movement.Lots {
+		movementLot := &movement.Lots[i]
+		if movementLot.LotID == 0 {
+			lot, err := resolveLot(tx, movement.ProductID, movementLot.Lot)
+			if err != nil {
+				return err
+			}
+			movementLot.LotID = lot.ID
+		}
+		// Only the reference is stored with the movement
+		movementLot.Lot = nil
+
+		change := movementLot.Quantity
+		if delta < 0 {
+			change = -change
+		}
+		if err := changeLotStock(tx, movementLot.LotID, movement.LocationID, change); err != nil {
+			return err
+		}
+		total += movementLot.Quantity
+	}
+
+	if total != delta.Abs() {
+		return errors.New("lot quantities must add up to the movement quantity")
+	}
+	return nil
+}
+
+// resolveLot finds the product's lot with the given number, creating it on
+// first receipt. A known lot number must keep its original expiry date.
+func resolveLot(tx *gorm.DB, productID uint, lot *models.Lot) (*models.Lot, error) {
+	if lot == nil || lot.LotNumber == "" {
+		return nil, errors.New("lot number is required for lot-tracked products")
+	}
+
+	var existing models.Lot
+	err := tx.Where("product_id = ? AND lot_number = ?", productID, lot.LotNumber).First(&existing).Error
+	if err == nil {
+		if !sameDay(existing.ExpiryDate, lot.ExpiryDate) {
+			return nil, fmt.Errorf("lot %s already exists with a different expiry date", lot.LotNumber)
+		}
+		return &existing, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+
+	created := &models.Lot{ProductID: productID, LotNumber: lot.LotNumber, ExpiryDate: lot.ExpiryDate}
+	if err := tx.Create(created).Error; err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// changeLotStock adds delta to the quantity of a lot at a location.
+func changeLotStock(tx *gorm.DB, lotID, locationID uint, delta models.Quantity) error {
+	var lotStock models.LotStock
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("lot_id = ? AND location_id = ?", lotID, locationID).
+		First(&lotStock).Error
+	if err == gorm.ErrRecordNotFound && delta > 0 {
+		lotStock = models.LotStock{LotID: lotID, LocationID: locationID, Quantity: delta}
+		return tx.Create(&lotStock).Error
+	}
+	if err == gorm.ErrRecordNotFound {
+		return fmt.Errorf("lot %d holds no stock at location %d", lotID, locationID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if lotStock.Quantity+delta < 0 {
+		return fmt.Errorf("lot %d holds only %s at location %d", lotID, lotStock.Quantity, locationID)
+	}
+	return tx.Model(&lotStock).Update("quantity", lotStock.Quantity+delta).Error
+}
+
+// allocateLotsFEFO takes quantity out of the product's lots at a location,
+// earliest expiry first. Expired lots are never picked.
+func allocateLotsFEFO(tx *gorm.DB, productID, locationID uint, quantity models.Quantity) ([]models.StockMovementLot, error) {
+	var lotStocks []models.LotStock
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "lot_stocks"}}).
+		Joins("JOIN lots ON lots.id = lot_stocks.lot_id").
+		Where("lots.product_id = ? AND lot_stocks.location_id = ? AND lot_stocks.quantity > 0", productID, locationID).
+		Where("lots.expiry_date IS NULL OR lots.expiry_date >= ?", startOfDay(time.Now())).
+		Order("lots.expiry_date ASC NULLS LAST, lots.id").
+		Find(&lotStocks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var allocations []models.StockMovementLot
+	remaining := quantity
+	for _, lotStock := range lotStocks {
+		if remaining == 0 {
+			break
+		}
+		take := min(lotStock.Quantity, remaining)
+		if err := tx.Model(&lotStock).Update("quantity", lotStock.Quantity-take).Error; err != nil {
+			return nil, err
+		}
+		allocations = append(allocations, models.StockMovementLot{LotID: lotStock.LotID, Quantity: take})
+		remaining -= take
+	}
+
+	if remaining > 0 {
+		return nil, &InsufficientStockError{
+			ProductID:  productID,
+			LocationID: locationID,
+			Available:  quantity - remaining,
+			Requested:  quantity,
+		}
+	}
+	return allocations, nil
+}
+
+// takeTransferLots picks, earliest expiry first, which of the lots still in
+// transit on a transfer line make up quantity. It returns nil for products
+// that are not lot tracked.
+func takeTransferLots(tx *gorm.DB, transferLineID uint, quantity models.Quantity) ([]models.StockMovementLot, error) {
+	var inTransit []struct {
+		LotID    uint
+		Quantity models.Quantity
+	}
+	err := tx.Table("stock_movement_lots").
+		Select("stock_movement_lots.lot_id, SUM(CASE WHEN stock_movements.direction = 'out' THEN stock_movement_lots.quantity ELSE -stock_movement_lots.quantity END) AS quantity").
+		Joins("JOIN stock_movements ON stock_movements.id = stock_movement_lots.stock_movement_id").
+		Joins("JOIN lots ON lots.id = stock_movement_lots.lot_id").
+		Where("stock_movements.transfer_line_id = ? AND stock_movements.deleted_at IS NULL", transferLineID).
+		Group("stock_movement_lots.lot_id, lots.expiry_date, lots.id").
+		Order("lots.expiry_date ASC NULLS LAST, lots.id").
+		Scan(&inTransit).Error
+	if err != nil || len(inTransit) == 0 {
+		return nil, err
+	}
+
+	var lots []models.StockMovementLot
+	remaining := quantity
+	for _, lot := range inTransit {
+		if remaining == 0 {
+			break
+		}
+		if lot.Quantity <= 0 {
+			continue
+		}
+		take := min(lot.Quantity, remaining)
+		lots = append(lots, models.StockMovementLot{LotID: lot.LotID, Quantity: take})
+		remaining -= take
+	}
+	return lots, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func sameDay(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return startOfDay(*a).Equal(startOfDay(b.In(a.Location())))
+}
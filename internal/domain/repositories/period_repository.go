@@ -0,0 +1,146 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type PeriodRepository interface {
+	ClosePeriod(ctx context.Context, period *models.ClosedPeriod) error
+	ReopenPeriod(ctx context.Context, year, month int) error
+	GetClosedPeriods(ctx context.Context) ([]models.ClosedPeriod, error)
+	IsPeriodClosed(ctx context.Context, date time.Time) (bool, error)
+	TakeSnapshot(ctx context.Context, day time.Time) (int, error)
+	HasSnapshot(ctx context.Context, day time.Time) (bool, error)
+	GetSnapshots(ctx context.Context, day time.Time, locationID *uint) ([]models.StockSnapshot, error)
+}
+
+type periodRepository struct {
+	db *gorm.DB
+}
+
+func NewPeriodRepository(db *gorm.DB) PeriodRepository {
+	return &periodRepository{db: db}
+}
+
+func (r *periodRepository) ClosePeriod(ctx context.Context, period *models.ClosedPeriod) error {
+	var existing int64
+	err := r.db.WithContext(ctx).Model(&models.ClosedPeriod{}).
+		Where("year = ? AND month = ?", period.Year, period.Month).
+		Count(&existing).Error
+	if err != nil {
+		return err
+	}
+	if existing > 0 {
+		return errors.New("period is already closed")
+	}
+	return r.db.WithContext(ctx).Create(period).Error
+}
+
+func (r *periodRepository) ReopenPeriod(ctx context.Context, year, month int) error {
+	result := r.db.WithContext(ctx).Where("year = ? AND month = ?", year, month).Delete(&models.ClosedPeriod{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("period is not closed")
+	}
+	return nil
+}
+
+func (r *periodRepository) GetClosedPeriods(ctx context.Context) ([]models.ClosedPeriod, error) {
+	var periods []models.ClosedPeriod
+	err := r.db.WithContext(ctx).Order("year DESC, month DESC").Find(&periods).Error
+	return periods, err
+}
+
+func (r *periodRepository) IsPeriodClosed(ctx context.Context, date time.Time) (bool, error) {
+	var closed int64
+	err := r.db.WithContext(ctx).Model(&models.ClosedPeriod{}).
+		Where("year = ? AND month = ?", date.Year(), int(date.Month())).
+		Count(&closed).Error
+	return closed > 0, err
+}
+
+// TakeSnapshot records the live stock and its value under day and returns
+// the number of rows written. Stock changes are held off while it reads: the
+// stocks table is share-locked before anything is read, which waits for
+// changes in flight and blocks new ones until the snapshot is written. So
+// every movement is either in the snapshot or created after AsOf, including
+// receipts into stock rows that did not exist yet. Stock and cost layers are
+// read in one repeatable-read snapshot.
+func (r *periodRepository) TakeSnapshot(ctx context.Context, day time.Time) (int, error) {
+	var written int
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE stocks IN SHARE MODE").Error; err != nil {
+			return err
+		}
+		asOf := time.Now()
+
+		var stocks []models.Stock
+		err := tx.Where("quantity <> 0").Order("product_id, location_id").Find(&stocks).Error
+		if err != nil {
+			return err
+		}
+
+		var values []struct {
+			ProductID  uint
+			LocationID uint
+			Value      models.Money
+		}
+		err = tx.Model(&models.CostLayer{}).
+			Select("product_id, location_id, SUM(remaining * unit_cost) AS value").
+			Where("remaining > 0").
+			Group("product_id, location_id").
+			Scan(&values).Error
+		if err != nil {
+			return err
+		}
+		valueOf := make(map[[2]uint]models.Money, len(values))
+		for _, value := range values {
+			valueOf[[2]uint{value.ProductID, value.LocationID}] = value.Value
+		}
+
+		snapshots := make([]models.StockSnapshot, 0, len(stocks))
+		for _, stock := range stocks {
+			snapshots = append(snapshots, models.StockSnapshot{
+				SnapshotDate: day,
+				AsOf:         asOf,
+				ProductID:    stock.ProductID,
+				LocationID:   stock.LocationID,
+				Quantity:     stock.Quantity,
+				Value:        valueOf[[2]uint{stock.ProductID, stock.LocationID}],
+			})
+		}
+		written = len(snapshots)
+		if written == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(snapshots, 500).Error
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead})
+	return written, err
+}
+
+func (r *periodRepository) HasSnapshot(ctx context.Context, day time.Time) (bool, error) {
+	var taken int64
+	err := r.db.WithContext(ctx).Model(&models.StockSnapshot{}).
+		Where("snapshot_date = ?", day).
+		Count(&taken).Error
+	return taken > 0, err
+}
+
+func (r *periodRepository) GetSnapshots(ctx context.Context, day time.Time, locationID *uint) ([]models.StockSnapshot, error) {
+	query := r.db.WithContext(ctx).Where("snapshot_date = ?", day)
+	if locationID != nil {
+		query = query.Where("location_id = ?", *locationID)
+	}
+
+	var snapshots []models.StockSnapshot
+	err := query.Order("product_id, location_id").Find(&snapshots).Error
+	return snapshots, err
+}
@@ -0,0 +1,315 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PurchaseRepository interface {
+	CreateSupplier(ctx context.Context, supplier *models.Supplier) error
+	GetSupplier(ctx context.Context, id uint) (*models.Supplier, error)
+	GetSuppliers(ctx context.Context) ([]models.Supplier, error)
+	UpdateSupplier(ctx context.Context, supplier *models.Supplier) error
+	DeleteSupplier(ctx context.Context, id uint) error
+
+	CreatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder) error
+	GetPurchaseOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error)
+	GetPurchaseOrders(ctx context.Context, status *string, supplierID *uint) ([]models.PurchaseOrder, error)
+	UpdatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder) error
+	DeletePurchaseOrder(ctx context.Context, id uint) error
+	ApprovePurchaseOrder(ctx context.Context, id uint, userID uint) (*models.PurchaseOrder, error)
+	ReceivePurchaseOrder(ctx context.Context, id uint, receipts map[uint]models.PurchaseReceipt, userID uint) (*models.PurchaseOrder, error)
+	ClosePurchaseOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error)
+}
+
+type purchaseRepository struct {
+	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
+}
+
+func NewPurchaseRepository(db *gorm.DB, costingMethod string) PurchaseRepository {
+	return &purchaseRepository{db: db, costingMethod: costingMethod}
+}
+
+func (r *purchaseRepository) CreateSupplier(ctx context.Context, supplier *models.Supplier) error {
+	return r.db.WithContext(ctx).Create(supplier).Error
+}
+
+func (r *purchaseRepository) GetSupplier(ctx context.Context, id uint) (*models.Supplier, error) {
+	var supplier models.Supplier
+	err := r.db.WithContext(ctx).First(&supplier, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &supplier, nil
+}
+
+func (r *purchaseRepository) GetSuppliers(ctx context.Context) ([]models.Supplier, error) {
+	var suppliers []models.Supplier
+	err := r.db.WithContext(ctx).Order("code").Find(&suppliers).Error
+	return suppliers, err
+}
+
+func (r *purchaseRepository) UpdateSupplier(ctx context.Context, supplier *models.Supplier) error {
+	return r.db.WithContext(ctx).Save(supplier).Error
+}
+
+func (r *purchaseRepository) DeleteSupplier(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Keep suppliers that orders still point to
+		var orders int64
+		if err := tx.Model(&models.PurchaseOrder{}).Where("supplier_id = ?", id).Count(&orders).Error; err != nil {
+			return err
+		}
+		if orders > 0 {
+			return errors.New("supplier has purchase orders")
+		}
+
+		return tx.Delete(&models.Supplier{}, id).Error
+	})
+}
+
+func (r *purchaseRepository) CreatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder) error {
+	order.Status = models.PurchaseOrderStatusDraft
+	return r.db.WithContext(ctx).Create(order).Error
+}
+
+func (r *purchaseRepository) GetPurchaseOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := r.db.WithContext(ctx).
+		Preload("Supplier").
+		Preload("Location").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Lines.Product").
+		First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *purchaseRepository) GetPurchaseOrders(ctx context.Context, status *string, supplierID *uint) ([]models.PurchaseOrder, error) {
+	query := r.db.WithContext(ctx).
+		Preload("Supplier").
+		Preload("Location").
+		Preload("Lines")
+
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if supplierID != nil {
+		query = query.Where("supplier_id = ?", *supplierID)
+	}
+
+	var orders []models.PurchaseOrder
+	err := query.Order("id DESC").Find(&orders).Error
+	return orders, err
+}
+
+// UpdatePurchaseOrder replaces the header and lines of a draft order.
+func (r *purchaseRepository) UpdatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockPurchaseOrder(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		if existing.Status != models.PurchaseOrderStatusDraft {
+			return errors.New("only draft purchase orders can be changed")
+		}
+
+		if err := tx.Where("purchase_order_id = ?", order.ID).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+			return err
+		}
+		for i := range order.Lines {
+			order.Lines[i].ID = 0
+			order.Lines[i].PurchaseOrderID = order.ID
+		}
+		if len(order.Lines) > 0 {
+			if err := tx.Create(&order.Lines).Error; err != nil {
+				return err
+			}
+		}
+
+		order.Status = existing.Status
+		order.CreatedByID = existing.CreatedByID
+		order.CreatedAt = existing.CreatedAt
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+}
+
+func (r *purchaseRepository) DeletePurchaseOrder(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockPurchaseOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.PurchaseOrderStatusDraft {
+			return errors.New("only draft purchase orders can be deleted")
+		}
+
+		if err := tx.Where("purchase_order_id = ?", id).Delete(&models.PurchaseOrderLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.PurchaseOrder{}, id).Error
+	})
+}
+
+func (r *purchaseRepository) ApprovePurchaseOrder(ctx context.Context, id uint, userID uint) (*models.PurchaseOrder, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockPurchaseOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.PurchaseOrderStatusDraft {
+			return errors.New("purchase order is already " + order.Status)
+		}
+		if len(order.Lines) == 0 {
+			return errors.New("purchase order needs at least one line")
+		}
+
+		now := time.Now()
+		order.Status = models.PurchaseOrderStatusApproved
+		order.ApprovedByID = &userID
+		order.ApprovedAt = &now
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetPurchaseOrder(ctx, id)
+}
+
+// ReceivePurchaseOrder books the given quantities per line ID into the
+// order's location as import movements linked to the lines. An empty receipts
+// map receives everything outstanding.
+func (r *purchaseRepository) ReceivePurchaseOrder(ctx context.Context, id uint, receipts map[uint]models.PurchaseReceipt, userID uint) (*models.PurchaseOrder, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockPurchaseOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.PurchaseOrderStatusApproved && order.Status != models.PurchaseOrderStatusPartiallyReceived {
+			return errors.New("purchase order is " + order.Status + ", only approved orders can be received")
+		}
+
+		if len(receipts) == 0 {
+			receipts = make(map[uint]models.PurchaseReceipt)
+			for _, line := range order.Lines {
+				if line.Outstanding() > 0 {
+					receipts[line.ID] = models.PurchaseReceipt{Quantity: line.Outstanding()}
+				}
+			}
+		}
+
+		lineIDs := make(map[uint]bool, len(order.Lines))
+		for _, line := range order.Lines {
+			lineIDs[line.ID] = true
+		}
+		for lineID := range receipts {
+			if !lineIDs[lineID] {
+				return fmt.Errorf("line %d does not belong to purchase order %d", lineID, order.ID)
+			}
+		}
+
+		now := time.Now()
+		for i := range order.Lines {
+			line := &order.Lines[i]
+			receipt, ok := receipts[line.ID]
+			if !ok {
+				continue
+			}
+
+			if receipt.Quantity > line.Outstanding() {
+				return fmt.Errorf("line %d: cannot receive %s, only %s outstanding", line.ID, receipt.Quantity, line.Outstanding())
+			}
+
+			line.ReceivedQuantity += receipt.Quantity
+			if err := tx.Omit(clause.Associations).Save(line).Error; err != nil {
+				return err
+			}
+
+			movement := &models.StockMovement{
+				ProductID:           line.ProductID,
+				LocationID:          order.LocationID,
+				UserID:              userID,
+				Type:                models.MovementReceipt,
+				Quantity:            receipt.Quantity,
+				Date:                now,
+				Notes:               fmt.Sprintf("Purchase order #%d received", order.ID),
+				UnitCost:            line.UnitCost,
+				PurchaseOrderLineID: &line.ID,
+				Lots:                receipt.Lots,
+				Serials:             receipt.Serials,
+			}
+			if err := applyStockChange(tx, movement, receipt.Quantity, r.costingMethod); err != nil {
+				return err
+			}
+		}
+
+		order.Status = models.PurchaseOrderStatusPartiallyReceived
+		if purchaseOrderOutstanding(order) == 0 {
+			order.Status = models.PurchaseOrderStatusClosed
+			order.ClosedAt = &now
+		}
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetPurchaseOrder(ctx, id)
+}
+
+// ClosePurchaseOrder closes an approved order short; whatever is still
+// outstanding will not be received.
+func (r *purchaseRepository) ClosePurchaseOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockPurchaseOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.PurchaseOrderStatusApproved && order.Status != models.PurchaseOrderStatusPartiallyReceived {
+			return errors.New("purchase order is " + order.Status + ", only approved orders can be closed")
+		}
+
+		now := time.Now()
+		order.Status = models.PurchaseOrderStatusClosed
+		order.ClosedAt = &now
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetPurchaseOrder(ctx, id)
+}
+
+// lockPurchaseOrder loads a purchase order with its lines, holding a row lock
+// on the order so concurrent receipts cannot both book the same line.
+func lockPurchaseOrder(tx *gorm.DB, id uint) (*models.PurchaseOrder, error) {
+	var order models.PurchaseOrder
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error
+	if err != nil {
+		return nil, errors.New("purchase order not found")
+	}
+	if order.Status == models.PurchaseOrderStatusClosed {
+		return nil, errors.New("purchase order is already closed")
+	}
+	if err := tx.Where("purchase_order_id = ?", id).Order("id").Find(&order.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func purchaseOrderOutstanding(order *models.PurchaseOrder) models.Quantity {
+	var total models.Quantity
+	for _, line := range order.Lines {
+		total += line.Outstanding()
+	}
+	return total
+}
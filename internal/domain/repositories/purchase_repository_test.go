@@ -0,0 +1,88 @@
+package repositories_test
+
+import (
+	"context"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"testing"
+)
+
+// TestReceivePurchaseOrder walks an order from draft through a partial
+// receipt to closed, refusing receipts its status or the outstanding
+// quantity do not allow.
+func TestReceivePurchaseOrder(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewPurchaseRepository(db, models.CostingFIFO)
+	f := newStockFixture(t, db, "purchase")
+
+	supplier := models.Supplier{Code: "SUP-" + f.suffix, Name: "Supplier " + f.suffix}
+	if err := repo.CreateSupplier(ctx, &supplier); err != nil {
+		t.Fatalf("create supplier: %v", err)
+	}
+	order := models.PurchaseOrder{
+		SupplierID:  supplier.ID,
+		LocationID:  f.location.ID,
+		CreatedByID: f.user.ID,
+		// 2.50 in ten-thousandths
+		Lines: []models.PurchaseOrderLine{{ProductID: f.product.ID, Quantity: models.Units(10), UnitCost: 2_5000}},
+	}
+	if err := repo.CreatePurchaseOrder(ctx, &order); err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	lineID := order.Lines[0].ID
+
+	if _, err := repo.ReceivePurchaseOrder(ctx, order.ID, nil, f.user.ID); err == nil {
+		t.Errorf("a draft order was received")
+	}
+	if _, err := repo.ApprovePurchaseOrder(ctx, order.ID, f.user.ID); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if _, err := repo.ApprovePurchaseOrder(ctx, order.ID, f.user.ID); err == nil {
+		t.Errorf("an approved order was approved again")
+	}
+
+	partial := map[uint]models.PurchaseReceipt{lineID: {Quantity: models.Units(4)}}
+	received, err := repo.ReceivePurchaseOrder(ctx, order.ID, partial, f.user.ID)
+	if err != nil {
+		t.Fatalf("receive 4: %v", err)
+	}
+	if received.Status != models.PurchaseOrderStatusPartiallyReceived || received.Lines[0].ReceivedQuantity != models.Units(4) {
+		t.Errorf("after receiving 4: status %s, received %s", received.Status, received.Lines[0].ReceivedQuantity)
+	}
+
+	tooMany := map[uint]models.PurchaseReceipt{lineID: {Quantity: models.Units(7)}}
+	if _, err := repo.ReceivePurchaseOrder(ctx, order.ID, tooMany, f.user.ID); err == nil {
+		t.Errorf("received 7 with only 6 outstanding")
+	}
+	if got := f.onHand(t); got != models.Units(4) {
+		t.Errorf("on hand after a refused receipt = %s, want 4", got)
+	}
+
+	received, err = repo.ReceivePurchaseOrder(ctx, order.ID, nil, f.user.ID)
+	if err != nil {
+		t.Fatalf("receive the rest: %v", err)
+	}
+	if received.Status != models.PurchaseOrderStatusClosed || received.ClosedAt == nil {
+		t.Errorf("after receiving everything: status %s, closed at %v", received.Status, received.ClosedAt)
+	}
+	if got := f.onHand(t); got != models.Units(10) {
+		t.Errorf("on hand = %s, want 10", got)
+	}
+	if _, err := repo.ReceivePurchaseOrder(ctx, order.ID, nil, f.user.ID); err == nil {
+		t.Errorf("a closed order was received")
+	}
+
+	var movements []models.StockMovement
+	if err := db.Where("purchase_order_line_id = ?", lineID).Order("id").Find(&movements).Error; err != nil {
+		t.Fatalf("read movements: %v", err)
+	}
+	if len(movements) != 2 {
+		t.Fatalf("%d receipt movements, want 2", len(movements))
+	}
+	for _, movement := range movements {
+		if movement.Type != models.MovementReceipt || movement.UnitCost != 2_5000 {
+			t.Errorf("movement %d: %s at %s, want a receipt at 2.5", movement.ID, movement.Type, movement.UnitCost)
+		}
+	}
+}
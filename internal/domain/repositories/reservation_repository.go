@@ -0,0 +1,145 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReservationRepository interface {
+	CreateReservation(ctx context.Context, reservation *models.Reservation) error
+	GetReservation(ctx context.Context, id uint) (*models.Reservation, error)
+	GetReservations(ctx context.Context, productID *uint, status *string) ([]models.Reservation, error)
+	ReleaseReservation(ctx context.Context, id uint) error
+	ExpireReservations(ctx context.Context, now time.Time) ([]models.Reservation, error)
+}
+
+type reservationRepository struct {
+	db *gorm.DB
+}
+
+func NewReservationRepository(db *gorm.DB) ReservationRepository {
+	return &reservationRepository{db: db}
+}
+
+// CreateReservation holds stock if enough of it is available. The stock row
+// is locked while checking so reservations and exports cannot overbook.
+func (r *reservationRepository) CreateReservation(ctx context.Context, reservation *models.Reservation) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		stock, err := lockStock(tx, reservation.ProductID, reservation.LocationID, false)
+		if err != nil {
+			return err
+		}
+
+		reserved, err := reservedQuantity(tx, reservation.ProductID, reservation.LocationID)
+		if err != nil {
+			return err
+		}
+		if stock.Quantity-reserved < reservation.Quantity {
+			return &InsufficientStockError{
+				ProductID:  reservation.ProductID,
+				LocationID: reservation.LocationID,
+				Available:  stock.Quantity - reserved,
+				Requested:  reservation.Quantity,
+			}
+		}
+
+		reservation.Status = models.ReservationStatusActive
+		return tx.Create(reservation).Error
+	})
+	return translateStockError(err)
+}
+
+func (r *reservationRepository) GetReservation(ctx context.Context, id uint) (*models.Reservation, error) {
+	var reservation models.Reservation
+	err := r.db.WithContext(ctx).Preload("Product").Preload("Location").First(&reservation, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reservation, nil
+}
+
+func (r *reservationRepository) GetReservations(ctx context.Context, productID *uint, status *string) ([]models.Reservation, error) {
+	query := r.db.WithContext(ctx).Preload("Product").Preload("Location")
+	if productID != nil {
+		query = query.Where("product_id = ?", *productID)
+	}
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var reservations []models.Reservation
+	err := query.Order("id DESC").Find(&reservations).Error
+	return reservations, err
+}
+
+func (r *reservationRepository) ReleaseReservation(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Model(&models.Reservation{}).
+		Where("id = ? AND status = ?", id, models.ReservationStatusActive).
+		Update("status", models.ReservationStatusReleased)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("reservation is not active")
+	}
+	return nil
+}
+
+// ExpireReservations marks every active reservation past its expiry as
+// expired, which returns its stock to the available quantity, and returns
+// the reservations it expired.
+func (r *reservationRepository) ExpireReservations(ctx context.Context, now time.Time) ([]models.Reservation, error) {
+	var expired []models.Reservation
+	err := r.db.WithContext(ctx).Model(&expired).
+		Clauses(clause.Returning{}).
+		Where("status = ? AND expires_at <= ?", models.ReservationStatusActive, now).
+		Update("status", models.ReservationStatusExpired).Error
+	return expired, err
+}
+
+// reservedQuantity sums the live reservations of a product at a location.
+// Reservations past their expiry no longer count even before the sweeper
+// has marked them expired.
+func reservedQuantity(tx *gorm.DB, productID, locationID uint) (models.Quantity, error) {
+	var reserved models.Quantity
+	err := tx.Model(&models.Reservation{}).
+		Where("product_id = ? AND location_id = ? AND status = ? AND expires_at > ?",
+			productID, locationID, models.ReservationStatusActive, time.Now()).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&reserved).Error
+	return reserved, err
+}
+
+// consumeReservation draws quantity from an active reservation for an export
+// of the same product at the same location. It returns how much of the
+// reservation was used; the rest stays reserved.
+func consumeReservation(tx *gorm.DB, id uint, movement *models.StockMovement, quantity models.Quantity) (models.Quantity, error) {
+	var reservation models.Reservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&reservation, id).Error
+	if err != nil {
+		return 0, errors.New("reservation not found")
+	}
+	if reservation.Status != models.ReservationStatusActive || !reservation.ExpiresAt.After(time.Now()) {
+		return 0, errors.New("reservation is not active")
+	}
+	if reservation.ProductID != movement.ProductID || reservation.LocationID != movement.LocationID {
+		return 0, errors.New("reservation is for a different product or location")
+	}
+
+	used := min(reservation.Quantity, quantity)
+	updates := map[string]interface{}{"quantity": reservation.Quantity - used}
+	if used == reservation.Quantity {
+		updates["status"] = models.ReservationStatusConsumed
+		// Keep the reserved quantity on record once fully consumed
+		delete(updates, "quantity")
+	}
+	if err := tx.Model(&reservation).Updates(updates).Error; err != nil {
+		return 0, err
+	}
+	return used, nil
+}
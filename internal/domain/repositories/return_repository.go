@@ -0,0 +1,369 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReturnRepository interface {
+	CreateReturn(ctx context.Context, rma *models.ReturnAuthorization) error
+	GetReturn(ctx context.Context, id uint) (*models.ReturnAuthorization, error)
+	GetReturns(ctx context.Context, status *string) ([]models.ReturnAuthorization, error)
+	ReceiveReturn(ctx context.Context, id uint, receipts map[uint]models.Quantity) (*models.ReturnAuthorization, error)
+	InspectReturn(ctx context.Context, id uint, inspections map[uint]models.ReturnInspection, userID uint) (*models.ReturnAuthorization, error)
+	CancelReturn(ctx context.Context, id uint) (*models.ReturnAuthorization, error)
+}
+
+type returnRepository struct {
+	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
+}
+
+func NewReturnRepository(db *gorm.DB, costingMethod string) ReturnRepository {
+	return &returnRepository{db: db, costingMethod: costingMethod}
+}
+
+// CreateReturn authorizes a return against an export movement or a sales
+// order. No more can be authorized than left with the source, counting
+// every earlier return that was not cancelled.
+func (r *returnRepository) CreateReturn(ctx context.Context, rma *models.ReturnAuthorization) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if rma.MovementID != nil {
+			err = checkMovementReturn(tx, *rma.MovementID, rma.Lines)
+		} else {
+			err = checkSalesOrderReturn(tx, *rma.SalesOrderID, rma.Lines)
+		}
+		if err != nil {
+			return err
+		}
+
+		rma.Status = models.ReturnStatusAuthorized
+		return tx.Create(rma).Error
+	})
+}
+
+func (r *returnRepository) GetReturn(ctx context.Context, id uint) (*models.ReturnAuthorization, error) {
+	var rma models.ReturnAuthorization
+	err := r.db.WithContext(ctx).
+		Preload("Location").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Lines.Product").
+		First(&rma, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &rma, nil
+}
+
+func (r *returnRepository) GetReturns(ctx context.Context, status *string) ([]models.ReturnAuthorization, error) {
+	query := r.db.WithContext(ctx).Preload("Location").Preload("Lines")
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var returns []models.ReturnAuthorization
+	err := query.Order("id DESC").Find(&returns).Error
+	return returns, err
+}
+
+// ReceiveReturn puts the given quantities per line ID into quarantine. The
+// goods do not count as stock until inspection restocks them. An empty
+// receipts map receives everything still expected.
+func (r *returnRepository) ReceiveReturn(ctx context.Context, id uint, receipts map[uint]models.Quantity) (*models.ReturnAuthorization, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rma, err := lockReturn(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if len(receipts) == 0 {
+			receipts = make(map[uint]models.Quantity)
+			for _, line := range rma.Lines {
+				if line.Expected() > 0 {
+					receipts[line.ID] = line.Expected()
+				}
+			}
+		}
+		lineIDs := returnLineIDs(rma)
+		for lineID := range receipts {
+			if !lineIDs[lineID] {
+				return fmt.Errorf("line %d does not belong to return %d", lineID, rma.ID)
+			}
+		}
+
+		for i := range rma.Lines {
+			line := &rma.Lines[i]
+			quantity, ok := receipts[line.ID]
+			if !ok {
+				continue
+			}
+			if quantity > line.Expected() {
+				return fmt.Errorf("line %d: cannot receive %s, only %s expected", line.ID, quantity, line.Expected())
+			}
+
+			line.ReceivedQuantity += quantity
+			if err := tx.Omit(clause.Associations).Save(line).Error; err != nil {
+				return err
+			}
+		}
+
+		rma.Status = models.ReturnStatusReceived
+		return tx.Omit(clause.Associations).Save(rma).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetReturn(ctx, id)
+}
+
+// InspectReturn settles quarantined goods per line ID. Restocked goods are
+// booked into the return's location as return movements; written off goods
+// never re-enter stock. The return closes once everything authorized has
+// been received and inspected.
+func (r *returnRepository) InspectReturn(ctx context.Context, id uint, inspections map[uint]models.ReturnInspection, userID uint) (*models.ReturnAuthorization, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rma, err := lockReturn(tx, id)
+		if err != nil {
+			return err
+		}
+		lineIDs := returnLineIDs(rma)
+		for lineID := range inspections {
+			if !lineIDs[lineID] {
+				return fmt.Errorf("line %d does not belong to return %d", lineID, rma.ID)
+			}
+		}
+
+		now := time.Now()
+		for i := range rma.Lines {
+			line := &rma.Lines[i]
+			inspection, ok := inspections[line.ID]
+			if !ok {
+				continue
+			}
+			if inspected := inspection.RestockQuantity + inspection.WriteOffQuantity; inspected > line.Quarantined() {
+				return fmt.Errorf("line %d: cannot inspect %s, only %s in quarantine", line.ID, inspected, line.Quarantined())
+			}
+
+			line.RestockedQuantity += inspection.RestockQuantity
+			line.WrittenOffQuantity += inspection.WriteOffQuantity
+			if inspection.Notes != "" {
+				line.InspectionNotes = inspection.Notes
+			}
+			if err := tx.Omit(clause.Associations).Save(line).Error; err != nil {
+				return err
+			}
+
+			if inspection.RestockQuantity == 0 {
+				continue
+			}
+			movement := &models.StockMovement{
+				ProductID:    line.ProductID,
+				LocationID:   rma.LocationID,
+				UserID:       userID,
+				Type:         models.MovementReturn,
+				Quantity:     inspection.RestockQuantity,
+				Date:         now,
+				Notes:        fmt.Sprintf("Return #%d restocked after inspection", rma.ID),
+				ReturnLineID: &line.ID,
+				Lots:         inspection.Lots,
+				Serials:      inspection.Serials,
+			}
+			if err := applyStockChange(tx, movement, inspection.RestockQuantity, r.costingMethod); err != nil {
+				return err
+			}
+		}
+
+		settled := true
+		for _, line := range rma.Lines {
+			if line.Expected() > 0 || line.Quarantined() > 0 {
+				settled = false
+			}
+		}
+		if settled {
+			rma.Status = models.ReturnStatusClosed
+			rma.ClosedAt = &now
+		}
+		return tx.Omit(clause.Associations).Save(rma).Error
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetReturn(ctx, id)
+}
+
+// CancelReturn withdraws an authorization before any goods arrived.
+func (r *returnRepository) CancelReturn(ctx context.Context, id uint) (*models.ReturnAuthorization, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rma, err := lockReturn(tx, id)
+		if err != nil {
+			return err
+		}
+		if rma.Status != models.ReturnStatusAuthorized {
+			return errors.New("goods have already been received against this return")
+		}
+
+		now := time.Now()
+		rma.Status = models.ReturnStatusCancelled
+		rma.ClosedAt = &now
+		return tx.Omit(clause.Associations).Save(rma).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetReturn(ctx, id)
+}
+
+// checkMovementReturn makes sure the lines only return the product of the
+// export movement, and no more of it than left. A reversed issue has
+// nothing left to return. A movement that shipped a sales order line also
+// counts against that line, so the same goods cannot come back once through
+// the movement and again through the order.
+func checkMovementReturn(tx *gorm.DB, movementID uint, lines []models.ReturnLine) error {
+	var movement models.StockMovement
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&movement, movementID).Error
+	if err != nil {
+		return errors.New("movement not found")
+	}
+	// A reversal keeps the type of the movement it undoes, so an issue is
+	// told apart from the reversal of one by its direction
+	if movement.Type != models.MovementIssue || movement.Direction != models.MovementOut || movement.ReversalOfID != nil {
+		return errors.New("only issue movements can be returned")
+	}
+	var reversals int64
+	if err := tx.Model(&models.StockMovement{}).Where("reversal_of_id = ?", movementID).Count(&reversals).Error; err != nil {
+		return err
+	}
+	if reversals > 0 {
+		return fmt.Errorf("movement %d has been reversed, its goods are already back", movementID)
+	}
+
+	var quantity models.Quantity
+	for _, line := range lines {
+		if line.ProductID != movement.ProductID {
+			return fmt.Errorf("product %d was not part of movement %d", line.ProductID, movementID)
+		}
+		quantity += line.Quantity
+	}
+
+	var returned models.Quantity
+	err = tx.Model(&models.ReturnLine{}).
+		Joins("JOIN return_authorizations ON return_authorizations.id = return_lines.return_authorization_id").
+		Where("return_authorizations.movement_id = ? AND return_authorizations.status <> ?", movementID, models.ReturnStatusCancelled).
+		Select("COALESCE(SUM(return_lines.quantity), 0)").
+		Scan(&returned).Error
+	if err != nil {
+		return err
+	}
+	if returned+quantity > movement.Quantity {
+		return fmt.Errorf("cannot return %s, only %s left with movement %d", quantity, movement.Quantity-returned, movementID)
+	}
+
+	if movement.SalesOrderLineID == nil {
+		return nil
+	}
+	var orderLine models.SalesOrderLine
+	if err := tx.First(&orderLine, *movement.SalesOrderLineID).Error; err != nil {
+		return err
+	}
+	// Serialise with returns raised against the order itself
+	var order models.SalesOrder
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderLine.SalesOrderID).Error; err != nil {
+		return err
+	}
+	if err := checkSalesOrderLineReturn(tx, orderLine, quantity); err != nil {
+		return err
+	}
+	for i := range lines {
+		lines[i].SalesOrderLineID = &orderLine.ID
+	}
+	return nil
+}
+
+// checkSalesOrderReturn links each line to the sales order line of its
+// product and makes sure no more is returned than was shipped.
+func checkSalesOrderReturn(tx *gorm.DB, salesOrderID uint, lines []models.ReturnLine) error {
+	var order models.SalesOrder
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, salesOrderID).Error
+	if err != nil {
+		return errors.New("sales order not found")
+	}
+
+	var orderLines []models.SalesOrderLine
+	if err := tx.Where("sales_order_id = ?", salesOrderID).Find(&orderLines).Error; err != nil {
+		return err
+	}
+	byProduct := make(map[uint]models.SalesOrderLine, len(orderLines))
+	for _, orderLine := range orderLines {
+		byProduct[orderLine.ProductID] = orderLine
+	}
+
+	for i := range lines {
+		line := &lines[i]
+		orderLine, ok := byProduct[line.ProductID]
+		if !ok {
+			return fmt.Errorf("product %d was not ordered on sales order %d", line.ProductID, salesOrderID)
+		}
+		line.SalesOrderLineID = &orderLine.ID
+
+		if err := checkSalesOrderLineReturn(tx, orderLine, line.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSalesOrderLineReturn makes sure quantity more can come back of what
+// a sales order line shipped. Earlier returns count whether they were raised
+// against the order or against one of the movements that shipped the line.
+func checkSalesOrderLineReturn(tx *gorm.DB, orderLine models.SalesOrderLine, quantity models.Quantity) error {
+	shipments := tx.Model(&models.StockMovement{}).Select("id").Where("sales_order_line_id = ?", orderLine.ID)
+
+	var returned models.Quantity
+	err := tx.Model(&models.ReturnLine{}).
+		Joins("JOIN return_authorizations ON return_authorizations.id = return_lines.return_authorization_id").
+		Where("return_authorizations.status <> ?", models.ReturnStatusCancelled).
+		Where("return_lines.sales_order_line_id = ? OR return_authorizations.movement_id IN (?)", orderLine.ID, shipments).
+		Select("COALESCE(SUM(return_lines.quantity), 0)").
+		Scan(&returned).Error
+	if err != nil {
+		return err
+	}
+	if returned+quantity > orderLine.ShippedQuantity {
+		return fmt.Errorf("cannot return %s of product %d, only %s shipped and not yet returned",
+			quantity, orderLine.ProductID, orderLine.ShippedQuantity-returned)
+	}
+	return nil
+}
+
+func returnLineIDs(rma *models.ReturnAuthorization) map[uint]bool {
+	lineIDs := make(map[uint]bool, len(rma.Lines))
+	for _, line := range rma.Lines {
+		lineIDs[line.ID] = true
+	}
+	return lineIDs
+}
+
+// lockReturn loads an open return with its lines, holding a row lock on the
+// return so concurrent receipts and inspections cannot double count.
+func lockReturn(tx *gorm.DB, id uint) (*models.ReturnAuthorization, error) {
+	var rma models.ReturnAuthorization
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&rma, id).Error
+	if err != nil {
+		return nil, errors.New("return not found")
+	}
+	if rma.Status == models.ReturnStatusClosed || rma.Status == models.ReturnStatusCancelled {
+		return nil, errors.New("return is already " + rma.Status)
+	}
+	if err := tx.Where("return_authorization_id = ?", id).Order("id").Find(&rma.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &rma, nil
+}
@@ -0,0 +1,88 @@
+package repositories_test
+
+import (
+	"context"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"testing"
+)
+
+// TestReceiveAndInspectReturn takes goods back into quarantine, where they
+// are not stock, and restocks or writes them off on inspection.
+func TestReceiveAndInspectReturn(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewReturnRepository(db, models.CostingFIFO)
+	stockRepo := repositories.NewStockRepository(db, models.CostingFIFO)
+	f := newStockFixture(t, db, "return")
+
+	f.book(t, models.MovementReceipt, models.Units(10), 0)
+	issue := f.book(t, models.MovementIssue, -models.Units(5), 0)
+
+	rma := models.ReturnAuthorization{
+		MovementID:  &issue.ID,
+		LocationID:  f.location.ID,
+		CreatedByID: f.user.ID,
+		Lines:       []models.ReturnLine{{ProductID: f.product.ID, Quantity: models.Units(3)}},
+	}
+	if err := repo.CreateReturn(ctx, &rma); err != nil {
+		t.Fatalf("authorize return: %v", err)
+	}
+	lineID := rma.Lines[0].ID
+
+	tooMany := models.ReturnAuthorization{
+		MovementID:  &issue.ID,
+		LocationID:  f.location.ID,
+		CreatedByID: f.user.ID,
+		Lines:       []models.ReturnLine{{ProductID: f.product.ID, Quantity: models.Units(3)}},
+	}
+	if err := repo.CreateReturn(ctx, &tooMany); err == nil {
+		t.Errorf("authorized 6 returns of an issue of 5")
+	}
+	if _, err := stockRepo.ReverseMovement(ctx, issue.ID, f.user.ID, ""); err == nil {
+		t.Errorf("an issue on a customer return was reversed")
+	}
+
+	restock := map[uint]models.ReturnInspection{lineID: {RestockQuantity: models.Units(1)}}
+	if _, err := repo.InspectReturn(ctx, rma.ID, restock, f.user.ID); err == nil {
+		t.Errorf("inspected goods that have not arrived")
+	}
+
+	received, err := repo.ReceiveReturn(ctx, rma.ID, map[uint]models.Quantity{lineID: models.Units(3)})
+	if err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+	if received.Status != models.ReturnStatusReceived || received.Lines[0].Quarantined() != models.Units(3) {
+		t.Errorf("after receiving: status %s, quarantined %s", received.Status, received.Lines[0].Quarantined())
+	}
+	if got := f.onHand(t); got != models.Units(5) {
+		t.Errorf("on hand with goods in quarantine = %s, want 5", got)
+	}
+	if _, err := repo.CancelReturn(ctx, rma.ID); err == nil {
+		t.Errorf("a received return was cancelled")
+	}
+
+	settle := map[uint]models.ReturnInspection{lineID: {RestockQuantity: models.Units(2), WriteOffQuantity: models.Units(1)}}
+	inspected, err := repo.InspectReturn(ctx, rma.ID, settle, f.user.ID)
+	if err != nil {
+		t.Fatalf("inspect: %v", err)
+	}
+	line := inspected.Lines[0]
+	if inspected.Status != models.ReturnStatusClosed || line.RestockedQuantity != models.Units(2) || line.WrittenOffQuantity != models.Units(1) {
+		t.Errorf("after inspecting: status %s, restocked %s, written off %s", inspected.Status, line.RestockedQuantity, line.WrittenOffQuantity)
+	}
+	if got := f.onHand(t); got != models.Units(7) {
+		t.Errorf("on hand = %s, want 7 with 2 restocked", got)
+	}
+	if _, err := repo.ReceiveReturn(ctx, rma.ID, nil); err == nil {
+		t.Errorf("a closed return was received")
+	}
+
+	var restocked models.StockMovement
+	if err := db.Where("return_line_id = ?", lineID).First(&restocked).Error; err != nil {
+		t.Fatalf("read restock movement: %v", err)
+	}
+	if restocked.Type != models.MovementReturn || restocked.Quantity != models.Units(2) {
+		t.Errorf("restock movement: %s of %s, want a return of 2", restocked.Type, restocked.Quantity)
+	}
+}
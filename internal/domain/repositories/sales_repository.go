@@ -0,0 +1,446 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SalesRepository interface {
+	CreateSalesOrder(ctx context.Context, order *models.SalesOrder) error
+	GetSalesOrder(ctx context.Context, id uint) (*models.SalesOrder, error)
+	GetSalesOrders(ctx context.Context, status *string, customerReference *string) ([]models.SalesOrder, error)
+	UpdateSalesOrder(ctx context.Context, order *models.SalesOrder) error
+	DeleteSalesOrder(ctx context.Context, id uint) error
+	ConfirmSalesOrder(ctx context.Context, id uint, userID uint, reserveUntil time.Time) (*models.SalesOrder, error)
+	ReserveSalesOrder(ctx context.Context, id uint, userID uint, reserveUntil time.Time) (*models.SalesOrder, error)
+	ShipSalesOrder(ctx context.Context, id uint, shipments []models.ShipmentLine, userID uint) (*models.SalesOrder, error)
+	CancelSalesOrder(ctx context.Context, id uint) (*models.SalesOrder, error)
+}
+
+type salesRepository struct {
+	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
+}
+
+func NewSalesRepository(db *gorm.DB, costingMethod string) SalesRepository {
+	return &salesRepository{db: db, costingMethod: costingMethod}
+}
+
+func (r *salesRepository) CreateSalesOrder(ctx context.Context, order *models.SalesOrder) error {
+	order.Status = models.SalesOrderStatusDraft
+	return r.db.WithContext(ctx).Create(order).Error
+}
+
+func (r *salesRepository) GetSalesOrder(ctx context.Context, id uint) (*models.SalesOrder, error) {
+	var order models.SalesOrder
+	err := r.db.WithContext(ctx).
+		Preload("Warehouse").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Lines.Product").
+		Preload("Lines.Reservations", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Lines.Reservations.Location.Warehouse").
+		First(&order, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func (r *salesRepository) GetSalesOrders(ctx context.Context, status *string, customerReference *string) ([]models.SalesOrder, error) {
+	query := r.db.WithContext(ctx).
+		Preload("Warehouse").
+		Preload("Lines")
+
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+	if customerReference != nil {
+		query = query.Where("customer_reference = ?", *customerReference)
+	}
+
+	var orders []models.SalesOrder
+	err := query.Order("id DESC").Find(&orders).Error
+	return orders, err
+}
+
+// UpdateSalesOrder replaces the header and lines of a draft order.
+func (r *salesRepository) UpdateSalesOrder(ctx context.Context, order *models.SalesOrder) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		existing, err := lockSalesOrder(tx, order.ID)
+		if err != nil {
+			return err
+		}
+		if existing.Status != models.SalesOrderStatusDraft {
+			return errors.New("only draft sales orders can be changed")
+		}
+
+		if err := tx.Where("sales_order_id = ?", order.ID).Delete(&models.SalesOrderLine{}).Error; err != nil {
+			return err
+		}
+		for i := range order.Lines {
+			order.Lines[i].ID = 0
+			order.Lines[i].SalesOrderID = order.ID
+		}
+		if len(order.Lines) > 0 {
+			if err := tx.Create(&order.Lines).Error; err != nil {
+				return err
+			}
+		}
+
+		order.Status = existing.Status
+		order.CreatedByID = existing.CreatedByID
+		order.CreatedAt = existing.CreatedAt
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+}
+
+func (r *salesRepository) DeleteSalesOrder(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockSalesOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.SalesOrderStatusDraft {
+			return errors.New("only draft sales orders can be deleted")
+		}
+
+		if err := tx.Where("sales_order_id = ?", id).Delete(&models.SalesOrderLine{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.SalesOrder{}, id).Error
+	})
+}
+
+// ConfirmSalesOrder reserves every line of a draft order until reserveUntil.
+// Lines are reserved from the locations with the most available stock first,
+// split across locations when no single one holds enough. Nothing is
+// reserved unless the whole order can be.
+func (r *salesRepository) ConfirmSalesOrder(ctx context.Context, id uint, userID uint, reserveUntil time.Time) (*models.SalesOrder, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockSalesOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.SalesOrderStatusDraft {
+			return errors.New("sales order is already " + order.Status)
+		}
+		if len(order.Lines) == 0 {
+			return errors.New("sales order needs at least one line")
+		}
+
+		for i := range order.Lines {
+			line := &order.Lines[i]
+			if err := reserveSalesOrderLine(tx, order, line, line.Quantity, nil, userID, reserveUntil); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		order.Status = models.SalesOrderStatusConfirmed
+		order.ConfirmedAt = &now
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetSalesOrder(ctx, id)
+}
+
+// ReserveSalesOrder renews the reservations of a confirmed order until
+// reserveUntil and reserves again what its lapsed reservations held, so an
+// order that sat too long can still ship. Nothing changes unless every
+// outstanding line can be covered in full.
+func (r *salesRepository) ReserveSalesOrder(ctx context.Context, id uint, userID uint, reserveUntil time.Time) (*models.SalesOrder, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockSalesOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.SalesOrderStatusConfirmed && order.Status != models.SalesOrderStatusPartiallyShipped {
+			return errors.New("sales order is " + order.Status + ", only confirmed orders hold reservations")
+		}
+
+		now := time.Now()
+		for i := range order.Lines {
+			line := &order.Lines[i]
+
+			// Lapsed reservations the sweeper has not got to yet
+			err := tx.Model(&models.Reservation{}).
+				Where("sales_order_line_id = ? AND status = ? AND expires_at <= ?", line.ID, models.ReservationStatusActive, now).
+				Update("status", models.ReservationStatusExpired).Error
+			if err != nil {
+				return err
+			}
+
+			var live []models.Reservation
+			err = tx.Where("sales_order_line_id = ? AND status = ?", line.ID, models.ReservationStatusActive).
+				Order("id").Find(&live).Error
+			if err != nil {
+				return err
+			}
+			var held models.Quantity
+			for j := range live {
+				held += live[j].Quantity
+				live[j].ExpiresAt = reserveUntil
+				if err := tx.Model(&live[j]).Update("expires_at", reserveUntil).Error; err != nil {
+					return err
+				}
+			}
+
+			if short := line.Outstanding() - held; short > 0 {
+				if err := reserveSalesOrderLine(tx, order, line, short, live, userID, reserveUntil); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetSalesOrder(ctx, id)
+}
+
+// ShipSalesOrder exports the given quantities per line out of the stock
+// reserved for them. Each export draws on one reservation and is linked to
+// the order line. No shipments ships everything outstanding.
+func (r *salesRepository) ShipSalesOrder(ctx context.Context, id uint, shipments []models.ShipmentLine, userID uint) (*models.SalesOrder, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockSalesOrder(tx, id)
+		if err != nil {
+			return err
+		}
+		if order.Status != models.SalesOrderStatusConfirmed && order.Status != models.SalesOrderStatusPartiallyShipped {
+			return errors.New("sales order is " + order.Status + ", only confirmed orders can be shipped")
+		}
+
+		if len(shipments) == 0 {
+			for _, line := range order.Lines {
+				if line.Outstanding() > 0 {
+					shipments = append(shipments, models.ShipmentLine{LineID: line.ID, Quantity: line.Outstanding()})
+				}
+			}
+		}
+
+		lines := make(map[uint]*models.SalesOrderLine, len(order.Lines))
+		for i := range order.Lines {
+			lines[order.Lines[i].ID] = &order.Lines[i]
+		}
+
+		now := time.Now()
+		for _, shipment := range shipments {
+			line, ok := lines[shipment.LineID]
+			if !ok {
+				return fmt.Errorf("line %d does not belong to sales order %d", shipment.LineID, order.ID)
+			}
+			if shipment.Quantity > line.Outstanding() {
+				return fmt.Errorf("line %d: cannot ship %s, only %s outstanding", line.ID, shipment.Quantity, line.Outstanding())
+			}
+
+			if err := shipSalesOrderLine(tx, order, line, shipment, userID, now, r.costingMethod); err != nil {
+				return err
+			}
+
+			line.ShippedQuantity += shipment.Quantity
+			if err := tx.Omit(clause.Associations).Save(line).Error; err != nil {
+				return err
+			}
+		}
+
+		order.Status = models.SalesOrderStatusPartiallyShipped
+		if salesOrderOutstanding(order) == 0 {
+			order.Status = models.SalesOrderStatusShipped
+			order.ClosedAt = &now
+		}
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetSalesOrder(ctx, id)
+}
+
+// CancelSalesOrder cancels an order that is not fully shipped and releases
+// whatever is still reserved for it. Goods already shipped stay shipped.
+func (r *salesRepository) CancelSalesOrder(ctx context.Context, id uint) (*models.SalesOrder, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		order, err := lockSalesOrder(tx, id)
+		if err != nil {
+			return err
+		}
+
+		lineIDs := make([]uint, 0, len(order.Lines))
+		for _, line := range order.Lines {
+			lineIDs = append(lineIDs, line.ID)
+		}
+		if len(lineIDs) > 0 {
+			err := tx.Model(&models.Reservation{}).
+				Where("sales_order_line_id IN ? AND status = ?", lineIDs, models.ReservationStatusActive).
+				Update("status", models.ReservationStatusReleased).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		order.Status = models.SalesOrderStatusCancelled
+		order.ClosedAt = &now
+		return tx.Omit(clause.Associations).Save(order).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.GetSalesOrder(ctx, id)
+}
+
+// reserveSalesOrderLine reserves quantity more of a line. A line keeps one
+// reservation per location, so stock found where the line already holds a
+// live reservation tops that one up.
+func reserveSalesOrderLine(tx *gorm.DB, order *models.SalesOrder, line *models.SalesOrderLine, quantity models.Quantity, held []models.Reservation, userID uint, reserveUntil time.Time) error {
+	query := tx.Model(&models.Stock{}).Where("product_id = ? AND quantity > 0", line.ProductID)
+	if order.WarehouseID != nil {
+		query = query.Where("location_id IN (?)",
+			tx.Model(&models.Location{}).Select("id").Where("warehouse_id = ?", *order.WarehouseID))
+	}
+
+	var candidates []models.Stock
+	if err := query.Order("quantity DESC, location_id").Find(&candidates).Error; err != nil {
+		return err
+	}
+
+	heldAt := make(map[uint]*models.Reservation, len(held))
+	for i := range held {
+		heldAt[held[i].LocationID] = &held[i]
+	}
+
+	remaining := quantity
+	for _, candidate := range candidates {
+		if remaining == 0 {
+			break
+		}
+
+		stock, err := lockStock(tx, line.ProductID, candidate.LocationID, false)
+		if err != nil {
+			return err
+		}
+		reserved, err := reservedQuantity(tx, line.ProductID, candidate.LocationID)
+		if err != nil {
+			return err
+		}
+		available := stock.Quantity - reserved
+		if available <= 0 {
+			continue
+		}
+
+		if reservation, ok := heldAt[candidate.LocationID]; ok {
+			take := min(available, remaining)
+			if err := tx.Model(reservation).Update("quantity", reservation.Quantity+take).Error; err != nil {
+				return err
+			}
+			remaining -= take
+			continue
+		}
+
+		reservation := &models.Reservation{
+			ProductID:        line.ProductID,
+			LocationID:       candidate.LocationID,
+			Quantity:         min(available, remaining),
+			Reference:        fmt.Sprintf("Sales order #%d", order.ID),
+			Status:           models.ReservationStatusActive,
+			ExpiresAt:        reserveUntil,
+			CreatedByID:      userID,
+			SalesOrderLineID: &line.ID,
+		}
+		if err := tx.Create(reservation).Error; err != nil {
+			return err
+		}
+		remaining -= reservation.Quantity
+	}
+
+	if remaining > 0 {
+		return &InsufficientStockError{
+			ProductID: line.ProductID,
+			Available: quantity - remaining,
+			Requested: quantity,
+		}
+	}
+	return nil
+}
+
+func shipSalesOrderLine(tx *gorm.DB, order *models.SalesOrder, line *models.SalesOrderLine, shipment models.ShipmentLine, userID uint, now time.Time, defaultCosting string) error {
+	query := tx.Where("sales_order_line_id = ? AND status = ? AND expires_at > ?",
+		line.ID, models.ReservationStatusActive, now)
+	if shipment.LocationID != 0 {
+		query = query.Where("location_id = ?", shipment.LocationID)
+	}
+
+	var reservations []models.Reservation
+	if err := query.Order("id").Find(&reservations).Error; err != nil {
+		return err
+	}
+
+	remaining := shipment.Quantity
+	for _, reservation := range reservations {
+		if remaining == 0 {
+			break
+		}
+
+		quantity := min(reservation.Quantity, remaining)
+		movement := &models.StockMovement{
+			ProductID:        line.ProductID,
+			LocationID:       reservation.LocationID,
+			UserID:           userID,
+			Type:             models.MovementIssue,
+			Quantity:         quantity,
+			Date:             now,
+			Notes:            fmt.Sprintf("Sales order #%d shipped to %s", order.ID, order.CustomerReference),
+			ReservationID:    &reservation.ID,
+			SalesOrderLineID: &line.ID,
+		}
+		// Serial numbers are only accepted with a location, and a line holds
+		// at most one reservation per location, so they all belong here
+		movement.Serials = shipment.Serials
+		if err := applyStockChange(tx, movement, -quantity, defaultCosting); err != nil {
+			return err
+		}
+		remaining -= quantity
+	}
+
+	if remaining > 0 {
+		// Reservations may have lapsed, ReserveSalesOrder renews them
+		return fmt.Errorf("line %d: only %s of %s reserved for shipping, reserve the order again", line.ID, shipment.Quantity-remaining, shipment.Quantity)
+	}
+	return nil
+}
+
+// lockSalesOrder loads an open sales order with its lines, holding a row lock
+// on the order so concurrent shipments cannot both ship the same line.
+func lockSalesOrder(tx *gorm.DB, id uint) (*models.SalesOrder, error) {
+	var order models.SalesOrder
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, id).Error
+	if err != nil {
+		return nil, errors.New("sales order not found")
+	}
+	if order.Status == models.SalesOrderStatusShipped || order.Status == models.SalesOrderStatusCancelled {
+		return nil, errors.New("sales order is already " + order.Status)
+	}
+	if err := tx.Where("sales_order_id = ?", id).Order("id").Find(&order.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+func salesOrderOutstanding(order *models.SalesOrder) models.Quantity {
+	var total models.Quantity
+	for _, line := range order.Lines {
+		total += line.Outstanding()
+	}
+	return total
+}
@@ -0,0 +1,86 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"testing"
+	"time"
+)
+
+// TestConfirmAndShipSalesOrder confirms an order only once all of it can be
+// reserved, then ships it in two parts out of its reservations.
+func TestConfirmAndShipSalesOrder(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewSalesRepository(db, models.CostingFIFO)
+	stockRepo := repositories.NewStockRepository(db, models.CostingFIFO)
+	f := newStockFixture(t, db, "sales")
+
+	order := models.SalesOrder{
+		CustomerReference: "CUST-" + f.suffix,
+		CreatedByID:       f.user.ID,
+		Lines:             []models.SalesOrderLine{{ProductID: f.product.ID, Quantity: models.Units(6)}},
+	}
+	if err := repo.CreateSalesOrder(ctx, &order); err != nil {
+		t.Fatalf("create order: %v", err)
+	}
+	lineID := order.Lines[0].ID
+	reserveUntil := time.Now().Add(time.Hour)
+
+	f.book(t, models.MovementReceipt, models.Units(4), 0)
+	var short *repositories.InsufficientStockError
+	if _, err := repo.ConfirmSalesOrder(ctx, order.ID, f.user.ID, reserveUntil); !errors.As(err, &short) {
+		t.Fatalf("confirm with 4 of 6 on hand: %v, want InsufficientStockError", err)
+	}
+	if reserved, _ := stockRepo.GetProductReserved(ctx, f.product.ID); reserved != 0 {
+		t.Errorf("a refused confirmation left %s reserved", reserved)
+	}
+
+	f.book(t, models.MovementReceipt, models.Units(6), 0)
+	confirmed, err := repo.ConfirmSalesOrder(ctx, order.ID, f.user.ID, reserveUntil)
+	if err != nil {
+		t.Fatalf("confirm: %v", err)
+	}
+	if confirmed.Status != models.SalesOrderStatusConfirmed || confirmed.ConfirmedAt == nil {
+		t.Errorf("after confirming: status %s, confirmed at %v", confirmed.Status, confirmed.ConfirmedAt)
+	}
+	if reserved, _ := stockRepo.GetProductReserved(ctx, f.product.ID); reserved != models.Units(6) {
+		t.Errorf("reserved = %s, want 6", reserved)
+	}
+	if _, err := repo.ConfirmSalesOrder(ctx, order.ID, f.user.ID, reserveUntil); err == nil {
+		t.Errorf("a confirmed order was confirmed again")
+	}
+
+	shipped, err := repo.ShipSalesOrder(ctx, order.ID, []models.ShipmentLine{{LineID: lineID, Quantity: models.Units(2)}}, f.user.ID)
+	if err != nil {
+		t.Fatalf("ship 2: %v", err)
+	}
+	if shipped.Status != models.SalesOrderStatusPartiallyShipped || shipped.Lines[0].ShippedQuantity != models.Units(2) {
+		t.Errorf("after shipping 2: status %s, shipped %s", shipped.Status, shipped.Lines[0].ShippedQuantity)
+	}
+	if reserved, _ := stockRepo.GetProductReserved(ctx, f.product.ID); reserved != models.Units(4) {
+		t.Errorf("reserved after shipping 2 = %s, want 4", reserved)
+	}
+	if _, err := repo.ShipSalesOrder(ctx, order.ID, []models.ShipmentLine{{LineID: lineID, Quantity: models.Units(5)}}, f.user.ID); err == nil {
+		t.Errorf("shipped 5 with only 4 outstanding")
+	}
+
+	shipped, err = repo.ShipSalesOrder(ctx, order.ID, nil, f.user.ID)
+	if err != nil {
+		t.Fatalf("ship the rest: %v", err)
+	}
+	if shipped.Status != models.SalesOrderStatusShipped || shipped.ClosedAt == nil {
+		t.Errorf("after shipping everything: status %s, closed at %v", shipped.Status, shipped.ClosedAt)
+	}
+	if got := f.onHand(t); got != models.Units(4) {
+		t.Errorf("on hand = %s, want 4", got)
+	}
+	if reserved, _ := stockRepo.GetProductReserved(ctx, f.product.ID); reserved != 0 {
+		t.Errorf("a shipped order still holds %s reserved", reserved)
+	}
+	if _, err := repo.ShipSalesOrder(ctx, order.ID, nil, f.user.ID); err == nil {
+		t.Errorf("a shipped order was shipped again")
+	}
+}
@@ -0,0 +1,123 @@
+package repositories
+
+import (
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// applySerialChange moves the serial numbers named on the movement in or out
+// of the movement's location. Every unit must be named, so the number of
+// serials has to match the quantity. Incoming units may not already be in
+// stock; outgoing units must be in stock at the location.
+func applySerialChange(tx *gorm.DB, movement *models.StockMovement, delta models.Quantity) error {
+	quantity := delta.Abs()
+	if models.Units(len(movement.Serials)) != quantity {
+		return fmt.Errorf("expected %s serial numbers, got %d", quantity, len(movement.Serials))
+	}
+
+	seen := make(map[string]bool, len(movement.Serials))
+	for i := range movement.Serials {
+		movementSerial := &movement.Serials[i]
+		if movementSerial.SerialNumber == nil || movementSerial.SerialNumber.Serial == "" {
+			return errors.New("serial number is required for serial-tracked products")
+		}
+		serial := movementSerial.SerialNumber.Serial
+		if seen[serial] {
+			return fmt.Errorf("serial number %s is listed twice", serial)
+		}
+		seen[serial] = true
+
+		var unit models.SerialNumber
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ? AND serial = ?", movement.ProductID, serial).
+			First(&unit).Error
+		if err != nil && err != gorm.ErrRecordNotFound {
+			return err
+		}
+		known := err == nil
+
+		if delta > 0 {
+			switch {
+			case !known:
+				unit = models.SerialNumber{ProductID: movement.ProductID, Serial: serial}
+			case unit.Status == models.SerialStatusInStock:
+				return fmt.Errorf("serial number %s is already in stock", serial)
+			case unit.Status == models.SerialStatusInTransit && movement.TransferLineID == nil:
+				return fmt.Errorf("serial number %s is in transit", serial)
+			}
+			unit.Status = models.SerialStatusInStock
+			unit.LocationID = &movement.LocationID
+		} else {
+			if !known {
+				return fmt.Errorf("unknown serial number %s", serial)
+			}
+			if unit.Status != models.SerialStatusInStock || unit.LocationID == nil || *unit.LocationID != movement.LocationID {
+				return fmt.Errorf("serial number %s is not in stock at this location", serial)
+			}
+			unit.LocationID = nil
+			unit.Status = models.SerialStatusOut
+			if movement.TransferLineID != nil {
+				unit.Status = models.SerialStatusInTransit
+			}
+		}
+
+		if err := tx.Omit(clause.Associations).Save(&unit).Error; err != nil {
+			return err
+		}
+		movementSerial.SerialNumberID = unit.ID
+		// Only the reference is stored with the movement
+		movementSerial.SerialNumber = nil
+	}
+	return nil
+}
+
+// takeTransferSerials picks which serial numbers still in transit on a
+// transfer line are booked by a receipt or cancellation of quantity units.
+// When serials are given they must all be in transit on the line, otherwise
+// the first quantity units in transit are taken. It returns nil for products
+// that are not serial tracked.
+func takeTransferSerials(tx *gorm.DB, transferLineID uint, quantity models.Quantity, serials []string) ([]models.StockMovementSerial, error) {
+	var inTransit []models.SerialNumber
+	err := tx.Model(&models.SerialNumber{}).
+		Joins("JOIN stock_movement_serials ON stock_movement_serials.serial_number_id = serial_numbers.id").
+		Joins("JOIN stock_movements ON stock_movements.id = stock_movement_serials.stock_movement_id").
+		Where("stock_movements.transfer_line_id = ? AND stock_movements.direction = ? AND stock_movements.deleted_at IS NULL", transferLineID, models.MovementOut).
+		Where("serial_numbers.status = ?", models.SerialStatusInTransit).
+		Order("serial_numbers.serial").
+		Find(&inTransit).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(inTransit) == 0 {
+		if len(serials) > 0 {
+			return nil, errors.New("no serial numbers are in transit on this line")
+		}
+		return nil, nil
+	}
+
+	available := make(map[string]bool, len(inTransit))
+	for _, unit := range inTransit {
+		available[unit.Serial] = true
+	}
+
+	if len(serials) == 0 {
+		for i := 0; models.Units(i) < quantity && i < len(inTransit); i++ {
+			serials = append(serials, inTransit[i].Serial)
+		}
+	}
+
+	movementSerials := make([]models.StockMovementSerial, 0, len(serials))
+	for _, serial := range serials {
+		if !available[serial] {
+			return nil, fmt.Errorf("serial number %s is not in transit on this line", serial)
+		}
+		movementSerials = append(movementSerials, models.StockMovementSerial{
+			SerialNumber: &models.SerialNumber{Serial: serial},
+		})
+	}
+	return movementSerials, nil
+}
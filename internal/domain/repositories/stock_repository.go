@@ -2,10 +2,13 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"stock-management/internal/domain/models"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type StockRepository interface {
@@ -19,42 +22,88 @@ type StockRepository interface {
 	GetCategories(ctx context.Context) ([]models.Category, error)
 	UpdateCategory(ctx context.Context, category *models.Category) error
 	DeleteCategory(ctx context.Context, id uint) error
-
-	GetStock(ctx context.Context, productID uint) (*models.Stock, error)
+	CreateReasonCode(ctx context.Context, reasonCode *models.ReasonCode) error
+	GetReasonCode(ctx context.Context, id uint) (*models.ReasonCode, error)
+	GetReasonCodeByCode(ctx context.Context, code string) (*models.ReasonCode, error)
+	GetReasonCodes(ctx context.Context, movementType *models.MovementType) ([]models.ReasonCode, error)
+	UpdateReasonCode(ctx context.Context, reasonCode *models.ReasonCode) error
+	CreateUnit(ctx context.Context, unit *models.UnitOfMeasure) error
+	GetUnit(ctx context.Context, id uint) (*models.UnitOfMeasure, error)
+	GetUnitByCode(ctx context.Context, code string) (*models.UnitOfMeasure, error)
+	GetUnits(ctx context.Context) ([]models.UnitOfMeasure, error)
+	UpdateUnit(ctx context.Context, unit *models.UnitOfMeasure) error
+	GetProductUnits(ctx context.Context, productID uint) ([]models.ProductUnit, error)
+	GetProductUnit(ctx context.Context, productID, unitID uint) (*models.ProductUnit, error)
+	SaveProductUnit(ctx context.Context, productUnit *models.ProductUnit) error
+	DeleteProductUnit(ctx context.Context, productID, unitID uint) error
+	CreateWarehouse(ctx context.Context, warehouse *models.Warehouse) error
+	GetWarehouse(ctx context.Context, id uint) (*models.Warehouse, error)
+	GetWarehouses(ctx context.Context) ([]models.Warehouse, error)
+	UpdateWarehouse(ctx context.Context, warehouse *models.Warehouse) error
+	DeleteWarehouse(ctx context.Context, id uint) error
+	CreateLocation(ctx context.Context, location *models.Location) error
+	GetLocation(ctx context.Context, id uint) (*models.Location, error)
+	GetLocations(ctx context.Context, warehouseID *uint) ([]models.Location, error)
+	UpdateLocation(ctx context.Context, location *models.Location) error
+	DeleteLocation(ctx context.Context, id uint) error
+
+	GetStock(ctx context.Context, productID, locationID uint) (*models.Stock, error)
+	GetProductQuantity(ctx context.Context, productID uint) (models.Quantity, error)
+	GetProductQuantities(ctx context.Context) (map[uint]models.Quantity, error)
+	GetProductReserved(ctx context.Context, productID uint) (models.Quantity, error)
+	IsInKit(ctx context.Context, productID uint) (bool, error)
+	GetReservedQuantities(ctx context.Context, locationID *uint) ([]models.ReservedQuantity, error)
+	GetLots(ctx context.Context, productID uint) ([]models.Lot, error)
+	GetSerialNumbers(ctx context.Context, productID uint, status *string) ([]models.SerialNumber, error)
+	GetSerialHistory(ctx context.Context, productID uint, serial string) (*models.SerialNumber, []models.StockMovement, error)
 	CreateMovement(ctx context.Context, movement *models.StockMovement) error
-	GetMovements(ctx context.Context, startDate, endDate time.Time, productID *uint, category *uint) ([]models.StockMovement, error)
+	GetMovements(ctx context.Context, filter models.MovementFilter) ([]models.StockMovement, error)
 	GetStockByProductID(productID uint) (*models.Stock, error)
-	CreateStock(stock *models.Stock, movement *models.StockMovement) error
-	UpdateStock(stock *models.Stock, movement *models.StockMovement) error
+	ApplyMovement(ctx context.Context, movement *models.StockMovement, delta models.Quantity) error
+	ReverseMovement(ctx context.Context, id uint, userID uint, notes string) (*models.StockMovement, error)
 	GetStockMovements(startDate, endDate *time.Time, productID, categoryID *uint) ([]models.StockMovement, error)
 	GetCurrentStock() ([]models.Stock, error)
-	GetStockSummary() ([]models.Stock, error)
+	GetStockSummary(locationID *uint) ([]models.Stock, error)
+	GetBalancesAsOf(ctx context.Context, asOf time.Time, productID, locationID *uint) ([]models.StockBalanceRow, error)
 }
 
 type stockRepository struct {
 	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
 }
 
-func NewStockRepository(db *gorm.DB) StockRepository {
-	return &stockRepository{db: db}
+func NewStockRepository(db *gorm.DB, costingMethod string) StockRepository {
+	return &stockRepository{db: db, costingMethod: costingMethod}
 }
 
 func (r *stockRepository) CreateProduct(ctx context.Context, product *models.Product) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Create the product
-		if err := tx.Create(product).Error; err != nil {
+		if err := checkSKUNotBarcode(tx, product.SKU, 0); err != nil {
 			return err
 		}
-
-		// Create initial stock record
-		stock := &models.Stock{
-			ProductID: product.ID,
-			Quantity:  0,
+		if err := tx.Create(product).Error; err != nil {
+			return err
 		}
-		return tx.Create(stock).Error
+		return createInitialStock(tx, product.ID)
 	})
 }
 
+// createInitialStock gives a new product an empty stock record at the
+// default location, so it is listed in stock reports before its first
+// movement. Records at other locations are created as stock arrives there.
+func createInitialStock(tx *gorm.DB, productID uint) error {
+	var location models.Location
+	err := tx.Order("id").First(&location).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.Stock{ProductID: productID, LocationID: location.ID}).Error
+}
+
 func (r *stockRepository) GetProduct(ctx context.Context, id uint) (*models.Product, error) {
 	var product models.Product
 	err := r.db.WithContext(ctx).First(&product, id).Error
@@ -66,31 +115,108 @@ func (r *stockRepository) GetProduct(ctx context.Context, id uint) (*models.Prod
 
 func (r *stockRepository) GetProducts(ctx context.Context) ([]models.Product, error) {
 	var products []models.Product
-	err := r.db.WithContext(ctx).Preload("Category").Find(&products).Error
+	err := r.db.WithContext(ctx).
+		Preload("Category").
+		Preload("BaseUnit").
+		Preload("VariantAttributes.Attribute").
+		Preload("VariantAttributes.Value").
+		Find(&products).Error
 	return products, err
 }
 
 func (r *stockRepository) UpdateProduct(ctx context.Context, product *models.Product) error {
-	return r.db.WithContext(ctx).Save(product).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkSKUNotBarcode(tx, product.SKU, product.ID); err != nil {
+			return err
+		}
+		return tx.Save(product).Error
+	})
 }
 
+// DeleteProduct archives a product that no longer holds stock, is not on
+// any open document and is not part of a kit. Its movements are kept, the
+// ledger is never rewritten.
 func (r *stockRepository) DeleteProduct(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Delete associated stock records
-		if err := tx.Where("product_id = ?", id).Delete(&models.Stock{}).Error; err != nil {
+		var variants int64
+		if err := tx.Model(&models.Product{}).Where("parent_id = ?", id).Count(&variants).Error; err != nil {
+			return err
+		}
+		if variants > 0 {
+			return errors.New("product still has variants")
+		}
+		if err := checkProductNotOnOpenDocuments(tx, id); err != nil {
 			return err
 		}
+		inKit, err := isInKit(tx, id)
+		if err != nil {
+			return err
+		}
+		if inKit {
+			return errors.New("product is still a kit or a kit component")
+		}
 
-		// Delete associated stock movements
-		if err := tx.Where("product_id = ?", id).Delete(&models.StockMovement{}).Error; err != nil {
+		var stocks []models.Stock
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("product_id = ?", id).
+			Find(&stocks).Error
+		if err != nil {
 			return err
 		}
+		for _, stock := range stocks {
+			if stock.Quantity != 0 {
+				return errors.New("product still has stock on hand")
+			}
+		}
 
-		// Delete the product
+		// Delete the empty stock records
+		if err := tx.Where("product_id = ?", id).Delete(&models.Stock{}).Error; err != nil {
+			return err
+		}
+
+		// Free its barcodes for reuse and archive the product
+		if err := tx.Where("product_id = ?", id).Delete(&models.Barcode{}).Error; err != nil {
+			return err
+		}
 		return tx.Delete(&models.Product{}, id).Error
 	})
 }
 
+// checkProductNotOnOpenDocuments refuses to archive a product that open
+// orders, returns or transfers still have to move.
+func checkProductNotOnOpenDocuments(tx *gorm.DB, productID uint) error {
+	documents := []struct {
+		name  string
+		query *gorm.DB
+	}{
+		{"purchase order", tx.Model(&models.PurchaseOrderLine{}).
+			Joins("JOIN purchase_orders ON purchase_orders.id = purchase_order_lines.purchase_order_id").
+			Where("purchase_order_lines.product_id = ? AND purchase_orders.status <> ?", productID, models.PurchaseOrderStatusClosed)},
+		{"sales order", tx.Model(&models.SalesOrderLine{}).
+			Joins("JOIN sales_orders ON sales_orders.id = sales_order_lines.sales_order_id").
+			Where("sales_order_lines.product_id = ? AND sales_orders.status IN ?", productID,
+				[]string{models.SalesOrderStatusDraft, models.SalesOrderStatusConfirmed, models.SalesOrderStatusPartiallyShipped})},
+		{"return", tx.Model(&models.ReturnLine{}).
+			Joins("JOIN return_authorizations ON return_authorizations.id = return_lines.return_authorization_id").
+			Where("return_lines.product_id = ? AND return_authorizations.status IN ?", productID,
+				[]string{models.ReturnStatusAuthorized, models.ReturnStatusReceived})},
+		{"transfer", tx.Model(&models.StockTransferLine{}).
+			Joins("JOIN stock_transfers ON stock_transfers.id = stock_transfer_lines.transfer_id").
+			Where("stock_transfer_lines.product_id = ? AND stock_transfers.status IN ?", productID,
+				[]string{models.TransferStatusInTransit, models.TransferStatusPartiallyReceived})},
+	}
+	for _, document := range documents {
+		var open int64
+		if err := document.query.Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return fmt.Errorf("product is still on an open %s", document.name)
+		}
+	}
+	return nil
+}
+
 func (r *stockRepository) CreateCategory(ctx context.Context, category *models.Category) error {
 	return r.db.WithContext(ctx).Create(category).Error
 }
@@ -126,41 +252,382 @@ func (r *stockRepository) DeleteCategory(ctx context.Context, id uint) error {
 	})
 }
 
-func (r *stockRepository) GetStock(ctx context.Context, productID uint) (*models.Stock, error) {
+func (r *stockRepository) CreateReasonCode(ctx context.Context, reasonCode *models.ReasonCode) error {
+	return r.db.WithContext(ctx).Create(reasonCode).Error
+}
+
+func (r *stockRepository) GetReasonCode(ctx context.Context, id uint) (*models.ReasonCode, error) {
+	var reasonCode models.ReasonCode
+	err := r.db.WithContext(ctx).First(&reasonCode, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reasonCode, nil
+}
+
+func (r *stockRepository) GetReasonCodeByCode(ctx context.Context, code string) (*models.ReasonCode, error) {
+	var reasonCode models.ReasonCode
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&reasonCode).Error
+	if err != nil {
+		return nil, err
+	}
+	return &reasonCode, nil
+}
+
+// GetReasonCodes lists reason codes, limited to those usable on movementType
+// when it is set.
+func (r *stockRepository) GetReasonCodes(ctx context.Context, movementType *models.MovementType) ([]models.ReasonCode, error) {
+	query := r.db.WithContext(ctx)
+	if movementType != nil {
+		query = query.Where("movement_type = ? OR movement_type = '' OR movement_type IS NULL", *movementType)
+	}
+
+	var reasonCodes []models.ReasonCode
+	err := query.Order("code").Find(&reasonCodes).Error
+	return reasonCodes, err
+}
+
+func (r *stockRepository) UpdateReasonCode(ctx context.Context, reasonCode *models.ReasonCode) error {
+	return r.db.WithContext(ctx).Save(reasonCode).Error
+}
+
+func (r *stockRepository) CreateUnit(ctx context.Context, unit *models.UnitOfMeasure) error {
+	return r.db.WithContext(ctx).Create(unit).Error
+}
+
+func (r *stockRepository) GetUnit(ctx context.Context, id uint) (*models.UnitOfMeasure, error) {
+	var unit models.UnitOfMeasure
+	err := r.db.WithContext(ctx).First(&unit, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (r *stockRepository) GetUnitByCode(ctx context.Context, code string) (*models.UnitOfMeasure, error) {
+	var unit models.UnitOfMeasure
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&unit).Error
+	if err != nil {
+		return nil, err
+	}
+	return &unit, nil
+}
+
+func (r *stockRepository) GetUnits(ctx context.Context) ([]models.UnitOfMeasure, error) {
+	var units []models.UnitOfMeasure
+	err := r.db.WithContext(ctx).Order("code").Find(&units).Error
+	return units, err
+}
+
+func (r *stockRepository) UpdateUnit(ctx context.Context, unit *models.UnitOfMeasure) error {
+	return r.db.WithContext(ctx).Save(unit).Error
+}
+
+func (r *stockRepository) GetProductUnits(ctx context.Context, productID uint) ([]models.ProductUnit, error) {
+	var productUnits []models.ProductUnit
+	err := r.db.WithContext(ctx).
+		Preload("Unit").
+		Where("product_id = ?", productID).
+		Order("factor").
+		Find(&productUnits).Error
+	return productUnits, err
+}
+
+func (r *stockRepository) GetProductUnit(ctx context.Context, productID, unitID uint) (*models.ProductUnit, error) {
+	var productUnit models.ProductUnit
+	err := r.db.WithContext(ctx).
+		Preload("Unit").
+		Where("product_id = ? AND unit_id = ?", productID, unitID).
+		First(&productUnit).Error
+	if err != nil {
+		return nil, err
+	}
+	return &productUnit, nil
+}
+
+// SaveProductUnit adds an alternate unit to a product or changes the factor
+// of one it already has.
+func (r *stockRepository) SaveProductUnit(ctx context.Context, productUnit *models.ProductUnit) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "unit_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"factor", "updated_at"}),
+	}).Create(productUnit).Error
+}
+
+func (r *stockRepository) DeleteProductUnit(ctx context.Context, productID, unitID uint) error {
+	result := r.db.WithContext(ctx).
+		Where("product_id = ? AND unit_id = ?", productID, unitID).
+		Delete(&models.ProductUnit{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("product does not use this unit")
+	}
+	return nil
+}
+
+func (r *stockRepository) CreateWarehouse(ctx context.Context, warehouse *models.Warehouse) error {
+	return r.db.WithContext(ctx).Create(warehouse).Error
+}
+
+func (r *stockRepository) GetWarehouse(ctx context.Context, id uint) (*models.Warehouse, error) {
+	var warehouse models.Warehouse
+	err := r.db.WithContext(ctx).Preload("Locations").First(&warehouse, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &warehouse, nil
+}
+
+func (r *stockRepository) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	var warehouses []models.Warehouse
+	err := r.db.WithContext(ctx).Preload("Locations").Find(&warehouses).Error
+	return warehouses, err
+}
+
+func (r *stockRepository) UpdateWarehouse(ctx context.Context, warehouse *models.Warehouse) error {
+	return r.db.WithContext(ctx).Omit("Locations").Save(warehouse).Error
+}
+
+func (r *stockRepository) DeleteWarehouse(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Refuse to drop locations that still hold goods
+		var onHand int64
+		err := tx.Model(&models.Stock{}).
+			Joins("JOIN locations ON locations.id = stocks.location_id").
+			Where("locations.warehouse_id = ? AND stocks.quantity <> 0", id).
+			Count(&onHand).Error
+		if err != nil {
+			return err
+		}
+		if onHand > 0 {
+			return errors.New("warehouse still holds stock")
+		}
+
+		if err := tx.Where("warehouse_id = ?", id).Delete(&models.Location{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Delete(&models.Warehouse{}, id).Error
+	})
+}
+
+func (r *stockRepository) CreateLocation(ctx context.Context, location *models.Location) error {
+	return r.db.WithContext(ctx).Create(location).Error
+}
+
+func (r *stockRepository) GetLocation(ctx context.Context, id uint) (*models.Location, error) {
+	var location models.Location
+	err := r.db.WithContext(ctx).Preload("Warehouse").First(&location, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &location, nil
+}
+
+func (r *stockRepository) GetLocations(ctx context.Context, warehouseID *uint) ([]models.Location, error) {
+	query := r.db.WithContext(ctx).Preload("Warehouse")
+	if warehouseID != nil {
+		query = query.Where("warehouse_id = ?", *warehouseID)
+	}
+
+	var locations []models.Location
+	err := query.Find(&locations).Error
+	return locations, err
+}
+
+func (r *stockRepository) UpdateLocation(ctx context.Context, location *models.Location) error {
+	return r.db.WithContext(ctx).Omit("Warehouse").Save(location).Error
+}
+
+func (r *stockRepository) DeleteLocation(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var onHand int64
+		err := tx.Model(&models.Stock{}).
+			Where("location_id = ? AND quantity <> 0", id).
+			Count(&onHand).Error
+		if err != nil {
+			return err
+		}
+		if onHand > 0 {
+			return errors.New("location still holds stock")
+		}
+
+		return tx.Delete(&models.Location{}, id).Error
+	})
+}
+
+func (r *stockRepository) GetStock(ctx context.Context, productID, locationID uint) (*models.Stock, error) {
 	var stock models.Stock
-	err := r.db.WithContext(ctx).Where("product_id = ?", productID).First(&stock).Error
+	err := r.db.WithContext(ctx).Where("product_id = ? AND location_id = ?", productID, locationID).First(&stock).Error
 	if err != nil {
 		return nil, err
 	}
 	return &stock, nil
 }
 
+// GetProductQuantity returns the quantity of a product summed over all locations.
+func (r *stockRepository) GetProductQuantity(ctx context.Context, productID uint) (models.Quantity, error) {
+	var total models.Quantity
+	err := r.db.WithContext(ctx).Model(&models.Stock{}).
+		Where("product_id = ?", productID).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// GetProductQuantities returns the quantity of every product in stock summed
+// over all locations, keyed by product ID.
+func (r *stockRepository) GetProductQuantities(ctx context.Context) (map[uint]models.Quantity, error) {
+	var rows []struct {
+		ProductID uint
+		Quantity  models.Quantity
+	}
+	err := r.db.WithContext(ctx).Model(&models.Stock{}).
+		Select("product_id, SUM(quantity) AS quantity").
+		Group("product_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	quantities := make(map[uint]models.Quantity, len(rows))
+	for _, row := range rows {
+		quantities[row.ProductID] = row.Quantity
+	}
+	return quantities, nil
+}
+
+// GetProductReserved returns the live reserved quantity of a product summed
+// over all locations.
+func (r *stockRepository) GetProductReserved(ctx context.Context, productID uint) (models.Quantity, error) {
+	var reserved models.Quantity
+	err := r.db.WithContext(ctx).Model(&models.Reservation{}).
+		Where("product_id = ? AND status = ? AND expires_at > ?", productID, models.ReservationStatusActive, time.Now()).
+		Select("COALESCE(SUM(quantity), 0)").
+		Scan(&reserved).Error
+	return reserved, err
+}
+
+// IsInKit reports whether the product is a kit with components or a
+// component of a kit.
+func (r *stockRepository) IsInKit(ctx context.Context, productID uint) (bool, error) {
+	return isInKit(r.db.WithContext(ctx), productID)
+}
+
+func isInKit(tx *gorm.DB, productID uint) (bool, error) {
+	var lines int64
+	err := tx.Model(&models.KitComponent{}).
+		Where("kit_id = ? OR component_id = ?", productID, productID).
+		Count(&lines).Error
+	return lines > 0, err
+}
+
+// GetReservedQuantities returns the live reserved quantity per product and location.
+func (r *stockRepository) GetReservedQuantities(ctx context.Context, locationID *uint) ([]models.ReservedQuantity, error) {
+	query := r.db.WithContext(ctx).Model(&models.Reservation{}).
+		Select("product_id, location_id, SUM(quantity) AS quantity").
+		Where("status = ? AND expires_at > ?", models.ReservationStatusActive, time.Now()).
+		Group("product_id, location_id")
+	if locationID != nil {
+		query = query.Where("location_id = ?", *locationID)
+	}
+
+	var reserved []models.ReservedQuantity
+	err := query.Scan(&reserved).Error
+	return reserved, err
+}
+
+func (r *stockRepository) GetLots(ctx context.Context, productID uint) ([]models.Lot, error) {
+	var lots []models.Lot
+	err := r.db.WithContext(ctx).
+		Preload("Stocks", "quantity <> 0").
+		Preload("Stocks.Location").
+		Where("product_id = ?", productID).
+		Order("expiry_date ASC NULLS LAST, id").
+		Find(&lots).Error
+	return lots, err
+}
+
+func (r *stockRepository) GetSerialNumbers(ctx context.Context, productID uint, status *string) ([]models.SerialNumber, error) {
+	query := r.db.WithContext(ctx).Preload("Location").Where("product_id = ?", productID)
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var serials []models.SerialNumber
+	err := query.Order("serial").Find(&serials).Error
+	return serials, err
+}
+
+func (r *stockRepository) GetSerialHistory(ctx context.Context, productID uint, serial string) (*models.SerialNumber, []models.StockMovement, error) {
+	var unit models.SerialNumber
+	err := r.db.WithContext(ctx).Preload("Location").
+		Where("product_id = ? AND serial = ?", productID, serial).
+		First(&unit).Error
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var movements []models.StockMovement
+	err = r.db.WithContext(ctx).
+		Preload("Product").
+		Preload("Location").
+		Preload("Location.Warehouse").
+		Preload("User").
+		Joins("JOIN stock_movement_serials ON stock_movement_serials.stock_movement_id = stock_movements.id").
+		Where("stock_movement_serials.serial_number_id = ?", unit.ID).
+		Order("stock_movements.date, stock_movements.id").
+		Find(&movements).Error
+	return &unit, movements, err
+}
+
 func (r *stockRepository) CreateMovement(ctx context.Context, movement *models.StockMovement) error {
 	return r.db.WithContext(ctx).Create(movement).Error
 }
 
-func (r *stockRepository) GetMovements(ctx context.Context, startDate, endDate time.Time, productID *uint, category *uint) ([]models.StockMovement, error) {
+func (r *stockRepository) GetMovements(ctx context.Context, filter models.MovementFilter) ([]models.StockMovement, error) {
 	query := r.db.WithContext(ctx).Model(&models.StockMovement{}).
-		Preload("Product").
+		Preload("Product", unscoped).
 		Preload("Product.Category").
+		Preload("Location").
+		Preload("Location.Warehouse").
+		Preload("ReasonCode").
+		Preload("EnteredUnit").
+		Preload("Lots.Lot").
+		Preload("Serials.SerialNumber").
 		Preload("User")
 
 	// Only add date filter if both dates are provided
-	if !startDate.IsZero() && !endDate.IsZero() {
-		query = query.Where("date BETWEEN ? AND ?", startDate, endDate)
+	if !filter.StartDate.IsZero() && !filter.EndDate.IsZero() {
+		query = query.Where("date BETWEEN ? AND ?", filter.StartDate, filter.EndDate)
 	}
 
-	if productID != nil {
-		query = query.Where("product_id = ?", *productID)
+	if filter.ProductID != nil {
+		query = query.Where("product_id = ?", *filter.ProductID)
 	}
 
-	if category != nil {
+	if filter.LocationID != nil {
+		query = query.Where("stock_movements.location_id = ?", *filter.LocationID)
+	}
+
+	if filter.CategoryID != nil {
 		query = query.Joins("JOIN products ON products.id = stock_movements.product_id").
-			Where("products.category_id = ?", *category)
+			Where("products.category_id = ?", *filter.CategoryID)
+	}
+
+	if filter.Type != nil {
+		query = query.Where("stock_movements.type = ?", *filter.Type)
+	}
+
+	if filter.ReasonCode != nil {
+		query = query.Joins("JOIN reason_codes ON reason_codes.id = stock_movements.reason_code_id").
+			Where("reason_codes.code = ?", *filter.ReasonCode)
 	}
 
 	var movements []models.StockMovement
-	err := query.Find(&movements).Error
+	err := query.Order("date DESC").Find(&movements).Error
 	return movements, err
 }
 
@@ -173,41 +640,147 @@ func (r *stockRepository) GetStockByProductID(productID uint) (*models.Stock, er
 	return &stock, nil
 }
 
-func (r *stockRepository) CreateStock(stock *models.Stock, movement *models.StockMovement) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Create stock record
-		if err := tx.Create(stock).Error; err != nil {
-			return err
+// ApplyMovement changes the stock at the movement's location by delta and
+// records the movement in a single transaction.
+func (r *stockRepository) ApplyMovement(ctx context.Context, movement *models.StockMovement, delta models.Quantity) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return applyStockChange(tx, movement, delta, r.costingMethod)
+	})
+	return translateStockError(err)
+}
+
+// ReverseMovement posts the compensating movement of a movement: the same
+// product, location, lots and serials moved the other way. Movements booked
+// through a document are corrected through that document instead, and an
+// issue whose goods are on a return is corrected through the return.
+func (r *stockRepository) ReverseMovement(ctx context.Context, id uint, userID uint, notes string) (*models.StockMovement, error) {
+	var reversal *models.StockMovement
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var original models.StockMovement
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&original, id).Error
+		if err != nil {
+			return errors.New("movement not found")
+		}
+		if original.ReversalOfID != nil {
+			return errors.New("a reversal cannot itself be reversed")
+		}
+		if document := movementDocument(&original); document != "" {
+			return errors.New("movement belongs to a " + document + " and must be corrected there")
 		}
 
-		// Create movement record
-		if err := tx.Create(movement).Error; err != nil {
+		var reversals int64
+		if err := tx.Model(&models.StockMovement{}).Where("reversal_of_id = ?", id).Count(&reversals).Error; err != nil {
 			return err
 		}
-
-		return nil
-	})
-}
-
-func (r *stockRepository) UpdateStock(stock *models.Stock, movement *models.StockMovement) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		// Update stock record
-		if err := tx.Save(stock).Error; err != nil {
+		if reversals > 0 {
+			return ErrAlreadyReversed
+		}
+		var returns int64
+		err = tx.Model(&models.ReturnAuthorization{}).
+			Where("movement_id = ? AND status <> ?", id, models.ReturnStatusCancelled).
+			Count(&returns).Error
+		if err != nil {
 			return err
 		}
+		if returns > 0 {
+			return errors.New("movement is on a customer return and cannot be reversed")
+		}
 
-		// Create movement record
-		if err := tx.Create(movement).Error; err != nil {
+		if err := tx.Where("stock_movement_id = ?", id).Find(&original.Lots).Error; err != nil {
+			return err
+		}
+		if err := tx.Preload("SerialNumber").Where("stock_movement_id = ?", id).Find(&original.Serials).Error; err != nil {
 			return err
 		}
 
-		return nil
+		if notes == "" {
+			notes = fmt.Sprintf("Reversal of movement #%d", id)
+		}
+		reversal = &models.StockMovement{
+			ProductID:  original.ProductID,
+			LocationID: original.LocationID,
+			UserID:     userID,
+			Type:       original.Type,
+			Quantity:   original.Quantity,
+			Date:       time.Now(),
+			Notes:      notes,
+
+			EnteredUnitID:   original.EnteredUnitID,
+			EnteredQuantity: original.EnteredQuantity,
+
+			ReasonCodeID: original.ReasonCodeID,
+			ReversalOfID: &original.ID,
+		}
+		for _, movementLot := range original.Lots {
+			reversal.Lots = append(reversal.Lots, models.StockMovementLot{
+				LotID:    movementLot.LotID,
+				Quantity: movementLot.Quantity,
+			})
+		}
+		for _, movementSerial := range original.Serials {
+			if movementSerial.SerialNumber == nil {
+				continue
+			}
+			reversal.Serials = append(reversal.Serials, models.StockMovementSerial{
+				SerialNumber: &models.SerialNumber{Serial: movementSerial.SerialNumber.Serial},
+			})
+		}
+
+		// Reversing a receipt takes the goods back out, so it is subject to
+		// the same availability rules as any other outgoing movement
+		delta := original.Quantity
+		if original.Direction == models.MovementIn {
+			delta = -delta
+		}
+		return applyStockChange(tx, reversal, delta, r.costingMethod)
 	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+
+	err = r.db.WithContext(ctx).
+		Preload("Product", unscoped).
+		Preload("Location.Warehouse").
+		Preload("ReasonCode").
+		Preload("EnteredUnit").
+		Preload("Lots.Lot").
+		Preload("Serials.SerialNumber").
+		Preload("User").
+		First(reversal, reversal.ID).Error
+	if err != nil {
+		return nil, err
+	}
+	return reversal, nil
+}
+
+// movementDocument names the document a movement was booked through, or
+// returns "" for a standalone movement.
+func movementDocument(movement *models.StockMovement) string {
+	switch {
+	case movement.TransferLineID != nil:
+		return "transfer"
+	case movement.PurchaseOrderLineID != nil:
+		return "purchase order"
+	case movement.SalesOrderLineID != nil:
+		return "sales order"
+	case movement.ReturnLineID != nil:
+		return "return"
+	case movement.CountLineID != nil:
+		return "stock count"
+	case movement.KitBuildID != nil:
+		return "kit build"
+	}
+	return ""
+}
+
+// unscoped lets movement history preload products that have been archived.
+func unscoped(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
 }
 
 func (r *stockRepository) GetStockMovements(startDate, endDate *time.Time, productID, categoryID *uint) ([]models.StockMovement, error) {
 	query := r.db.Model(&models.StockMovement{}).
-		Preload("Product").
+		Preload("Product", unscoped).
 		Preload("Product.Category").
 		Preload("User")
 
@@ -234,14 +807,216 @@ func (r *stockRepository) GetCurrentStock() ([]models.Stock, error) {
 	var stocks []models.Stock
 	err := r.db.Preload("Product").
 		Preload("Product.Category").
+		Preload("Location").
+		Preload("Location.Warehouse").
 		Find(&stocks).Error
 	return stocks, err
 }
 
-func (r *stockRepository) GetStockSummary() ([]models.Stock, error) {
-	var stocks []models.Stock
-	err := r.db.Preload("Product").
+func (r *stockRepository) GetStockSummary(locationID *uint) ([]models.Stock, error) {
+	query := r.db.Preload("Product").
 		Preload("Product.Category").
-		Find(&stocks).Error
+		Preload("Location").
+		Preload("Location.Warehouse")
+
+	if locationID != nil {
+		query = query.Where("location_id = ?", *locationID)
+	}
+
+	var stocks []models.Stock
+	err := query.Order("product_id, location_id").Find(&stocks).Error
 	return stocks, err
 }
+
+// GetBalancesAsOf rebuilds the non-zero quantity of each product at each
+// location at asOf: the latest snapshot taken by then plus the movements
+// dated after it. Movements booked after the snapshot but dated before it
+// are added too, they are not part of the snapshot.
+func (r *stockRepository) GetBalancesAsOf(ctx context.Context, asOf time.Time, productID, locationID *uint) ([]models.StockBalanceRow, error) {
+	db := r.db.WithContext(ctx)
+
+	var snapshotAt *time.Time
+	err := db.Model(&models.StockSnapshot{}).
+		Select("MAX(as_of)").
+		Where("as_of <= ?", asOf).
+		Scan(&snapshotAt).Error
+	if err != nil {
+		return nil, err
+	}
+	since := time.Time{}
+	if snapshotAt != nil {
+		since = *snapshotAt
+	}
+
+	snapshots := db.Model(&models.StockSnapshot{}).
+		Select("product_id, location_id, quantity").
+		Where("as_of = ?", since)
+	movements := db.Model(&models.StockMovement{}).
+		Select("product_id, location_id, CASE WHEN direction = ? THEN -quantity ELSE quantity END AS quantity", models.MovementOut).
+		Where("date <= ?", asOf).
+		Where("date >= ? OR created_at >= ?", since, since)
+
+	query := db.Table("(? UNION ALL ?) AS balances", snapshots, movements).
+		Select(`balances.product_id,
+			products.sku,
+			products.name AS product_name,
+			balances.location_id,
+			locations.code AS location_code,
+			locations.warehouse_id,
+			warehouses.name AS warehouse_name,
+			SUM(balances.quantity) AS quantity`).
+		Joins("JOIN products ON products.id = balances.product_id").
+		Joins("JOIN locations ON locations.id = balances.location_id").
+		Joins("JOIN warehouses ON warehouses.id = locations.warehouse_id")
+
+	if productID != nil {
+		query = query.Where("balances.product_id = ?", *productID)
+	}
+	if locationID != nil {
+		query = query.Where("balances.location_id = ?", *locationID)
+	}
+
+	var rows []models.StockBalanceRow
+	err = query.
+		Group("balances.product_id, products.sku, products.name, balances.location_id, locations.code, locations.warehouse_id, warehouses.name").
+		Having("SUM(balances.quantity) <> 0").
+		Order("products.sku, locations.code").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// applyStockChange adds delta to the stock of the movement's product at the
+// movement's location and records the movement, on the caller's transaction.
+// The stock row is locked with SELECT ... FOR UPDATE so the check and the
+// write cannot interleave with another change; stock may never go negative
+// and outgoing movements may not dip into stock reserved for others.
+// defaultCosting is the costing method of products that do not choose one.
+func applyStockChange(tx *gorm.DB, movement *models.StockMovement, delta models.Quantity, defaultCosting string) error {
+	if !movement.Type.Valid() {
+		return errors.New("invalid movement type " + string(movement.Type))
+	}
+	movement.Direction = models.MovementIn
+	if delta < 0 {
+		movement.Direction = models.MovementOut
+	}
+
+	stock, err := lockStock(tx, movement.ProductID, movement.LocationID, delta > 0)
+	if err != nil {
+		return err
+	}
+
+	if movement.ReservationID != nil && delta >= 0 {
+		return errors.New("only outgoing movements can consume a reservation")
+	}
+
+	if delta < 0 {
+		// Stock held for reservations is not available, except what this
+		// movement draws from its own reservation
+		if movement.ReservationID != nil {
+			if _, err := consumeReservation(tx, *movement.ReservationID, movement, -delta); err != nil {
+				return err
+			}
+		}
+		reserved, err := reservedQuantity(tx, movement.ProductID, movement.LocationID)
+		if err != nil {
+			return err
+		}
+
+		if available := stock.Quantity - reserved; available+delta < 0 {
+			return &InsufficientStockError{
+				ProductID:  movement.ProductID,
+				LocationID: movement.LocationID,
+				Available:  max(available, 0),
+				Requested:  -delta,
+			}
+		}
+	}
+
+	if err := applyTrackingChange(tx, movement, delta); err != nil {
+		return err
+	}
+	if err := priceMovement(tx, movement, delta, defaultCosting); err != nil {
+		return err
+	}
+
+	if err := tx.Model(stock).Update("quantity", stock.Quantity+delta).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(movement).Error; err != nil {
+		return err
+	}
+	if delta > 0 {
+		return addCostLayer(tx, movement, delta, defaultCosting)
+	}
+	return nil
+}
+
+// applyTrackingChange checks the movement against the product's precision
+// and variants, then updates the lots or serial numbers of products that
+// track them, depending on the product's tracking mode.
+func applyTrackingChange(tx *gorm.DB, movement *models.StockMovement, delta models.Quantity) error {
+	var product models.Product
+	if err := tx.Select("id", "tracking_mode", "quantity_decimals").First(&product, movement.ProductID).Error; err != nil {
+		return errors.New("product not found")
+	}
+	if !product.AllowsQuantity(delta) {
+		return fmt.Errorf("quantity %s has more than the %d decimals allowed for the product", delta.Abs(), product.QuantityDecimals)
+	}
+	var variants int64
+	if err := tx.Model(&models.Product{}).Where("parent_id = ?", product.ID).Count(&variants).Error; err != nil {
+		return err
+	}
+	if variants > 0 {
+		return errors.New("stock of a product with variants is kept on its variants")
+	}
+
+	if product.TrackingMode != models.TrackingLot && len(movement.Lots) > 0 {
+		return errors.New("product is not lot tracked")
+	}
+	if product.TrackingMode != models.TrackingSerial && len(movement.Serials) > 0 {
+		return errors.New("product is not serial tracked")
+	}
+
+	switch product.TrackingMode {
+	case models.TrackingLot:
+		return applyLotChange(tx, movement, delta)
+	case models.TrackingSerial:
+		return applySerialChange(tx, movement, delta)
+	}
+	return nil
+}
+
+// lockStock loads and row-locks the stock of a product at a location. When
+// create is set a missing row is inserted first so there is something to lock.
+func lockStock(tx *gorm.DB, productID, locationID uint, create bool) (*models.Stock, error) {
+	var stock models.Stock
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND location_id = ?", productID, locationID).
+		First(&stock).Error
+	if err == nil {
+		return &stock, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, err
+	}
+	if !create {
+		return &models.Stock{ProductID: productID, LocationID: locationID}, nil
+	}
+
+	// Two first imports may race here; the loser's insert is a no-op
+	err = tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "product_id"}, {Name: "location_id"}},
+		DoNothing: true,
+	}).Create(&models.Stock{ProductID: productID, LocationID: locationID}).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("product_id = ? AND location_id = ?", productID, locationID).
+		First(&stock).Error
+	if err != nil {
+		return nil, err
+	}
+	return &stock, nil
+}
@@ -0,0 +1,306 @@
+package repositories_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"stock-management/internal/infrastructure/database"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDB connects to the Postgres database named by TEST_DATABASE_DSN
+// and migrates it. Tests that need a database are skipped without one.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("connection pool: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(40)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+// stockFixture is a user and a product of its own category, stocked at the
+// default location. Names carry a suffix so tests can share a database.
+type stockFixture struct {
+	db       *gorm.DB
+	suffix   string
+	user     models.User
+	product  models.Product
+	location models.Location
+}
+
+func newStockFixture(t *testing.T, db *gorm.DB, name string) *stockFixture {
+	t.Helper()
+	f := &stockFixture{db: db, suffix: fmt.Sprint(time.Now().UnixNano())}
+	f.user = f.newUser(t, name)
+
+	category := models.Category{Name: name + " " + f.suffix}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("create category: %v", err)
+	}
+	f.product = f.newProduct(t, name, category.ID, models.TrackingNone)
+
+	if err := db.Order("id").First(&f.location).Error; err != nil {
+		t.Fatalf("default location: %v", err)
+	}
+	return f
+}
+
+func (f *stockFixture) newUser(t *testing.T, name string) models.User {
+	t.Helper()
+	user := models.User{Username: name + "-" + f.suffix, Password: "-", Email: name + "-" + f.suffix + "@example.com"}
+	if err := f.db.Create(&user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	return user
+}
+
+func (f *stockFixture) newProduct(t *testing.T, name string, categoryID uint, trackingMode string) models.Product {
+	t.Helper()
+	product := models.Product{
+		Name:         name + " " + f.suffix,
+		SKU:          strings.ToUpper(name) + "-" + f.suffix,
+		CategoryID:   categoryID,
+		TrackingMode: trackingMode,
+	}
+	if err := repositories.NewStockRepository(f.db, models.CostingFIFO).CreateProduct(context.Background(), &product); err != nil {
+		t.Fatalf("create product: %v", err)
+	}
+	return product
+}
+
+func (f *stockFixture) newReasonCode(t *testing.T, movementType models.MovementType) models.ReasonCode {
+	t.Helper()
+	reason := models.ReasonCode{Code: strings.ToUpper(string(movementType)) + "-" + f.suffix, MovementType: movementType}
+	if err := f.db.Create(&reason).Error; err != nil {
+		t.Fatalf("create reason code: %v", err)
+	}
+	return reason
+}
+
+// book moves the fixture's product in or out of its location by delta.
+func (f *stockFixture) book(t *testing.T, movementType models.MovementType, delta models.Quantity, unitCost models.Money) *models.StockMovement {
+	t.Helper()
+	quantity := delta
+	if quantity < 0 {
+		quantity = -quantity
+	}
+	movement := &models.StockMovement{
+		ProductID:  f.product.ID,
+		LocationID: f.location.ID,
+		UserID:     f.user.ID,
+		Type:       movementType,
+		Quantity:   quantity,
+		Date:       time.Now(),
+		UnitCost:   unitCost,
+	}
+	if err := repositories.NewStockRepository(f.db, models.CostingFIFO).ApplyMovement(context.Background(), movement, delta); err != nil {
+		t.Fatalf("book %s of %s: %v", movementType, delta, err)
+	}
+	return movement
+}
+
+// onHand returns the quantity of the fixture's product at its location.
+func (f *stockFixture) onHand(t *testing.T) models.Quantity {
+	t.Helper()
+	var stock models.Stock
+	err := f.db.Where("product_id = ? AND location_id = ?", f.product.ID, f.location.ID).First(&stock).Error
+	if err != nil {
+		t.Fatalf("read stock: %v", err)
+	}
+	return stock.Quantity
+}
+
+// TestApplyMovementConcurrentExports fires many parallel exports at a
+// single stock row. Exactly as many as there is stock for may succeed, the
+// rest must be refused with an error the API reports as 409, and the
+// quantity must never go negative.
+func TestApplyMovementConcurrentExports(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewStockRepository(db, models.CostingFIFO)
+
+	const onHand, exports = 100, 300
+	f := newStockFixture(t, db, "concurrency")
+	product, location, user := f.product, f.location, f.user
+	f.book(t, models.MovementReceipt, models.Units(onHand), 0)
+
+	var (
+		wg                           sync.WaitGroup
+		mu                           sync.Mutex
+		shipped, insufficient, raced int
+	)
+	start := make(chan struct{})
+	for i := 0; i < exports; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			movement := &models.StockMovement{
+				ProductID:  product.ID,
+				LocationID: location.ID,
+				UserID:     user.ID,
+				Type:       models.MovementIssue,
+				Quantity:   models.Units(1),
+				Date:       time.Now(),
+			}
+			err := repo.ApplyMovement(ctx, movement, -models.Units(1))
+
+			var short *repositories.InsufficientStockError
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				shipped++
+			case errors.As(err, &short):
+				insufficient++
+			case errors.Is(err, repositories.ErrStockConflict):
+				raced++
+			default:
+				t.Errorf("export: unexpected error %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	quantity := f.onHand(t)
+	if quantity < 0 {
+		t.Fatalf("quantity went negative: %s", quantity)
+	}
+	if want := models.Units(onHand - shipped); quantity != want {
+		t.Errorf("quantity = %s, want %s after %d exports", quantity, want, shipped)
+	}
+	// Row locks make exports wait rather than fail, so every unit ships
+	if shipped != onHand {
+		t.Errorf("%d exports succeeded, want %d", shipped, onHand)
+	}
+	if conflicts := insufficient + raced; conflicts != exports-onHand {
+		t.Errorf("%d exports were refused with 409, want %d", conflicts, exports-onHand)
+	}
+
+	var movements int64
+	db.Model(&models.StockMovement{}).Where("product_id = ? AND type = ?", product.ID, models.MovementIssue).Count(&movements)
+	if movements != int64(shipped) {
+		t.Errorf("%d issue movements recorded for %d successful exports", movements, shipped)
+	}
+}
+
+// stockValue is what the cost layers of a product at a location carry.
+func stockValue(t *testing.T, db *gorm.DB, productID, locationID uint) models.Money {
+	t.Helper()
+	var layers []models.CostLayer
+	if err := db.Where("product_id = ? AND location_id = ? AND remaining > 0", productID, locationID).Find(&layers).Error; err != nil {
+		t.Fatalf("read cost layers: %v", err)
+	}
+	var value models.Money
+	for _, layer := range layers {
+		layerValue, err := layer.UnitCost.MulQuantity(layer.Remaining)
+		if err != nil {
+			t.Fatalf("value layer %d: %v", layer.ID, err)
+		}
+		value += layerValue
+	}
+	return value
+}
+
+// TestReverseMovementRestoresCost reverses a receipt, which takes back its
+// own layer rather than the oldest, and an issue, which puts back what the
+// goods cost.
+func TestReverseMovementRestoresCost(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewStockRepository(db, models.CostingFIFO)
+	f := newStockFixture(t, db, "reversal-cost")
+
+	// Costs are in ten-thousandths
+	f.book(t, models.MovementReceipt, models.Units(10), 1_0000)
+	expensive := f.book(t, models.MovementReceipt, models.Units(10), 3_0000)
+	if _, err := repo.ReverseMovement(ctx, expensive.ID, f.user.ID, ""); err != nil {
+		t.Fatalf("reverse receipt: %v", err)
+	}
+	if value := stockValue(t, db, f.product.ID, f.location.ID); value != 10_0000 {
+		t.Errorf("stock value after reversing the receipt at 3 = %s, want 10 left at 1", value)
+	}
+
+	f.book(t, models.MovementReceipt, models.Units(10), 3_0000)
+	issue := f.book(t, models.MovementIssue, -models.Units(20), 0)
+	if issue.TotalCost != 40_0000 {
+		t.Errorf("issue of 20 cost %s, want 40 from 10 at 1 and 10 at 3", issue.TotalCost)
+	}
+	reversal, err := repo.ReverseMovement(ctx, issue.ID, f.user.ID, "")
+	if err != nil {
+		t.Fatalf("reverse issue: %v", err)
+	}
+	if reversal.TotalCost != issue.TotalCost {
+		t.Errorf("reversal carries %s, want the issue's %s", reversal.TotalCost, issue.TotalCost)
+	}
+	if value := stockValue(t, db, f.product.ID, f.location.ID); value != 40_0000 {
+		t.Errorf("stock value after reversing the issue = %s, want 40", value)
+	}
+	if got := f.onHand(t); got != models.Units(20) {
+		t.Errorf("on hand = %s, want 20", got)
+	}
+}
+
+// TestTransferCarriesCost moves goods to another location at the cost they
+// left the source with.
+func TestTransferCarriesCost(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repositories.NewTransferRepository(db, models.CostingFIFO)
+	f := newStockFixture(t, db, "transfer-cost")
+
+	destination := models.Location{WarehouseID: f.location.WarehouseID, Code: "TRANSFER-" + f.suffix}
+	if err := db.Create(&destination).Error; err != nil {
+		t.Fatalf("create location: %v", err)
+	}
+	// Costs are in ten-thousandths
+	f.book(t, models.MovementReceipt, models.Units(10), 1_0000)
+	f.book(t, models.MovementReceipt, models.Units(10), 4_0000)
+
+	transfer := models.StockTransfer{
+		SourceLocationID:      f.location.ID,
+		DestinationLocationID: destination.ID,
+		CreatedByID:           f.user.ID,
+		Lines:                 []models.StockTransferLine{{ProductID: f.product.ID, Quantity: models.Units(12)}},
+	}
+	if err := repo.CreateTransfer(ctx, &transfer); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	if _, err := repo.ReceiveTransfer(ctx, transfer.ID, nil, f.user.ID); err != nil {
+		t.Fatalf("receive: %v", err)
+	}
+
+	// 10 at 1 and 2 at 4 left the source, 1.50 a unit
+	if value := stockValue(t, db, f.product.ID, destination.ID); value != 18_0000 {
+		t.Errorf("destination value = %s, want 18", value)
+	}
+	if value := stockValue(t, db, f.product.ID, f.location.ID); value != 32_0000 {
+		t.Errorf("source value = %s, want 32 for the 8 left at 4", value)
+	}
+}
@@ -0,0 +1,255 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TransferRepository interface {
+	CreateTransfer(ctx context.Context, transfer *models.StockTransfer) error
+	GetTransfer(ctx context.Context, id uint) (*models.StockTransfer, error)
+	GetTransfers(ctx context.Context, status *string) ([]models.StockTransfer, error)
+	ReceiveTransfer(ctx context.Context, id uint, receipts map[uint]models.TransferReceipt, userID uint) (*models.StockTransfer, error)
+	CancelTransfer(ctx context.Context, id uint, userID uint) (*models.StockTransfer, error)
+}
+
+type transferRepository struct {
+	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
+}
+
+func NewTransferRepository(db *gorm.DB, costingMethod string) TransferRepository {
+	return &transferRepository{db: db, costingMethod: costingMethod}
+}
+
+// CreateTransfer stores the transfer and dispatches it: every line is taken
+// out of the source location and recorded as an outgoing transfer movement.
+func (r *transferRepository) CreateTransfer(ctx context.Context, transfer *models.StockTransfer) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transfer.Status = models.TransferStatusInTransit
+		transfer.DispatchedAt = time.Now()
+		if err := tx.Create(transfer).Error; err != nil {
+			return err
+		}
+
+		for i := range transfer.Lines {
+			line := &transfer.Lines[i]
+			movement := &models.StockMovement{
+				ProductID:      line.ProductID,
+				LocationID:     transfer.SourceLocationID,
+				UserID:         transfer.CreatedByID,
+				Type:           models.MovementTransfer,
+				Quantity:       line.Quantity,
+				Date:           transfer.DispatchedAt,
+				Notes:          fmt.Sprintf("Transfer #%d dispatched", transfer.ID),
+				TransferLineID: &line.ID,
+			}
+			for _, serial := range line.Serials {
+				movement.Serials = append(movement.Serials, models.StockMovementSerial{
+					SerialNumber: &models.SerialNumber{Serial: serial},
+				})
+			}
+			if err := applyStockChange(tx, movement, -line.Quantity, r.costingMethod); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	return translateStockError(err)
+}
+
+func (r *transferRepository) GetTransfer(ctx context.Context, id uint) (*models.StockTransfer, error) {
+	var transfer models.StockTransfer
+	err := r.db.WithContext(ctx).
+		Preload("SourceLocation").
+		Preload("DestinationLocation").
+		Preload("Lines").
+		Preload("Lines.Product").
+		First(&transfer, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func (r *transferRepository) GetTransfers(ctx context.Context, status *string) ([]models.StockTransfer, error) {
+	query := r.db.WithContext(ctx).
+		Preload("SourceLocation").
+		Preload("DestinationLocation").
+		Preload("Lines")
+
+	if status != nil {
+		query = query.Where("status = ?", *status)
+	}
+
+	var transfers []models.StockTransfer
+	err := query.Order("id DESC").Find(&transfers).Error
+	return transfers, err
+}
+
+// ReceiveTransfer books the given quantities per line ID into the destination.
+// An empty receipts map receives everything still in transit.
+func (r *transferRepository) ReceiveTransfer(ctx context.Context, id uint, receipts map[uint]models.TransferReceipt, userID uint) (*models.StockTransfer, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transfer, err := lockTransfer(tx, id)
+		if err != nil {
+			return err
+		}
+
+		if len(receipts) == 0 {
+			receipts = make(map[uint]models.TransferReceipt)
+			for _, line := range transfer.Lines {
+				if line.InTransit() > 0 {
+					receipts[line.ID] = models.TransferReceipt{Quantity: line.InTransit()}
+				}
+			}
+		}
+
+		lineIDs := make(map[uint]bool, len(transfer.Lines))
+		for _, line := range transfer.Lines {
+			lineIDs[line.ID] = true
+		}
+		for lineID := range receipts {
+			if !lineIDs[lineID] {
+				return fmt.Errorf("line %d does not belong to transfer %d", lineID, transfer.ID)
+			}
+		}
+
+		now := time.Now()
+		for i := range transfer.Lines {
+			line := &transfer.Lines[i]
+			receipt, ok := receipts[line.ID]
+			if !ok {
+				continue
+			}
+			quantity := receipt.Quantity
+
+			if quantity > line.InTransit() {
+				return fmt.Errorf("line %d: cannot receive %s, only %s in transit", line.ID, quantity, line.InTransit())
+			}
+
+			line.ReceivedQuantity += quantity
+			if err := tx.Save(line).Error; err != nil {
+				return err
+			}
+
+			movement := &models.StockMovement{
+				ProductID:      line.ProductID,
+				LocationID:     transfer.DestinationLocationID,
+				UserID:         userID,
+				Type:           models.MovementTransfer,
+				Quantity:       quantity,
+				Date:           now,
+				Notes:          fmt.Sprintf("Transfer #%d received", transfer.ID),
+				TransferLineID: &line.ID,
+			}
+			// Tracked goods arrive in the lots and units they were dispatched as
+			if movement.Lots, err = takeTransferLots(tx, line.ID, quantity); err != nil {
+				return err
+			}
+			if movement.Serials, err = takeTransferSerials(tx, line.ID, quantity, receipt.Serials); err != nil {
+				return err
+			}
+			if err := applyStockChange(tx, movement, quantity, r.costingMethod); err != nil {
+				return err
+			}
+		}
+
+		transfer.Status = models.TransferStatusPartiallyReceived
+		if transferOutstanding(transfer) == 0 {
+			transfer.Status = models.TransferStatusReceived
+			transfer.ClosedAt = &now
+		}
+		return tx.Omit(clause.Associations).Save(transfer).Error
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetTransfer(ctx, id)
+}
+
+// CancelTransfer returns everything still in transit to the source location.
+func (r *transferRepository) CancelTransfer(ctx context.Context, id uint, userID uint) (*models.StockTransfer, error) {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		transfer, err := lockTransfer(tx, id)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for i := range transfer.Lines {
+			line := &transfer.Lines[i]
+			quantity := line.InTransit()
+			if quantity == 0 {
+				continue
+			}
+
+			line.CancelledQuantity += quantity
+			if err := tx.Save(line).Error; err != nil {
+				return err
+			}
+
+			movement := &models.StockMovement{
+				ProductID:      line.ProductID,
+				LocationID:     transfer.SourceLocationID,
+				UserID:         userID,
+				Type:           models.MovementTransfer,
+				Quantity:       quantity,
+				Date:           now,
+				Notes:          fmt.Sprintf("Transfer #%d cancelled, returned to source", transfer.ID),
+				TransferLineID: &line.ID,
+			}
+			// Tracked goods return in the lots and units they were dispatched as
+			if movement.Lots, err = takeTransferLots(tx, line.ID, quantity); err != nil {
+				return err
+			}
+			if movement.Serials, err = takeTransferSerials(tx, line.ID, quantity, nil); err != nil {
+				return err
+			}
+			if err := applyStockChange(tx, movement, quantity, r.costingMethod); err != nil {
+				return err
+			}
+		}
+
+		transfer.Status = models.TransferStatusCancelled
+		transfer.ClosedAt = &now
+		return tx.Omit(clause.Associations).Save(transfer).Error
+	})
+	if err != nil {
+		return nil, translateStockError(err)
+	}
+	return r.GetTransfer(ctx, id)
+}
+
+// lockTransfer loads an open transfer with its lines, holding a row lock on
+// the transfer so concurrent receipts cannot both book the same goods.
+func lockTransfer(tx *gorm.DB, id uint) (*models.StockTransfer, error) {
+	var transfer models.StockTransfer
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&transfer, id).Error
+	if err != nil {
+		return nil, errors.New("transfer not found")
+	}
+	if transfer.Status == models.TransferStatusReceived || transfer.Status == models.TransferStatusCancelled {
+		return nil, errors.New("transfer is already " + transfer.Status)
+	}
+	if err := tx.Where("transfer_id = ?", id).Order("id").Find(&transfer.Lines).Error; err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+func transferOutstanding(transfer *models.StockTransfer) models.Quantity {
+	var total models.Quantity
+	for _, line := range transfer.Lines {
+		total += line.InTransit()
+	}
+	return total
+}
@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"stock-management/internal/domain/models"
 
 	"gorm.io/gorm"
@@ -12,8 +13,15 @@ type UserRepository interface {
 	FindByUsername(ctx context.Context, username string) (*models.User, error)
 	FindByID(ctx context.Context, id uint) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
+	CreateBootstrapping(ctx context.Context, user *models.User) error
+	EnsureAdmin(ctx context.Context, username string) (*models.User, error)
+	SetRole(ctx context.Context, id uint, role models.Role) (*models.User, error)
 }
 
+// userBootstrapLock is the advisory lock that serialises handing out the
+// first admin role.
+const userBootstrapLock = 72010001
+
 type userRepository struct {
 	db *gorm.DB
 }
@@ -47,3 +55,102 @@ func (r *userRepository) FindByID(ctx context.Context, id uint) (*models.User, e
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	return r.db.WithContext(ctx).Save(user).Error
 }
+
+// CreateBootstrapping stores a new user. The very first account becomes
+// admin; the check and the insert run under a lock so two concurrent first
+// registrations cannot both get the role.
+func (r *userRepository) CreateBootstrapping(ctx context.Context, user *models.User) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", userBootstrapLock).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			user.Role = models.RoleAdmin
+		}
+		return tx.Create(user).Error
+	})
+}
+
+// EnsureAdmin promotes a user to admin when nobody holds the role, as on a
+// database that had users before roles existed. Which user is promoted must
+// be named; an empty database gets its admin from the first registration
+// instead. It returns the promoted user, or nil when nothing changed.
+func (r *userRepository) EnsureAdmin(ctx context.Context, username string) (*models.User, error) {
+	var promoted *models.User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", userBootstrapLock).Error; err != nil {
+			return err
+		}
+
+		var admins int64
+		if err := tx.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+			return err
+		}
+		if admins > 0 {
+			return nil
+		}
+
+		if username == "" {
+			var users int64
+			if err := tx.Model(&models.User{}).Count(&users).Error; err != nil {
+				return err
+			}
+			if users > 0 {
+				return errors.New("no user is admin, name the user to promote in ADMIN_USERNAME")
+			}
+			return nil
+		}
+
+		var user models.User
+		if err := tx.Where("username = ?", username).First(&user).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return errors.New("admin user " + username + " not found")
+			}
+			return err
+		}
+
+		user.Role = models.RoleAdmin
+		if err := tx.Save(&user).Error; err != nil {
+			return err
+		}
+		promoted = &user
+		return nil
+	})
+	return promoted, err
+}
+
+// SetRole changes a user's role. The last admin cannot be demoted, so there
+// is always someone left to manage roles.
+func (r *userRepository) SetRole(ctx context.Context, id uint, role models.Role) (*models.User, error) {
+	var user models.User
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", userBootstrapLock).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&user, id).Error; err != nil {
+			return errors.New("user not found")
+		}
+
+		if user.Role == models.RoleAdmin && role != models.RoleAdmin {
+			var admins int64
+			if err := tx.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins).Error; err != nil {
+				return err
+			}
+			if admins <= 1 {
+				return errors.New("the last admin cannot be demoted")
+			}
+		}
+
+		user.Role = role
+		return tx.Save(&user).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
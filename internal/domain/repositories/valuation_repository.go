@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"stock-management/internal/domain/models"
+
+	"gorm.io/gorm"
+)
+
+type ValuationRepository interface {
+	GetValuationRows(ctx context.Context, filter models.ValuationFilter) ([]models.ValuationRow, error)
+}
+
+type valuationRepository struct {
+	db *gorm.DB
+	// costingMethod values products that do not choose one
+	costingMethod string
+}
+
+func NewValuationRepository(db *gorm.DB, costingMethod string) ValuationRepository {
+	return &valuationRepository{db: db, costingMethod: costingMethod}
+}
+
+// GetValuationRows sums the open cost layers per product and location.
+func (r *valuationRepository) GetValuationRows(ctx context.Context, filter models.ValuationFilter) ([]models.ValuationRow, error) {
+	query := r.db.WithContext(ctx).Table("cost_layers").
+		Select(`cost_layers.product_id,
+			products.sku,
+			products.name AS product_name,
+			COALESCE(NULLIF(products.costing_method, ''), ?) AS costing_method,
+			products.category_id,
+			categories.name AS category_name,
+			cost_layers.location_id,
+			locations.code AS location_code,
+			locations.name AS location_name,
+			warehouses.name AS warehouse_name,
+			SUM(cost_layers.remaining) AS quantity,
+			SUM(cost_layers.remaining * cost_layers.unit_cost) AS value`, r.costingMethod).
+		Joins("JOIN products ON products.id = cost_layers.product_id").
+		Joins("JOIN categories ON categories.id = products.category_id").
+		Joins("JOIN locations ON locations.id = cost_layers.location_id").
+		Joins("JOIN warehouses ON warehouses.id = locations.warehouse_id").
+		Where("cost_layers.remaining > 0")
+
+	if filter.LocationID != nil {
+		query = query.Where("cost_layers.location_id = ?", *filter.LocationID)
+	}
+	if filter.WarehouseID != nil {
+		query = query.Where("locations.warehouse_id = ?", *filter.WarehouseID)
+	}
+	if filter.CategoryID != nil {
+		query = query.Where("products.category_id = ?", *filter.CategoryID)
+	}
+
+	var rows []models.ValuationRow
+	err := query.
+		Group("cost_layers.product_id, products.sku, products.name, products.costing_method, products.category_id, categories.name, " +
+			"cost_layers.location_id, locations.code, locations.name, warehouses.name").
+		Order("products.sku, locations.code").
+		Scan(&rows).Error
+	return rows, err
+}
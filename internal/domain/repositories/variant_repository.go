@@ -0,0 +1,287 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VariantRepository interface {
+	CreateAttribute(ctx context.Context, attribute *models.ProductAttribute) error
+	AddAttributeValue(ctx context.Context, productID uint, value *models.ProductAttributeValue) error
+	GetAttributes(ctx context.Context, productID uint) ([]models.ProductAttribute, error)
+	GenerateVariants(ctx context.Context, parentID uint) ([]models.Product, error)
+	GetVariants(ctx context.Context, parentID uint) ([]models.Product, error)
+}
+
+type variantRepository struct {
+	db *gorm.DB
+}
+
+func NewVariantRepository(db *gorm.DB) VariantRepository {
+	return &variantRepository{db: db}
+}
+
+// CreateAttribute adds an attribute with its values to a parent product.
+// Attributes can only be added before variants are generated, so every
+// variant takes a value for every attribute of its parent.
+func (r *variantRepository) CreateAttribute(ctx context.Context, attribute *models.ProductAttribute) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := lockParentProduct(tx, attribute.ProductID); err != nil {
+			return err
+		}
+
+		var variants int64
+		err := tx.Unscoped().Model(&models.Product{}).Where("parent_id = ?", attribute.ProductID).Count(&variants).Error
+		if err != nil {
+			return err
+		}
+		if variants > 0 {
+			return errors.New("attributes can only be added before variants are generated")
+		}
+
+		var existing int64
+		err = tx.Model(&models.ProductAttribute{}).
+			Where("product_id = ? AND name = ?", attribute.ProductID, attribute.Name).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("product already has an attribute %s", attribute.Name)
+		}
+		return tx.Create(attribute).Error
+	})
+}
+
+// AddAttributeValue adds an option to an attribute. Variants taking the new
+// value are created by the next GenerateVariants.
+func (r *variantRepository) AddAttributeValue(ctx context.Context, productID uint, value *models.ProductAttributeValue) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var attribute models.ProductAttribute
+		err := tx.Where("id = ? AND product_id = ?", value.AttributeID, productID).First(&attribute).Error
+		if err != nil {
+			return errors.New("attribute not found")
+		}
+
+		var existing int64
+		err = tx.Model(&models.ProductAttributeValue{}).
+			Where("attribute_id = ? AND value = ?", value.AttributeID, value.Value).
+			Count(&existing).Error
+		if err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("attribute %s already has the value %s", attribute.Name, value.Value)
+		}
+		return tx.Create(value).Error
+	})
+}
+
+func (r *variantRepository) GetAttributes(ctx context.Context, productID uint) ([]models.ProductAttribute, error) {
+	var attributes []models.ProductAttribute
+	err := r.db.WithContext(ctx).
+		Preload("Values", orderByPosition).
+		Where("product_id = ?", productID).
+		Scopes(orderByPosition).
+		Find(&attributes).Error
+	return attributes, err
+}
+
+// GenerateVariants creates a variant for every combination of the parent's
+// attribute values that has none yet, archived variants included. Variants
+// copy the parent's settings and alternate units; their SKU and name extend
+// the parent's with the codes and values they take.
+func (r *variantRepository) GenerateVariants(ctx context.Context, parentID uint) ([]models.Product, error) {
+	created := []models.Product{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		parent, err := lockParentProduct(tx, parentID)
+		if err != nil {
+			return err
+		}
+
+		var stocked int64
+		err = tx.Model(&models.Stock{}).Where("product_id = ? AND quantity <> 0", parentID).Count(&stocked).Error
+		if err != nil {
+			return err
+		}
+		if stocked > 0 {
+			return errors.New("product still has stock on hand; stock of a product with variants is kept on its variants")
+		}
+
+		var attributes []models.ProductAttribute
+		err = tx.Preload("Values", orderByPosition).
+			Where("product_id = ?", parentID).
+			Scopes(orderByPosition).
+			Find(&attributes).Error
+		if err != nil {
+			return err
+		}
+		if len(attributes) == 0 {
+			return errors.New("product has no attributes to generate variants from")
+		}
+
+		existing, err := variantCombinations(tx, parentID, attributes)
+		if err != nil {
+			return err
+		}
+		var units []models.ProductUnit
+		if err := tx.Where("product_id = ?", parentID).Find(&units).Error; err != nil {
+			return err
+		}
+
+		for _, combination := range attributeCombinations(attributes) {
+			if existing[combinationKey(combination)] {
+				continue
+			}
+
+			names := make([]string, len(combination))
+			codes := make([]string, len(combination))
+			for i, value := range combination {
+				names[i] = value.Value
+				codes[i] = value.Code
+			}
+			variant := models.Product{
+				Name:             parent.Name + " " + strings.Join(names, " / "),
+				ImageURL:         parent.ImageURL,
+				Description:      parent.Description,
+				CategoryID:       parent.CategoryID,
+				SKU:              parent.SKU + "-" + strings.Join(codes, "-"),
+				TrackingMode:     parent.TrackingMode,
+				CostingMethod:    parent.CostingMethod,
+				QuantityDecimals: parent.QuantityDecimals,
+				BaseUnitID:       parent.BaseUnitID,
+				ParentID:         &parent.ID,
+			}
+
+			var taken int64
+			if err := tx.Model(&models.Product{}).Where("sku = ?", variant.SKU).Count(&taken).Error; err != nil {
+				return err
+			}
+			if taken > 0 {
+				return fmt.Errorf("SKU %s is already taken", variant.SKU)
+			}
+			if err := checkSKUNotBarcode(tx, variant.SKU, 0); err != nil {
+				return err
+			}
+			if err := tx.Create(&variant).Error; err != nil {
+				return err
+			}
+			if err := createInitialStock(tx, variant.ID); err != nil {
+				return err
+			}
+
+			for i, value := range combination {
+				variant.VariantAttributes = append(variant.VariantAttributes, models.VariantAttribute{
+					VariantID:   variant.ID,
+					AttributeID: attributes[i].ID,
+					ValueID:     value.ID,
+				})
+			}
+			if err := tx.Create(&variant.VariantAttributes).Error; err != nil {
+				return err
+			}
+			for _, unit := range units {
+				if err := tx.Create(&models.ProductUnit{ProductID: variant.ID, UnitID: unit.UnitID, Factor: unit.Factor}).Error; err != nil {
+					return err
+				}
+			}
+			created = append(created, variant)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (r *variantRepository) GetVariants(ctx context.Context, parentID uint) ([]models.Product, error) {
+	var variants []models.Product
+	err := r.db.WithContext(ctx).
+		Preload("Category").
+		Preload("BaseUnit").
+		Preload("VariantAttributes.Attribute").
+		Preload("VariantAttributes.Value").
+		Where("parent_id = ?", parentID).
+		Order("id").
+		Find(&variants).Error
+	return variants, err
+}
+
+// lockParentProduct loads and row-locks a product that may have variants,
+// which rules out variants themselves.
+func lockParentProduct(tx *gorm.DB, id uint) (*models.Product, error) {
+	var product models.Product
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&product, id).Error; err != nil {
+		return nil, errors.New("product not found")
+	}
+	if product.ParentID != nil {
+		return nil, errors.New("a variant cannot have variants of its own")
+	}
+	return &product, nil
+}
+
+func orderByPosition(db *gorm.DB) *gorm.DB {
+	return db.Order("position, id")
+}
+
+// attributeCombinations returns every combination of one value per
+// attribute, in attribute order.
+func attributeCombinations(attributes []models.ProductAttribute) [][]models.ProductAttributeValue {
+	combinations := [][]models.ProductAttributeValue{{}}
+	for _, attribute := range attributes {
+		var next [][]models.ProductAttributeValue
+		for _, combination := range combinations {
+			for _, value := range attribute.Values {
+				extended := append(append([]models.ProductAttributeValue{}, combination...), value)
+				next = append(next, extended)
+			}
+		}
+		combinations = next
+	}
+	return combinations
+}
+
+// variantCombinations returns the keys of the combinations the parent's
+// variants, archived ones included, already take.
+func variantCombinations(tx *gorm.DB, parentID uint, attributes []models.ProductAttribute) (map[string]bool, error) {
+	var rows []models.VariantAttribute
+	err := tx.Joins("JOIN products ON products.id = variant_attributes.variant_id").
+		Where("products.parent_id = ?", parentID).
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[uint]map[uint]uint)
+	for _, row := range rows {
+		if values[row.VariantID] == nil {
+			values[row.VariantID] = make(map[uint]uint)
+		}
+		values[row.VariantID][row.AttributeID] = row.ValueID
+	}
+
+	combinations := make(map[string]bool, len(values))
+	for _, byAttribute := range values {
+		combination := make([]models.ProductAttributeValue, len(attributes))
+		for i, attribute := range attributes {
+			combination[i].ID = byAttribute[attribute.ID]
+		}
+		combinations[combinationKey(combination)] = true
+	}
+	return combinations, nil
+}
+
+func combinationKey(combination []models.ProductAttributeValue) string {
+	ids := make([]string, len(combination))
+	for i, value := range combination {
+		ids[i] = fmt.Sprint(value.ID)
+	}
+	return strings.Join(ids, ",")
+}
@@ -9,7 +9,7 @@ import (
 
 // JWTService defines the interface for JWT operations.
 type JWTService interface {
-	GenerateToken(userID uint, username string) (string, error)
+	GenerateToken(userID uint, username string, role string, sessionID string) (string, error)
 	ValidateToken(tokenString string) (jwt.MapClaims, error)
 }
 
@@ -22,22 +22,24 @@ type jwtServiceImpl struct {
 // NewJWTService creates a new JWTService.
 // secretKey should be loaded from a secure configuration.
 // issuer is a string identifying the token issuer.
-// expiryHours defines the token validity period in hours.
-func NewJWTService(secretKey string, issuer string, expiryHours int) (JWTService, error) {
+// expiry defines the access token validity period.
+func NewJWTService(secretKey string, issuer string, expiry time.Duration) (JWTService, error) {
 	if secretKey == "" {
 		return nil, errors.New("jwt secret key cannot be empty")
 	}
 	return &jwtServiceImpl{
 		secretKey: []byte(secretKey),
 		issuer:    issuer,
-		expiry:    time.Hour * time.Duration(expiryHours),
+		expiry:    expiry,
 	}, nil
 }
 
-func (s *jwtServiceImpl) GenerateToken(userID uint, username string) (string, error) {
+func (s *jwtServiceImpl) GenerateToken(userID uint, username string, role string, sessionID string) (string, error) {
 	claims := jwt.MapClaims{
 		"user_id":  userID,
 		"username": username,
+		"role":     role,
+		"sid":      sessionID,
 		"iss":      s.issuer,
 		"exp":      time.Now().Add(s.expiry).Unix(),
 		"iat":      time.Now().Unix(),
@@ -0,0 +1,24 @@
+package services
+
+// Label symbologies and output formats.
+const (
+	LabelCode128 = "code128"
+	LabelQR      = "qr"
+	LabelPNG     = "png"
+	LabelPDF     = "pdf"
+)
+
+// Label is the content of one printed label.
+type Label struct {
+	Title   string // printed on top, usually the product name
+	Code    string // the value encoded in the barcode and printed under it
+	Caption string // printed last, usually the SKU
+}
+
+// LabelService defines the interface for rendering printable label sheets.
+type LabelService interface {
+	// Render lays the labels out on A4 sheets of 3 by 8 labels and returns
+	// them as a PDF document or, for a single sheet, a PNG image, together
+	// with the content type.
+	Render(labels []Label, symbology, format string) ([]byte, string, error)
+}
@@ -0,0 +1,75 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// WebhookService defines the interface for delivering events to an external endpoint.
+type WebhookService interface {
+	// Enabled reports whether a webhook endpoint is configured.
+	Enabled() bool
+	Send(ctx context.Context, payload interface{}) error
+}
+
+type webhookServiceImpl struct {
+	url    string
+	secret []byte
+	client *http.Client
+}
+
+// NewWebhookService creates a new WebhookService.
+// url is the endpoint events are POSTed to; an empty url disables delivery.
+// secret, when set, signs each body with HMAC-SHA256 in the X-Webhook-Signature header.
+func NewWebhookService(url string, secret string) WebhookService {
+	return &webhookServiceImpl{
+		url:    url,
+		secret: []byte(secret),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (s *webhookServiceImpl) Enabled() bool {
+	return s.url != ""
+}
+
+func (s *webhookServiceImpl) Send(ctx context.Context, payload interface{}) error {
+	if !s.Enabled() {
+		return errors.New("webhook url is not configured")
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.New("could not encode webhook payload: " + err.Error())
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if len(s.secret) > 0 {
+		mac := hmac.New(sha256.New, s.secret)
+		mac.Write(body)
+		req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+)
+
+// AdjustmentThresholds decide which adjustments need a manager's approval.
+// An adjustment needs approval when its absolute quantity or its value
+// exceeds the corresponding threshold, so with a zero threshold every
+// adjustment does.
+type AdjustmentThresholds struct {
+	Quantity models.Quantity
+	Value    models.Money
+}
+
+type AdjustmentService struct {
+	adjustmentRepo repositories.AdjustmentRepository
+	stockRepo      repositories.StockRepository
+	thresholds     AdjustmentThresholds
+	observers      []StockObserver
+}
+
+func NewAdjustmentService(adjustmentRepo repositories.AdjustmentRepository, stockRepo repositories.StockRepository, thresholds AdjustmentThresholds, observers ...StockObserver) *AdjustmentService {
+	return &AdjustmentService{
+		adjustmentRepo: adjustmentRepo,
+		stockRepo:      stockRepo,
+		thresholds:     thresholds,
+		observers:      observers,
+	}
+}
+
+// RequestAdjustment records a manual correction. change.Quantity is signed:
+// positive adds stock and negative removes it. Adjustments within the
+// thresholds are posted immediately; larger ones stay pending until a
+// manager approves them.
+func (s *AdjustmentService) RequestAdjustment(ctx context.Context, change StockChange) (*models.AdjustmentRequest, error) {
+	if change.Quantity == 0 {
+		return nil, errors.New("quantity cannot be 0")
+	}
+	if _, err := s.stockRepo.GetLocation(ctx, change.LocationID); err != nil {
+		return nil, errors.New("location not found")
+	}
+	product, err := s.stockRepo.GetProduct(ctx, change.ProductID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	// Adjustments are kept in the base unit
+	if _, err := toBaseUnit(ctx, s.stockRepo, product, &change); err != nil {
+		return nil, err
+	}
+
+	reasonCodeID, err := resolveReasonCode(ctx, s.stockRepo, models.MovementAdjustment, change.ReasonCode)
+	if err != nil {
+		return nil, err
+	}
+
+	// Tracking details are validated against the absolute quantity; lots
+	// are only named when stock is added, removals draw on the oldest lots
+	quantity := change.Quantity
+	if quantity < 0 {
+		quantity = -quantity
+		if change.LotNumber != "" {
+			return nil, errors.New("lot details only apply to adjustments that add stock")
+		}
+	} else if _, err := lotsFor(product, StockChange{LotNumber: change.LotNumber, ExpiryDate: change.ExpiryDate, Quantity: quantity}); err != nil {
+		return nil, err
+	}
+	if _, err := serialsFor(product, StockChange{Serials: change.Serials, Quantity: quantity}); err != nil {
+		return nil, err
+	}
+
+	unitCost, err := s.adjustmentRepo.GetUnitCost(ctx, change.ProductID, change.LocationID)
+	if err != nil {
+		return nil, err
+	}
+	value, err := unitCost.MulQuantity(quantity)
+	if err != nil {
+		return nil, err
+	}
+
+	adjustment := &models.AdjustmentRequest{
+		ProductID:     change.ProductID,
+		LocationID:    change.LocationID,
+		Quantity:      change.Quantity,
+		ReasonCodeID:  *reasonCodeID,
+		Notes:         change.Notes,
+		LotNumber:     change.LotNumber,
+		ExpiryDate:    change.ExpiryDate,
+		Value:         value,
+		RequestedByID: change.UserID,
+	}
+	for _, serial := range change.Serials {
+		adjustment.Serials = append(adjustment.Serials, models.AdjustmentSerial{Serial: serial})
+	}
+
+	post := !s.needsApproval(quantity, adjustment.Value)
+	if err := s.adjustmentRepo.CreateAdjustment(ctx, adjustment, post); err != nil {
+		return nil, err
+	}
+	if post {
+		notifyStockChanged(ctx, s.observers, adjustment.ProductID, adjustment.LocationID)
+	}
+	return s.adjustmentRepo.GetAdjustment(ctx, adjustment.ID)
+}
+
+func (s *AdjustmentService) needsApproval(quantity models.Quantity, value models.Money) bool {
+	return quantity > s.thresholds.Quantity || value > s.thresholds.Value
+}
+
+func (s *AdjustmentService) GetAdjustment(ctx context.Context, id uint) (*models.AdjustmentRequest, error) {
+	adjustment, err := s.adjustmentRepo.GetAdjustment(ctx, id)
+	if err != nil {
+		return nil, errors.New("adjustment not found")
+	}
+	return adjustment, nil
+}
+
+func (s *AdjustmentService) GetAdjustments(ctx context.Context, status *string) ([]models.AdjustmentRequest, error) {
+	return s.adjustmentRepo.GetAdjustments(ctx, status)
+}
+
+// ApproveAdjustment posts a pending adjustment to the ledger.
+func (s *AdjustmentService) ApproveAdjustment(ctx context.Context, id uint, reviewerID uint, notes string) (*models.AdjustmentRequest, error) {
+	adjustment, err := s.adjustmentRepo.ApproveAdjustment(ctx, id, reviewerID, notes)
+	if err != nil {
+		return nil, err
+	}
+
+	notifyStockChanged(ctx, s.observers, adjustment.ProductID, adjustment.LocationID)
+	return adjustment, nil
+}
+
+// RejectAdjustment closes a pending adjustment without touching stock.
+func (s *AdjustmentService) RejectAdjustment(ctx context.Context, id uint, reviewerID uint, notes string) (*models.AdjustmentRequest, error) {
+	return s.adjustmentRepo.RejectAdjustment(ctx, id, reviewerID, notes)
+}
@@ -0,0 +1,211 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"stock-management/internal/domain/services"
+	"time"
+)
+
+// webhookAttempts is how often delivery of one alert event is tried.
+const webhookAttempts = 3
+
+type AlertService struct {
+	alertRepo      repositories.AlertRepository
+	stockRepo      repositories.StockRepository
+	webhookService services.WebhookService
+}
+
+func NewAlertService(alertRepo repositories.AlertRepository, stockRepo repositories.StockRepository, webhookService services.WebhookService) *AlertService {
+	return &AlertService{
+		alertRepo:      alertRepo,
+		stockRepo:      stockRepo,
+		webhookService: webhookService,
+	}
+}
+
+func (s *AlertService) GetRules(ctx context.Context, productID *uint) ([]models.ReorderRule, error) {
+	return s.alertRepo.GetRules(ctx, productID)
+}
+
+// SaveRule creates the reorder rule of a product at a location, or replaces
+// the existing one, and evaluates it right away.
+func (s *AlertService) SaveRule(ctx context.Context, rule *models.ReorderRule) error {
+	if rule.MinQuantity < 0 {
+		return errors.New("minimum quantity cannot be negative")
+	}
+	if rule.MaxQuantity < rule.MinQuantity {
+		return errors.New("maximum quantity cannot be below the minimum quantity")
+	}
+	product, err := s.stockRepo.GetProduct(ctx, rule.ProductID)
+	if err != nil {
+		return errors.New("product not found")
+	}
+	if err := checkPrecision(product, rule.MinQuantity); err != nil {
+		return err
+	}
+	if err := checkPrecision(product, rule.MaxQuantity); err != nil {
+		return err
+	}
+	if rule.LocationID != nil {
+		if _, err := s.stockRepo.GetLocation(ctx, *rule.LocationID); err != nil {
+			return errors.New("location not found")
+		}
+	}
+
+	existing, err := s.alertRepo.FindRule(ctx, rule.ProductID, rule.LocationID)
+	if err == nil {
+		rule.ID = existing.ID
+		rule.CreatedAt = existing.CreatedAt
+		err = s.alertRepo.UpdateRule(ctx, rule)
+	} else {
+		err = s.alertRepo.CreateRule(ctx, rule)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.evaluateRule(ctx, rule)
+	return nil
+}
+
+func (s *AlertService) DeleteRule(ctx context.Context, id uint) error {
+	if _, err := s.alertRepo.GetRule(ctx, id); err != nil {
+		return errors.New("reorder rule not found")
+	}
+	return s.alertRepo.DeleteRule(ctx, id)
+}
+
+// GetLowStock lists every rule whose product is at or below its reorder point.
+func (s *AlertService) GetLowStock(ctx context.Context) ([]models.LowStockDTO, error) {
+	rules, err := s.alertRepo.GetRules(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	lowStock := []models.LowStockDTO{}
+	for _, rule := range rules {
+		available, err := s.alertRepo.GetAvailableQuantity(ctx, rule.ProductID, rule.LocationID)
+		if err != nil {
+			return nil, err
+		}
+		if available <= rule.MinQuantity {
+			lowStock = append(lowStock, toLowStockDTO(rule, available))
+		}
+	}
+	return lowStock, nil
+}
+
+func (s *AlertService) GetAlerts(ctx context.Context, status *string) ([]models.StockAlert, error) {
+	return s.alertRepo.GetAlerts(ctx, status)
+}
+
+// StockChanged re-evaluates the reorder rules touched by a stock change.
+func (s *AlertService) StockChanged(ctx context.Context, productID, locationID uint) {
+	rules, err := s.alertRepo.GetRulesForStock(ctx, productID, locationID)
+	if err != nil {
+		log.Printf("Failed to load reorder rules for product %d: %v", productID, err)
+		return
+	}
+	for i := range rules {
+		s.evaluateRule(ctx, &rules[i])
+	}
+}
+
+// evaluateRule opens an alert when the rule is breached and none is open yet,
+// and resolves the open alert once stock is back above the reorder point.
+func (s *AlertService) evaluateRule(ctx context.Context, rule *models.ReorderRule) {
+	available, err := s.alertRepo.GetAvailableQuantity(ctx, rule.ProductID, rule.LocationID)
+	if err != nil {
+		log.Printf("Failed to evaluate reorder rule %d: %v", rule.ID, err)
+		return
+	}
+
+	open, err := s.alertRepo.GetOpenAlert(ctx, rule.ID)
+	if err != nil {
+		log.Printf("Failed to load open alert of reorder rule %d: %v", rule.ID, err)
+		return
+	}
+	switch {
+	case available <= rule.MinQuantity && open == nil:
+		alert := &models.StockAlert{
+			RuleID:         rule.ID,
+			ProductID:      rule.ProductID,
+			LocationID:     rule.LocationID,
+			Status:         models.AlertStatusOpen,
+			Quantity:       available,
+			MinQuantity:    rule.MinQuantity,
+			SuggestedOrder: max(rule.MaxQuantity-available, 0),
+		}
+		// A concurrent evaluation may have opened it first, the unique index keeps one
+		created, err := s.alertRepo.CreateAlert(ctx, alert)
+		if err != nil {
+			log.Printf("Failed to open stock alert for reorder rule %d: %v", rule.ID, err)
+			return
+		}
+		if !created {
+			return
+		}
+		s.deliver(alert, "low_stock", toLowStockDTO(*rule, available))
+
+	case available > rule.MinQuantity && open != nil:
+		now := time.Now()
+		open.Status = models.AlertStatusResolved
+		open.ResolvedAt = &now
+		if err := s.alertRepo.UpdateAlert(ctx, open); err != nil {
+			log.Printf("Failed to resolve stock alert %d: %v", open.ID, err)
+			return
+		}
+		s.deliver(open, "low_stock_resolved", toLowStockDTO(*rule, available))
+	}
+}
+
+// deliver posts the alert event to the webhook in the background, retrying a
+// few times and recording the outcome on the alert.
+func (s *AlertService) deliver(alert *models.StockAlert, event string, stock models.LowStockDTO) {
+	if !s.webhookService.Enabled() {
+		return
+	}
+
+	payload := models.AlertEvent{Event: event, Alert: *alert, Stock: stock}
+	go func() {
+		ctx := context.Background()
+		var lastErr error
+		attempts := 0
+		for attempts < webhookAttempts {
+			attempts++
+			if lastErr = s.webhookService.Send(ctx, payload); lastErr == nil {
+				break
+			}
+			if attempts < webhookAttempts {
+				time.Sleep(time.Duration(attempts) * 2 * time.Second)
+			}
+		}
+		if err := s.alertRepo.RecordDelivery(ctx, alert.ID, attempts, lastErr); err != nil {
+			log.Printf("Failed to record delivery of stock alert %d: %v", alert.ID, err)
+		}
+	}()
+}
+
+func toLowStockDTO(rule models.ReorderRule, available models.Quantity) models.LowStockDTO {
+	dto := models.LowStockDTO{
+		RuleID:         rule.ID,
+		ProductID:      rule.ProductID,
+		LocationID:     rule.LocationID,
+		Available:      available,
+		MinQuantity:    rule.MinQuantity,
+		MaxQuantity:    rule.MaxQuantity,
+		SuggestedOrder: max(rule.MaxQuantity-available, 0),
+	}
+	if rule.Product != nil {
+		dto.Name = rule.Product.Name
+		dto.SKU = rule.Product.SKU
+	}
+	if rule.Location != nil {
+		dto.LocationCode = rule.Location.Code
+	}
+	return dto
+}
@@ -2,6 +2,9 @@ package usecases
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"stock-management/internal/domain/models"
 	"stock-management/internal/domain/repositories"
@@ -11,9 +14,16 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+)
+
 type AuthService struct {
-	userRepo   repositories.UserRepository
-	jwtService services.JWTService
+	userRepo         repositories.UserRepository
+	refreshTokenRepo repositories.RefreshTokenRepository
+	jwtService       services.JWTService
+	refreshTokenTTL  time.Duration
 }
 
 // UserDTO defines the user data returned to the client.
@@ -21,44 +31,137 @@ type UserDTO struct {
 	ID          uint      `json:"id"`
 	Username    string    `json:"username"`
 	Email       string    `json:"email"`
+	Role        string    `json:"role"`
 	LastLoginAt time.Time `json:"last_login_at"`
 }
 
-func NewAuthService(userRepo repositories.UserRepository, jwtService services.JWTService) *AuthService {
+// AuthTokens is the access/refresh token pair issued on login and refresh.
+type AuthTokens struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refreshToken"`
+}
+
+func NewAuthService(userRepo repositories.UserRepository, refreshTokenRepo repositories.RefreshTokenRepository, jwtService services.JWTService, refreshTokenTTL time.Duration) *AuthService {
 	return &AuthService{
-		userRepo:   userRepo,
-		jwtService: jwtService,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		jwtService:       jwtService,
+		refreshTokenTTL:  refreshTokenTTL,
 	}
 }
 
-func (s *AuthService) Login(ctx context.Context, username, password string) (*UserDTO, string, error) {
+func (s *AuthService) Login(ctx context.Context, username, password string) (*UserDTO, *AuthTokens, error) {
 	user, err := s.userRepo.FindByUsername(ctx, username)
 	if err != nil {
-		return nil, "", errors.New("invalid credentials")
+		return nil, nil, errors.New("invalid credentials")
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return nil, "", errors.New("invalid credentials")
+		return nil, nil, errors.New("invalid credentials")
 	}
 
 	user.LastLoginAt = time.Now()
 	if err := s.userRepo.Update(ctx, user); err != nil {
-		return nil, "", err
+		return nil, nil, err
+	}
+
+	// Every login starts a new token family
+	familyID, err := newOpaqueToken()
+	if err != nil {
+		return nil, nil, errors.New("could not generate token: " + err.Error())
+	}
+
+	refreshToken, rawRefresh, err := s.newRefreshToken(user.ID, familyID)
+	if err != nil {
+		return nil, nil, errors.New("could not generate token: " + err.Error())
+	}
+	if err := s.refreshTokenRepo.Create(ctx, refreshToken); err != nil {
+		return nil, nil, err
 	}
 
-	tokenString, err := s.jwtService.GenerateToken(user.ID, user.Username)
+	accessToken, err := s.jwtService.GenerateToken(user.ID, user.Username, string(user.Role), familyID)
 	if err != nil {
-		return nil, "", errors.New("could not generate token: " + err.Error())
+		return nil, nil, errors.New("could not generate token: " + err.Error())
 	}
 
 	userDto := &UserDTO{
 		ID:          user.ID,
 		Username:    user.Username,
 		Email:       user.Email,
+		Role:        string(user.Role),
 		LastLoginAt: user.LastLoginAt,
 	}
 
-	return userDto, tokenString, nil
+	return userDto, &AuthTokens{AccessToken: accessToken, RefreshToken: rawRefresh}, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. The presented token
+// is single use: presenting it a second time revokes the whole family, since
+// that means either the client or an attacker holds a stolen copy.
+func (s *AuthService) Refresh(ctx context.Context, rawRefresh string) (*AuthTokens, error) {
+	current, err := s.refreshTokenRepo.FindByHash(ctx, hashToken(rawRefresh))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if current.RevokedAt != nil {
+		if err := s.refreshTokenRepo.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(current.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.FindByID(ctx, current.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	next, rawNext, err := s.newRefreshToken(user.ID, current.FamilyID)
+	if err != nil {
+		return nil, errors.New("could not generate token: " + err.Error())
+	}
+
+	rotated, err := s.refreshTokenRepo.Rotate(ctx, current, next)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// Lost a race against another refresh with the same token
+		if err := s.refreshTokenRepo.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	accessToken, err := s.jwtService.GenerateToken(user.ID, user.Username, string(user.Role), current.FamilyID)
+	if err != nil {
+		return nil, errors.New("could not generate token: " + err.Error())
+	}
+
+	return &AuthTokens{AccessToken: accessToken, RefreshToken: rawNext}, nil
+}
+
+// Logout revokes the token family the refresh token belongs to, which also
+// invalidates every access token issued for that session.
+func (s *AuthService) Logout(ctx context.Context, rawRefresh string) error {
+	current, err := s.refreshTokenRepo.FindByHash(ctx, hashToken(rawRefresh))
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+	return s.refreshTokenRepo.RevokeFamily(ctx, current.FamilyID)
+}
+
+// IsSessionActive reports whether the session an access token was issued for
+// has not been logged out or revoked.
+func (s *AuthService) IsSessionActive(ctx context.Context, sessionID string) (bool, error) {
+	if sessionID == "" {
+		return false, nil
+	}
+	return s.refreshTokenRepo.IsFamilyActive(ctx, sessionID)
 }
 
 func (s *AuthService) Register(ctx context.Context, username, password, email string) error {
@@ -67,11 +170,83 @@ func (s *AuthService) Register(ctx context.Context, username, password, email st
 		return err
 	}
 
+	// The very first account bootstraps the system and becomes admin,
+	// everyone else starts as a read-only viewer until promoted.
 	user := &models.User{
 		Username: username,
 		Password: string(hashedPassword),
 		Email:    email,
+		Role:     models.RoleViewer,
+	}
+
+	return s.userRepo.CreateBootstrapping(ctx, user)
+}
+
+// EnsureAdmin makes sure someone can manage roles. When no admin exists it
+// promotes the named user; a database with users but no admin needs one.
+func (s *AuthService) EnsureAdmin(ctx context.Context, username string) (*UserDTO, error) {
+	user, err := s.userRepo.EnsureAdmin(ctx, username)
+	if err != nil || user == nil {
+		return nil, err
 	}
+	return &UserDTO{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        string(user.Role),
+		LastLoginAt: user.LastLoginAt,
+	}, nil
+}
+
+func (s *AuthService) SetRole(ctx context.Context, userID uint, role models.Role) (*UserDTO, error) {
+	if !role.Valid() {
+		return nil, errors.New("invalid role")
+	}
+
+	user, err := s.userRepo.SetRole(ctx, userID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	// Access tokens carry the role, so end the user's sessions to make the
+	// change apply now rather than when their tokens expire
+	if err := s.refreshTokenRepo.RevokeUser(ctx, user.ID); err != nil {
+		return nil, err
+	}
+
+	return &UserDTO{
+		ID:          user.ID,
+		Username:    user.Username,
+		Email:       user.Email,
+		Role:        string(user.Role),
+		LastLoginAt: user.LastLoginAt,
+	}, nil
+}
+
+func (s *AuthService) newRefreshToken(userID uint, familyID string) (*models.RefreshToken, string, error) {
+	raw, err := newOpaqueToken()
+	if err != nil {
+		return nil, "", err
+	}
+	return &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(s.refreshTokenTTL),
+	}, raw, nil
+}
+
+// newOpaqueToken returns 32 random bytes, hex encoded.
+func newOpaqueToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
 
-	return s.userRepo.Create(ctx, user)
+// hashToken is what gets persisted, so a database leak does not leak usable tokens.
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
 }
@@ -0,0 +1,116 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"stock-management/internal/domain/services"
+	"strings"
+)
+
+// maxLabelCopies bounds the labels printed per product in one request.
+const maxLabelCopies = 100
+
+type BarcodeService struct {
+	barcodeRepo  repositories.BarcodeRepository
+	stockRepo    repositories.StockRepository
+	labelService services.LabelService
+}
+
+func NewBarcodeService(barcodeRepo repositories.BarcodeRepository, stockRepo repositories.StockRepository, labelService services.LabelService) *BarcodeService {
+	return &BarcodeService{barcodeRepo: barcodeRepo, stockRepo: stockRepo, labelService: labelService}
+}
+
+func (s *BarcodeService) AddBarcode(ctx context.Context, barcode *models.Barcode) error {
+	barcode.Code = strings.TrimSpace(barcode.Code)
+	barcode.Symbology = strings.ToLower(barcode.Symbology)
+	if err := barcode.Validate(); err != nil {
+		return err
+	}
+	barcode.Normalize()
+	if _, err := s.stockRepo.GetProduct(ctx, barcode.ProductID); err != nil {
+		return errors.New("product not found")
+	}
+	return s.barcodeRepo.AddBarcode(ctx, barcode)
+}
+
+func (s *BarcodeService) GetBarcodes(ctx context.Context, productID uint) ([]models.Barcode, error) {
+	if _, err := s.stockRepo.GetProduct(ctx, productID); err != nil {
+		return nil, errors.New("product not found")
+	}
+	return s.barcodeRepo.GetBarcodes(ctx, productID)
+}
+
+func (s *BarcodeService) DeleteBarcode(ctx context.Context, productID, id uint) error {
+	return s.barcodeRepo.DeleteBarcode(ctx, productID, id)
+}
+
+// Lookup finds the product a scanned barcode or SKU belongs to, with its
+// stock.
+func (s *BarcodeService) Lookup(ctx context.Context, scanned string) (*models.ProductDTO, error) {
+	if strings.TrimSpace(scanned) == "" {
+		return nil, errors.New("barcode is required")
+	}
+	product, err := s.barcodeRepo.FindProductByBarcode(ctx, models.BarcodeCandidates(scanned))
+	if errors.Is(err, repositories.ErrAmbiguousBarcode) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.New("no product has barcode " + scanned)
+	}
+
+	quantity, err := s.stockRepo.GetProductQuantity(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+	reserved, err := s.stockRepo.GetProductReserved(ctx, product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	productDTO := toProductDTO(*product, quantity, reserved)
+	return &productDTO, nil
+}
+
+// RenderLabels prints copies labels for each product. A label encodes the
+// product's first barcode, or its SKU when it has none.
+func (s *BarcodeService) RenderLabels(ctx context.Context, productIDs []uint, symbology, format string, copies int) ([]byte, string, error) {
+	if len(productIDs) == 0 {
+		return nil, "", errors.New("at least one product is required")
+	}
+	if copies == 0 {
+		copies = 1
+	}
+	if copies < 0 || copies > maxLabelCopies {
+		return nil, "", fmt.Errorf("copies must be between 1 and %d", maxLabelCopies)
+	}
+	if symbology == "" {
+		symbology = services.LabelCode128
+	}
+	if format == "" {
+		format = services.LabelPDF
+	}
+
+	var labels []services.Label
+	for _, productID := range productIDs {
+		product, err := s.stockRepo.GetProduct(ctx, productID)
+		if err != nil {
+			return nil, "", fmt.Errorf("product %d not found", productID)
+		}
+		barcodes, err := s.barcodeRepo.GetBarcodes(ctx, productID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		label := services.Label{Title: product.Name, Code: product.SKU, Caption: product.SKU}
+		if len(barcodes) > 0 {
+			label.Code = barcodes[0].Code
+		}
+		for i := 0; i < copies; i++ {
+			labels = append(labels, label)
+		}
+	}
+	return s.labelService.Render(labels, strings.ToLower(symbology), strings.ToLower(format))
+}
@@ -0,0 +1,169 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+)
+
+type CountService struct {
+	countRepo repositories.CountRepository
+	stockRepo repositories.StockRepository
+	observers []StockObserver
+}
+
+func NewCountService(countRepo repositories.CountRepository, stockRepo repositories.StockRepository, observers ...StockObserver) *CountService {
+	return &CountService{
+		countRepo: countRepo,
+		stockRepo: stockRepo,
+		observers: observers,
+	}
+}
+
+// OpenSession starts a count of a location, a category or a category within
+// a location, snapshotting the quantities the books expect.
+func (s *CountService) OpenSession(ctx context.Context, session *models.CountSession, userID uint) error {
+	if session.LocationID == nil && session.CategoryID == nil {
+		return errors.New("a count needs a location or a category")
+	}
+	if session.LocationID != nil {
+		if _, err := s.stockRepo.GetLocation(ctx, *session.LocationID); err != nil {
+			return errors.New("location not found")
+		}
+	}
+	if session.CategoryID != nil {
+		if category, err := s.stockRepo.GetCategory(ctx, *session.CategoryID); err != nil || category == nil {
+			return errors.New("category not found")
+		}
+	}
+
+	session.CreatedByID = userID
+	return s.countRepo.CreateSession(ctx, session)
+}
+
+func (s *CountService) GetSession(ctx context.Context, id uint) (*models.CountSession, error) {
+	session, err := s.countRepo.GetSession(ctx, id)
+	if err != nil {
+		return nil, errors.New("count not found")
+	}
+	return session, nil
+}
+
+func (s *CountService) GetSessions(ctx context.Context, status *string) ([]models.CountSession, error) {
+	return s.countRepo.GetSessions(ctx, status)
+}
+
+// RecordCounts stores counted quantities; several counters may count the
+// same session, and a recount of a line replaces the earlier count.
+func (s *CountService) RecordCounts(ctx context.Context, id uint, lines []models.CountLine, userID uint) (*models.CountSession, error) {
+	if len(lines) == 0 {
+		return nil, errors.New("no counts to record")
+	}
+	for _, line := range lines {
+		if line.ProductID == 0 || line.LocationID == 0 {
+			return nil, errors.New("product and location are required")
+		}
+		if line.CountedQuantity == nil || *line.CountedQuantity < 0 {
+			return nil, errors.New("counted quantity cannot be negative")
+		}
+		product, err := s.stockRepo.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			return nil, fmt.Errorf("product %d not found", line.ProductID)
+		}
+		if err := checkPrecision(product, *line.CountedQuantity); err != nil {
+			return nil, err
+		}
+	}
+	return s.countRepo.RecordCounts(ctx, id, lines, userID)
+}
+
+// StartReview closes counting so the variances can be reviewed.
+func (s *CountService) StartReview(ctx context.Context, id uint) (*models.CountSession, error) {
+	return s.countRepo.SetStatus(ctx, id, models.CountStatusCounting, models.CountStatusReview)
+}
+
+// ReopenCounting sends a count under review back to the counters, e.g. to
+// recount lines with suspicious variances.
+func (s *CountService) ReopenCounting(ctx context.Context, id uint) (*models.CountSession, error) {
+	return s.countRepo.SetStatus(ctx, id, models.CountStatusReview, models.CountStatusCounting)
+}
+
+// GetVariances lists the counted lines whose count differs from the snapshot.
+func (s *CountService) GetVariances(ctx context.Context, id uint) ([]models.CountVarianceDTO, error) {
+	session, err := s.countRepo.GetSession(ctx, id)
+	if err != nil {
+		return nil, errors.New("count not found")
+	}
+
+	variances := make([]models.CountVarianceDTO, 0)
+	for _, line := range session.Lines {
+		if line.CountedQuantity == nil || line.Variance() == 0 {
+			continue
+		}
+
+		variance := models.CountVarianceDTO{
+			LineID:           line.ID,
+			ProductID:        line.ProductID,
+			LocationID:       line.LocationID,
+			ExpectedQuantity: line.ExpectedQuantity,
+			CountedQuantity:  *line.CountedQuantity,
+			Variance:         line.Variance(),
+			Approved:         line.Approved,
+		}
+		if line.Product != nil {
+			variance.ProductName = line.Product.Name
+			variance.SKU = line.Product.SKU
+		}
+		if line.Location != nil {
+			variance.LocationCode = line.Location.Code
+		}
+		variances = append(variances, variance)
+	}
+	return variances, nil
+}
+
+// ApproveLines approves the variances of the given lines, or of every
+// counted line when none are given. Only approved variances are posted.
+func (s *CountService) ApproveLines(ctx context.Context, id uint, lineIDs []uint) (*models.CountSession, error) {
+	seen := make(map[uint]bool, len(lineIDs))
+	unique := make([]uint, 0, len(lineIDs))
+	for _, lineID := range lineIDs {
+		if !seen[lineID] {
+			seen[lineID] = true
+			unique = append(unique, lineID)
+		}
+	}
+	return s.countRepo.ApproveLines(ctx, id, unique)
+}
+
+// PostSession books the approved variances as count corrections under the
+// given reason code, all or nothing.
+func (s *CountService) PostSession(ctx context.Context, id uint, reasonCode string, userID uint) (*models.CountSession, error) {
+	reasonCodeID, err := resolveReasonCode(ctx, s.stockRepo, models.MovementCountCorrection, reasonCode)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := s.countRepo.PostSession(ctx, id, *reasonCodeID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range session.Lines {
+		if line.Approved && line.Variance() != 0 {
+			notifyStockChanged(ctx, s.observers, line.ProductID, line.LocationID)
+		}
+	}
+	return session, nil
+}
+
+// CancelSession abandons a count without posting anything.
+func (s *CountService) CancelSession(ctx context.Context, id uint) (*models.CountSession, error) {
+	session, err := s.countRepo.GetSession(ctx, id)
+	if err != nil {
+		return nil, errors.New("count not found")
+	}
+	return s.countRepo.SetStatus(ctx, id, session.Status, models.CountStatusCancelled)
+}
@@ -0,0 +1,120 @@
+package usecases
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"sync"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyReused     = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+	ErrIdempotencyClaimLost     = errors.New("idempotency key was claimed by another request")
+)
+
+// idempotencyKeyTTL is how long a key and its stored response are kept.
+const idempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyLease is how long a claimed key stays in progress unless it is
+// renewed. The request holding it renews it while it runs, so only a claim
+// whose process died runs out and lets a retry through.
+const idempotencyLease = 2 * time.Minute
+
+// idempotencyRenewal is how often a running request renews its lease.
+const idempotencyRenewal = idempotencyLease / 4
+
+type IdempotencyService struct {
+	idempotencyRepo repositories.IdempotencyRepository
+}
+
+func NewIdempotencyService(idempotencyRepo repositories.IdempotencyRepository) *IdempotencyService {
+	return &IdempotencyService{idempotencyRepo: idempotencyRepo}
+}
+
+// Begin claims key for this request. When the key was already used for the
+// identical request and has completed, the stored response is returned for
+// replay and claimed is nil. Otherwise the caller must finish the returned
+// claim with Complete or Release.
+func (s *IdempotencyService) Begin(ctx context.Context, userID uint, key, endpoint string, body []byte) (claimed *models.IdempotencyKey, replay *models.IdempotencyKey, err error) {
+	sum := sha256.Sum256(append([]byte(endpoint+"\n"), body...))
+	record := &models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		Endpoint:    endpoint,
+		RequestHash: hex.EncodeToString(sum[:]),
+		ExpiresAt:   time.Now().Add(idempotencyLease),
+	}
+
+	ok, existing, err := s.idempotencyRepo.Claim(ctx, record)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ok {
+		return record, nil, nil
+	}
+
+	if existing.RequestHash != record.RequestHash {
+		return nil, nil, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed {
+		return nil, nil, ErrIdempotencyKeyInProgress
+	}
+	return nil, existing, nil
+}
+
+// KeepAlive renews the lease of a claimed key until the returned function is
+// called, so a request that runs long is not taken for a dead one and run a
+// second time by a retry.
+func (s *IdempotencyService) KeepAlive(ctx context.Context, claimed *models.IdempotencyKey) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(idempotencyRenewal)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				held, err := s.idempotencyRepo.Renew(ctx, claimed.ID, time.Now().Add(idempotencyLease))
+				if err == nil && !held {
+					return
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+// Complete stores the response so retries replay it, and keeps the key
+// for idempotencyKeyTTL from now. A claim that was lost stores nothing.
+func (s *IdempotencyService) Complete(ctx context.Context, claimed *models.IdempotencyKey, status int, body []byte) error {
+	held, err := s.idempotencyRepo.Complete(ctx, claimed.ID, status, body, time.Now().Add(idempotencyKeyTTL))
+	if err == nil && !held {
+		return ErrIdempotencyClaimLost
+	}
+	return err
+}
+
+// Release gives the key up again, used when the request failed in a way a
+// retry might not.
+func (s *IdempotencyService) Release(ctx context.Context, claimed *models.IdempotencyKey) error {
+	held, err := s.idempotencyRepo.Release(ctx, claimed.ID)
+	if err == nil && !held {
+		return ErrIdempotencyClaimLost
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package usecases_test
+
+import (
+	"context"
+	"errors"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/usecases"
+	"testing"
+	"time"
+)
+
+// leaseRepository holds a single claim whose lease the test can end.
+type leaseRepository struct {
+	claim  *models.IdempotencyKey
+	nextID uint
+}
+
+func (r *leaseRepository) Claim(ctx context.Context, record *models.IdempotencyKey) (bool, *models.IdempotencyKey, error) {
+	if r.claim != nil && r.claim.ExpiresAt.After(time.Now()) {
+		existing := *r.claim
+		return false, &existing, nil
+	}
+	r.nextID++
+	record.ID = r.nextID
+	claim := *record
+	r.claim = &claim
+	return true, nil, nil
+}
+
+func (r *leaseRepository) held(id uint) bool {
+	return r.claim != nil && r.claim.ID == id && !r.claim.Completed
+}
+
+func (r *leaseRepository) Renew(ctx context.Context, id uint, expiresAt time.Time) (bool, error) {
+	if !r.held(id) {
+		return false, nil
+	}
+	r.claim.ExpiresAt = expiresAt
+	return true, nil
+}
+
+func (r *leaseRepository) Complete(ctx context.Context, id uint, status int, body []byte, expiresAt time.Time) (bool, error) {
+	if !r.held(id) {
+		return false, nil
+	}
+	r.claim.Completed = true
+	r.claim.ResponseStatus = status
+	r.claim.ResponseBody = body
+	r.claim.ExpiresAt = expiresAt
+	return true, nil
+}
+
+func (r *leaseRepository) Release(ctx context.Context, id uint) (bool, error) {
+	if !r.held(id) {
+		return false, nil
+	}
+	r.claim = nil
+	return true, nil
+}
+
+// TestIdempotencyClaimFencing lets a retry claim a key whose lease ran out
+// and keeps the request that lost the claim from storing its response.
+func TestIdempotencyClaimFencing(t *testing.T) {
+	ctx := context.Background()
+	repo := &leaseRepository{}
+	service := usecases.NewIdempotencyService(repo)
+
+	stale, _, err := service.Begin(ctx, 1, "scan-1", "POST /api/stock/import", []byte(`{}`))
+	if err != nil || stale == nil {
+		t.Fatalf("first claim: %v", err)
+	}
+	if _, _, err := service.Begin(ctx, 1, "scan-1", "POST /api/stock/import", []byte(`{}`)); !errors.Is(err, usecases.ErrIdempotencyKeyInProgress) {
+		t.Fatalf("retry within the lease: %v, want ErrIdempotencyKeyInProgress", err)
+	}
+	if _, _, err := service.Begin(ctx, 1, "scan-1", "POST /api/stock/import", []byte(`{"quantity":2}`)); !errors.Is(err, usecases.ErrIdempotencyKeyReused) {
+		t.Fatalf("different payload: %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// The first request's process stopped renewing and its lease ran out
+	repo.claim.ExpiresAt = time.Now().Add(-time.Second)
+	current, _, err := service.Begin(ctx, 1, "scan-1", "POST /api/stock/import", []byte(`{}`))
+	if err != nil || current == nil {
+		t.Fatalf("claim after the lease ran out: %v", err)
+	}
+
+	if err := service.Complete(ctx, stale, 201, []byte(`{"stale":true}`)); !errors.Is(err, usecases.ErrIdempotencyClaimLost) {
+		t.Errorf("stale holder completing: %v, want ErrIdempotencyClaimLost", err)
+	}
+	if err := service.Release(ctx, stale); !errors.Is(err, usecases.ErrIdempotencyClaimLost) {
+		t.Errorf("stale holder releasing: %v, want ErrIdempotencyClaimLost", err)
+	}
+	if err := service.Complete(ctx, current, 201, []byte(`{}`)); err != nil {
+		t.Fatalf("current holder completing: %v", err)
+	}
+
+	_, replay, err := service.Begin(ctx, 1, "scan-1", "POST /api/stock/import", []byte(`{}`))
+	if err != nil || replay == nil || string(replay.ResponseBody) != `{}` {
+		t.Errorf("replay = %+v, %v, want the current holder's response", replay, err)
+	}
+}
@@ -0,0 +1,144 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+)
+
+type KitService struct {
+	kitRepo   repositories.KitRepository
+	stockRepo repositories.StockRepository
+	observers []StockObserver
+}
+
+func NewKitService(kitRepo repositories.KitRepository, stockRepo repositories.StockRepository, observers ...StockObserver) *KitService {
+	return &KitService{kitRepo: kitRepo, stockRepo: stockRepo, observers: observers}
+}
+
+// SetComponents replaces the bill of materials of a kit; an empty list
+// turns the kit back into a plain product.
+func (s *KitService) SetComponents(ctx context.Context, kitID uint, components []models.KitComponent) (*models.KitDTO, error) {
+	seen := make(map[uint]bool, len(components))
+	for _, component := range components {
+		if component.Quantity <= 0 {
+			return nil, errors.New("component quantity must be greater than 0")
+		}
+		if seen[component.ComponentID] {
+			return nil, errors.New("each component may appear only once per kit")
+		}
+		seen[component.ComponentID] = true
+	}
+
+	if err := s.kitRepo.SetComponents(ctx, kitID, components); err != nil {
+		return nil, err
+	}
+	return s.GetKit(ctx, kitID, nil)
+}
+
+// GetKit returns the bill of materials of a kit with how many kits the
+// available components allow to assemble, at one location or over all.
+func (s *KitService) GetKit(ctx context.Context, kitID uint, locationID *uint) (*models.KitDTO, error) {
+	kit, err := s.stockRepo.GetProduct(ctx, kitID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	components, err := s.kitRepo.GetComponents(ctx, kitID)
+	if err != nil {
+		return nil, err
+	}
+
+	kitDTO := &models.KitDTO{
+		KitID:      kit.ID,
+		Name:       kit.Name,
+		SKU:        kit.SKU,
+		LocationID: locationID,
+		Components: []models.KitComponentDTO{},
+	}
+	for i, component := range components {
+		available, err := s.kitRepo.GetAvailableQuantity(ctx, component.ComponentID, locationID)
+		if err != nil {
+			return nil, err
+		}
+		// Only whole steps of the kit's precision can be assembled
+		buildable, err := max(available, 0).Div(component.Quantity)
+		if err != nil {
+			return nil, err
+		}
+		buildable = buildable.Truncate(kit.QuantityDecimals)
+
+		componentDTO := models.KitComponentDTO{
+			ComponentID: component.ComponentID,
+			Quantity:    component.Quantity,
+			Available:   available,
+			Buildable:   buildable,
+		}
+		if component.Component != nil {
+			componentDTO.Name = component.Component.Name
+			componentDTO.SKU = component.Component.SKU
+		}
+		kitDTO.Components = append(kitDTO.Components, componentDTO)
+
+		if i == 0 || buildable < kitDTO.Buildable {
+			kitDTO.Buildable = buildable
+		}
+	}
+	return kitDTO, nil
+}
+
+// Assemble consumes the components of quantity kits at a location and
+// produces the kits, all in one transaction.
+func (s *KitService) Assemble(ctx context.Context, kitID, locationID uint, quantity models.Quantity, userID uint, notes string) (*models.KitBuildDTO, error) {
+	return s.build(ctx, models.KitBuildAssembly, kitID, locationID, quantity, userID, notes)
+}
+
+// Disassemble takes quantity kits apart at a location and puts their
+// components back into stock, all in one transaction.
+func (s *KitService) Disassemble(ctx context.Context, kitID, locationID uint, quantity models.Quantity, userID uint, notes string) (*models.KitBuildDTO, error) {
+	return s.build(ctx, models.KitBuildDisassembly, kitID, locationID, quantity, userID, notes)
+}
+
+func (s *KitService) build(ctx context.Context, buildType string, kitID, locationID uint, quantity models.Quantity, userID uint, notes string) (*models.KitBuildDTO, error) {
+	if quantity <= 0 {
+		return nil, errors.New("quantity must be greater than 0")
+	}
+	if _, err := s.stockRepo.GetLocation(ctx, locationID); err != nil {
+		return nil, errors.New("location not found")
+	}
+	if _, err := s.stockRepo.GetProduct(ctx, kitID); err != nil {
+		return nil, errors.New("product not found")
+	}
+
+	build := &models.KitBuild{
+		KitID:      kitID,
+		LocationID: locationID,
+		Type:       buildType,
+		Quantity:   quantity,
+		UserID:     userID,
+		Notes:      notes,
+	}
+	if err := s.kitRepo.CreateKitBuild(ctx, build); err != nil {
+		return nil, err
+	}
+
+	build, err := s.kitRepo.GetKitBuild(ctx, build.ID)
+	if err != nil {
+		return nil, err
+	}
+	buildDTO := &models.KitBuildDTO{
+		ID:         build.ID,
+		KitID:      build.KitID,
+		LocationID: build.LocationID,
+		Type:       build.Type,
+		Quantity:   build.Quantity,
+		Notes:      build.Notes,
+		CreatedAt:  build.CreatedAt,
+		Movements:  []models.MovementDTO{},
+	}
+	for _, movement := range build.Movements {
+		buildDTO.Movements = append(buildDTO.Movements, toMovementDTO(movement))
+		notifyStockChanged(ctx, s.observers, movement.ProductID, movement.LocationID)
+	}
+	return buildDTO, nil
+}
@@ -0,0 +1,94 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"time"
+)
+
+type PeriodService struct {
+	periodRepo repositories.PeriodRepository
+}
+
+func NewPeriodService(periodRepo repositories.PeriodRepository) *PeriodService {
+	return &PeriodService{periodRepo: periodRepo}
+}
+
+// ClosePeriod locks a month that has ended; movements dated inside it are
+// rejected from then on.
+func (s *PeriodService) ClosePeriod(ctx context.Context, year, month int, notes string, userID uint) (*models.ClosedPeriod, error) {
+	if month < 1 || month > 12 {
+		return nil, errors.New("month must be between 1 and 12")
+	}
+
+	period := &models.ClosedPeriod{
+		Year:       year,
+		Month:      month,
+		Notes:      notes,
+		ClosedByID: userID,
+		ClosedAt:   time.Now(),
+	}
+	if period.End(time.Local).After(period.ClosedAt) {
+		return nil, errors.New("only past months can be closed")
+	}
+
+	if err := s.periodRepo.ClosePeriod(ctx, period); err != nil {
+		return nil, err
+	}
+	return period, nil
+}
+
+// ReopenPeriod unlocks a closed month, e.g. to post a late correction.
+func (s *PeriodService) ReopenPeriod(ctx context.Context, year, month int) error {
+	return s.periodRepo.ReopenPeriod(ctx, year, month)
+}
+
+func (s *PeriodService) GetClosedPeriods(ctx context.Context) ([]models.ClosedPeriod, error) {
+	return s.periodRepo.GetClosedPeriods(ctx)
+}
+
+func (s *PeriodService) GetSnapshots(ctx context.Context, day time.Time, locationID *uint) ([]models.StockSnapshot, error) {
+	return s.periodRepo.GetSnapshots(ctx, startOfDay(day), locationID)
+}
+
+// TakeDailySnapshot snapshots the stock unless today already has a snapshot.
+func (s *PeriodService) TakeDailySnapshot(ctx context.Context, now time.Time) (int, error) {
+	day := startOfDay(now)
+	taken, err := s.periodRepo.HasSnapshot(ctx, day)
+	if err != nil || taken {
+		return 0, err
+	}
+	return s.periodRepo.TakeSnapshot(ctx, day)
+}
+
+// RunSnapshotJob takes the daily stock snapshot, checking every interval
+// whether today's is still missing, until ctx is cancelled. It is meant to
+// run in its own goroutine.
+func (s *PeriodService) RunSnapshotJob(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			written, err := s.TakeDailySnapshot(ctx, now)
+			if err != nil {
+				log.Printf("Failed to take stock snapshot: %v", err)
+				continue
+			}
+			if written > 0 {
+				log.Printf("Took stock snapshot of %d product locations", written)
+			}
+		}
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,241 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"time"
+)
+
+type PurchaseService struct {
+	purchaseRepo repositories.PurchaseRepository
+	stockRepo    repositories.StockRepository
+	observers    []StockObserver
+}
+
+func NewPurchaseService(purchaseRepo repositories.PurchaseRepository, stockRepo repositories.StockRepository, observers ...StockObserver) *PurchaseService {
+	return &PurchaseService{
+		purchaseRepo: purchaseRepo,
+		stockRepo:    stockRepo,
+		observers:    observers,
+	}
+}
+
+func (s *PurchaseService) GetSuppliers(ctx context.Context) ([]models.Supplier, error) {
+	return s.purchaseRepo.GetSuppliers(ctx)
+}
+
+func (s *PurchaseService) GetSupplier(ctx context.Context, id uint) (*models.Supplier, error) {
+	supplier, err := s.purchaseRepo.GetSupplier(ctx, id)
+	if err != nil {
+		return nil, errors.New("supplier not found")
+	}
+	return supplier, nil
+}
+
+func (s *PurchaseService) CreateSupplier(ctx context.Context, supplier *models.Supplier) error {
+	if supplier.Code == "" {
+		return errors.New("supplier code is required")
+	}
+	if supplier.Name == "" {
+		return errors.New("supplier name is required")
+	}
+	return s.purchaseRepo.CreateSupplier(ctx, supplier)
+}
+
+func (s *PurchaseService) UpdateSupplier(ctx context.Context, supplier *models.Supplier) error {
+	if supplier.ID == 0 {
+		return errors.New("supplier ID is required")
+	}
+	if supplier.Code == "" {
+		return errors.New("supplier code is required")
+	}
+	if supplier.Name == "" {
+		return errors.New("supplier name is required")
+	}
+
+	existingSupplier, err := s.purchaseRepo.GetSupplier(ctx, supplier.ID)
+	if err != nil {
+		return errors.New("supplier not found")
+	}
+
+	supplier.CreatedAt = existingSupplier.CreatedAt
+	return s.purchaseRepo.UpdateSupplier(ctx, supplier)
+}
+
+func (s *PurchaseService) DeleteSupplier(ctx context.Context, id uint) error {
+	if _, err := s.purchaseRepo.GetSupplier(ctx, id); err != nil {
+		return errors.New("supplier not found")
+	}
+	return s.purchaseRepo.DeleteSupplier(ctx, id)
+}
+
+// CreatePurchaseOrder stores a new order as a draft.
+func (s *PurchaseService) CreatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder, userID uint) error {
+	if err := s.validatePurchaseOrder(ctx, order); err != nil {
+		return err
+	}
+
+	order.CreatedByID = userID
+	return s.purchaseRepo.CreatePurchaseOrder(ctx, order)
+}
+
+// UpdatePurchaseOrder replaces a draft order; approved orders are fixed.
+func (s *PurchaseService) UpdatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder) error {
+	if order.ID == 0 {
+		return errors.New("purchase order ID is required")
+	}
+	if err := s.validatePurchaseOrder(ctx, order); err != nil {
+		return err
+	}
+	if err := s.purchaseRepo.UpdatePurchaseOrder(ctx, order); err != nil {
+		return err
+	}
+
+	updated, err := s.purchaseRepo.GetPurchaseOrder(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	*order = *updated
+	return nil
+}
+
+func (s *PurchaseService) validatePurchaseOrder(ctx context.Context, order *models.PurchaseOrder) error {
+	if order.SupplierID == 0 {
+		return errors.New("supplier is required")
+	}
+	if order.LocationID == 0 {
+		return errors.New("receiving location is required")
+	}
+	if len(order.Lines) == 0 {
+		return errors.New("purchase order needs at least one line")
+	}
+
+	if _, err := s.purchaseRepo.GetSupplier(ctx, order.SupplierID); err != nil {
+		return errors.New("supplier not found")
+	}
+	if _, err := s.stockRepo.GetLocation(ctx, order.LocationID); err != nil {
+		return errors.New("location not found")
+	}
+
+	seen := make(map[uint]bool)
+	for _, line := range order.Lines {
+		if line.Quantity <= 0 {
+			return errors.New("quantity must be greater than 0")
+		}
+		if line.UnitCost < 0 {
+			return errors.New("unit cost cannot be negative")
+		}
+		if seen[line.ProductID] {
+			return errors.New("each product may appear only once per purchase order")
+		}
+		seen[line.ProductID] = true
+
+		product, err := s.stockRepo.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			return fmt.Errorf("product %d not found", line.ProductID)
+		}
+		if err := checkPrecision(product, line.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *PurchaseService) GetPurchaseOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	order, err := s.purchaseRepo.GetPurchaseOrder(ctx, id)
+	if err != nil {
+		return nil, errors.New("purchase order not found")
+	}
+	return order, nil
+}
+
+func (s *PurchaseService) GetPurchaseOrders(ctx context.Context, status *string, supplierID *uint) ([]models.PurchaseOrder, error) {
+	return s.purchaseRepo.GetPurchaseOrders(ctx, status, supplierID)
+}
+
+func (s *PurchaseService) DeletePurchaseOrder(ctx context.Context, id uint) error {
+	return s.purchaseRepo.DeletePurchaseOrder(ctx, id)
+}
+
+func (s *PurchaseService) ApprovePurchaseOrder(ctx context.Context, id uint, userID uint) (*models.PurchaseOrder, error) {
+	return s.purchaseRepo.ApprovePurchaseOrder(ctx, id, userID)
+}
+
+// ClosePurchaseOrder closes an order short, giving up on what is outstanding.
+func (s *PurchaseService) ClosePurchaseOrder(ctx context.Context, id uint) (*models.PurchaseOrder, error) {
+	return s.purchaseRepo.ClosePurchaseOrder(ctx, id)
+}
+
+// PurchaseReceiptLine is a quantity of one purchase order line arriving,
+// with its lot or serial numbers for tracked products. A line may be received
+// in several parts, for example one per lot.
+type PurchaseReceiptLine struct {
+	LineID     uint
+	Quantity   models.Quantity
+	LotNumber  string
+	ExpiryDate *time.Time
+	Serials    []string
+}
+
+// ReceivePurchaseOrder books goods against the order's lines. Passing no lines
+// receives everything outstanding, which only works for untracked products.
+func (s *PurchaseService) ReceivePurchaseOrder(ctx context.Context, id uint, lines []PurchaseReceiptLine, userID uint) (*models.PurchaseOrder, error) {
+	order, err := s.purchaseRepo.GetPurchaseOrder(ctx, id)
+	if err != nil {
+		return nil, errors.New("purchase order not found")
+	}
+
+	orderLines := make(map[uint]*models.PurchaseOrderLine, len(order.Lines))
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		orderLines[line.ID] = line
+		if len(lines) == 0 && line.Outstanding() > 0 && line.Product.TrackingMode != models.TrackingNone {
+			return nil, fmt.Errorf("line %d: lot or serial numbers are required to receive tracked products", line.ID)
+		}
+	}
+
+	receipts := make(map[uint]models.PurchaseReceipt, len(lines))
+	for _, receiptLine := range lines {
+		if receiptLine.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than 0")
+		}
+		orderLine, ok := orderLines[receiptLine.LineID]
+		if !ok {
+			return nil, fmt.Errorf("line %d does not belong to purchase order %d", receiptLine.LineID, order.ID)
+		}
+
+		change := StockChange{
+			Quantity:   receiptLine.Quantity,
+			LotNumber:  receiptLine.LotNumber,
+			ExpiryDate: receiptLine.ExpiryDate,
+			Serials:    receiptLine.Serials,
+		}
+		lots, err := lotsFor(orderLine.Product, change)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", receiptLine.LineID, err)
+		}
+		serials, err := serialsFor(orderLine.Product, change)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", receiptLine.LineID, err)
+		}
+
+		receipt := receipts[receiptLine.LineID]
+		receipt.Quantity += receiptLine.Quantity
+		receipt.Lots = append(receipt.Lots, lots...)
+		receipt.Serials = append(receipt.Serials, serials...)
+		receipts[receiptLine.LineID] = receipt
+	}
+
+	order, err = s.purchaseRepo.ReceivePurchaseOrder(ctx, id, receipts, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range order.Lines {
+		notifyStockChanged(ctx, s.observers, line.ProductID, order.LocationID)
+	}
+	return order, nil
+}
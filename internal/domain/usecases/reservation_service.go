@@ -0,0 +1,110 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"log"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"time"
+)
+
+type ReservationService struct {
+	reservationRepo repositories.ReservationRepository
+	stockRepo       repositories.StockRepository
+	observers       []StockObserver
+}
+
+func NewReservationService(reservationRepo repositories.ReservationRepository, stockRepo repositories.StockRepository, observers ...StockObserver) *ReservationService {
+	return &ReservationService{
+		reservationRepo: reservationRepo,
+		stockRepo:       stockRepo,
+		observers:       observers,
+	}
+}
+
+func (s *ReservationService) CreateReservation(ctx context.Context, reservation *models.Reservation, userID uint) error {
+	if reservation.Quantity <= 0 {
+		return errors.New("quantity must be greater than 0")
+	}
+	if !reservation.ExpiresAt.After(time.Now()) {
+		return errors.New("expiry must be in the future")
+	}
+	product, err := s.stockRepo.GetProduct(ctx, reservation.ProductID)
+	if err != nil {
+		return errors.New("product not found")
+	}
+	if err := checkPrecision(product, reservation.Quantity); err != nil {
+		return err
+	}
+	if _, err := s.stockRepo.GetLocation(ctx, reservation.LocationID); err != nil {
+		return errors.New("location not found")
+	}
+
+	reservation.CreatedByID = userID
+	if err := s.reservationRepo.CreateReservation(ctx, reservation); err != nil {
+		return err
+	}
+
+	// Reserved stock is no longer available
+	notifyStockChanged(ctx, s.observers, reservation.ProductID, reservation.LocationID)
+	return nil
+}
+
+func (s *ReservationService) GetReservation(ctx context.Context, id uint) (*models.Reservation, error) {
+	reservation, err := s.reservationRepo.GetReservation(ctx, id)
+	if err != nil {
+		return nil, errors.New("reservation not found")
+	}
+	return reservation, nil
+}
+
+func (s *ReservationService) GetReservations(ctx context.Context, productID *uint, status *string) ([]models.Reservation, error) {
+	return s.reservationRepo.GetReservations(ctx, productID, status)
+}
+
+func (s *ReservationService) ReleaseReservation(ctx context.Context, id uint) error {
+	reservation, err := s.reservationRepo.GetReservation(ctx, id)
+	if err != nil {
+		return errors.New("reservation not found")
+	}
+	if err := s.reservationRepo.ReleaseReservation(ctx, id); err != nil {
+		return err
+	}
+
+	notifyStockChanged(ctx, s.observers, reservation.ProductID, reservation.LocationID)
+	return nil
+}
+
+// RunExpirySweeper releases expired reservations every interval until ctx is
+// cancelled. It is meant to run in its own goroutine.
+func (s *ReservationService) RunExpirySweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			released, err := s.reservationRepo.ExpireReservations(ctx, now)
+			if err != nil {
+				log.Printf("Failed to release expired reservations: %v", err)
+				continue
+			}
+			if len(released) > 0 {
+				log.Printf("Released %d expired reservations", len(released))
+			}
+
+			// The freed stock is available again
+			notified := make(map[[2]uint]bool)
+			for _, reservation := range released {
+				key := [2]uint{reservation.ProductID, reservation.LocationID}
+				if !notified[key] {
+					notified[key] = true
+					notifyStockChanged(ctx, s.observers, reservation.ProductID, reservation.LocationID)
+				}
+			}
+		}
+	}
+}
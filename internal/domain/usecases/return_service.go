@@ -0,0 +1,184 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"time"
+)
+
+type ReturnService struct {
+	returnRepo repositories.ReturnRepository
+	stockRepo  repositories.StockRepository
+	observers  []StockObserver
+}
+
+func NewReturnService(returnRepo repositories.ReturnRepository, stockRepo repositories.StockRepository, observers ...StockObserver) *ReturnService {
+	return &ReturnService{
+		returnRepo: returnRepo,
+		stockRepo:  stockRepo,
+		observers:  observers,
+	}
+}
+
+// CreateReturn authorizes a customer return against either an export
+// movement or a sales order.
+func (s *ReturnService) CreateReturn(ctx context.Context, rma *models.ReturnAuthorization, userID uint) error {
+	if (rma.MovementID == nil) == (rma.SalesOrderID == nil) {
+		return errors.New("a return needs either a movement or a sales order")
+	}
+	if rma.LocationID == 0 {
+		return errors.New("return location is required")
+	}
+	if len(rma.Lines) == 0 {
+		return errors.New("return needs at least one line")
+	}
+	if _, err := s.stockRepo.GetLocation(ctx, rma.LocationID); err != nil {
+		return errors.New("location not found")
+	}
+
+	seen := make(map[uint]bool)
+	for _, line := range rma.Lines {
+		if line.Quantity <= 0 {
+			return errors.New("quantity must be greater than 0")
+		}
+		if seen[line.ProductID] {
+			return errors.New("each product may appear only once per return")
+		}
+		seen[line.ProductID] = true
+
+		product, err := s.stockRepo.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			return fmt.Errorf("product %d not found", line.ProductID)
+		}
+		if err := checkPrecision(product, line.Quantity); err != nil {
+			return err
+		}
+	}
+
+	rma.CreatedByID = userID
+	return s.returnRepo.CreateReturn(ctx, rma)
+}
+
+func (s *ReturnService) GetReturn(ctx context.Context, id uint) (*models.ReturnAuthorization, error) {
+	rma, err := s.returnRepo.GetReturn(ctx, id)
+	if err != nil {
+		return nil, errors.New("return not found")
+	}
+	return rma, nil
+}
+
+func (s *ReturnService) GetReturns(ctx context.Context, status *string) ([]models.ReturnAuthorization, error) {
+	return s.returnRepo.GetReturns(ctx, status)
+}
+
+// ReceiveReturn puts returned goods into quarantine. Passing no receipts
+// receives everything still expected.
+func (s *ReturnService) ReceiveReturn(ctx context.Context, id uint, receipts map[uint]models.Quantity) (*models.ReturnAuthorization, error) {
+	if len(receipts) > 0 {
+		rma, err := s.returnRepo.GetReturn(ctx, id)
+		if err != nil {
+			return nil, errors.New("return not found")
+		}
+		for _, line := range rma.Lines {
+			quantity, ok := receipts[line.ID]
+			if !ok {
+				continue
+			}
+			if quantity <= 0 {
+				return nil, errors.New("quantity must be greater than 0")
+			}
+			if err := checkPrecision(line.Product, quantity); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.ID, err)
+			}
+		}
+	}
+	return s.returnRepo.ReceiveReturn(ctx, id, receipts)
+}
+
+func (s *ReturnService) CancelReturn(ctx context.Context, id uint) (*models.ReturnAuthorization, error) {
+	return s.returnRepo.CancelReturn(ctx, id)
+}
+
+// ReturnInspectionLine is the inspection outcome for quarantined goods of one
+// return line. Restocking a tracked product needs its lot or serial numbers.
+type ReturnInspectionLine struct {
+	LineID           uint
+	RestockQuantity  models.Quantity
+	WriteOffQuantity models.Quantity
+	Notes            string
+	LotNumber        string
+	ExpiryDate       *time.Time
+	Serials          []string
+}
+
+// InspectReturn restocks or writes off quarantined goods.
+func (s *ReturnService) InspectReturn(ctx context.Context, id uint, lines []ReturnInspectionLine, userID uint) (*models.ReturnAuthorization, error) {
+	if len(lines) == 0 {
+		return nil, errors.New("inspection needs at least one line")
+	}
+
+	rma, err := s.returnRepo.GetReturn(ctx, id)
+	if err != nil {
+		return nil, errors.New("return not found")
+	}
+	returnLines := make(map[uint]*models.ReturnLine, len(rma.Lines))
+	for i := range rma.Lines {
+		returnLines[rma.Lines[i].ID] = &rma.Lines[i]
+	}
+
+	inspections := make(map[uint]models.ReturnInspection, len(lines))
+	for _, line := range lines {
+		if line.RestockQuantity < 0 || line.WriteOffQuantity < 0 {
+			return nil, errors.New("quantities cannot be negative")
+		}
+		if line.RestockQuantity+line.WriteOffQuantity == 0 {
+			return nil, fmt.Errorf("line %d: nothing to inspect", line.LineID)
+		}
+		if _, ok := inspections[line.LineID]; ok {
+			return nil, fmt.Errorf("line %d is listed twice", line.LineID)
+		}
+		returnLine, ok := returnLines[line.LineID]
+		if !ok {
+			return nil, fmt.Errorf("line %d does not belong to return %d", line.LineID, rma.ID)
+		}
+		if err := checkPrecision(returnLine.Product, line.RestockQuantity+line.WriteOffQuantity); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line.LineID, err)
+		}
+
+		inspection := models.ReturnInspection{
+			RestockQuantity:  line.RestockQuantity,
+			WriteOffQuantity: line.WriteOffQuantity,
+			Notes:            line.Notes,
+		}
+		if line.RestockQuantity > 0 {
+			change := StockChange{
+				Quantity:   line.RestockQuantity,
+				LotNumber:  line.LotNumber,
+				ExpiryDate: line.ExpiryDate,
+				Serials:    line.Serials,
+			}
+			if inspection.Lots, err = lotsFor(returnLine.Product, change); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.LineID, err)
+			}
+			if inspection.Serials, err = serialsFor(returnLine.Product, change); err != nil {
+				return nil, fmt.Errorf("line %d: %w", line.LineID, err)
+			}
+		}
+		inspections[line.LineID] = inspection
+	}
+
+	inspected, err := s.returnRepo.InspectReturn(ctx, id, inspections, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range lines {
+		if line.RestockQuantity > 0 {
+			notifyStockChanged(ctx, s.observers, returnLines[line.LineID].ProductID, inspected.LocationID)
+		}
+	}
+	return inspected, nil
+}
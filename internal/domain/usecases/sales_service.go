@@ -0,0 +1,272 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"time"
+)
+
+type SalesService struct {
+	salesRepo      repositories.SalesRepository
+	stockRepo      repositories.StockRepository
+	reservationTTL time.Duration
+	observers      []StockObserver
+}
+
+// NewSalesService creates the sales order service. Confirmed orders hold
+// their stock for reservationTTL; after that unshipped goods become
+// available to others again.
+func NewSalesService(salesRepo repositories.SalesRepository, stockRepo repositories.StockRepository, reservationTTL time.Duration, observers ...StockObserver) *SalesService {
+	return &SalesService{
+		salesRepo:      salesRepo,
+		stockRepo:      stockRepo,
+		reservationTTL: reservationTTL,
+		observers:      observers,
+	}
+}
+
+// CreateSalesOrder stores a new order as a draft. Nothing is reserved until
+// the order is confirmed.
+func (s *SalesService) CreateSalesOrder(ctx context.Context, order *models.SalesOrder, userID uint) error {
+	if err := s.validateSalesOrder(ctx, order); err != nil {
+		return err
+	}
+
+	order.CreatedByID = userID
+	return s.salesRepo.CreateSalesOrder(ctx, order)
+}
+
+// UpdateSalesOrder replaces a draft order; confirmed orders are fixed.
+func (s *SalesService) UpdateSalesOrder(ctx context.Context, order *models.SalesOrder) error {
+	if order.ID == 0 {
+		return errors.New("sales order ID is required")
+	}
+	if err := s.validateSalesOrder(ctx, order); err != nil {
+		return err
+	}
+	if err := s.salesRepo.UpdateSalesOrder(ctx, order); err != nil {
+		return err
+	}
+
+	updated, err := s.salesRepo.GetSalesOrder(ctx, order.ID)
+	if err != nil {
+		return err
+	}
+	*order = *updated
+	return nil
+}
+
+func (s *SalesService) validateSalesOrder(ctx context.Context, order *models.SalesOrder) error {
+	if order.CustomerReference == "" {
+		return errors.New("customer reference is required")
+	}
+	if len(order.Lines) == 0 {
+		return errors.New("sales order needs at least one line")
+	}
+	if order.WarehouseID != nil {
+		if _, err := s.stockRepo.GetWarehouse(ctx, *order.WarehouseID); err != nil {
+			return errors.New("warehouse not found")
+		}
+	}
+
+	seen := make(map[uint]bool)
+	for _, line := range order.Lines {
+		if line.Quantity <= 0 {
+			return errors.New("quantity must be greater than 0")
+		}
+		if seen[line.ProductID] {
+			return errors.New("each product may appear only once per sales order")
+		}
+		seen[line.ProductID] = true
+
+		product, err := s.stockRepo.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			return fmt.Errorf("product %d not found", line.ProductID)
+		}
+		if err := checkPrecision(product, line.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SalesService) GetSalesOrder(ctx context.Context, id uint) (*models.SalesOrder, error) {
+	order, err := s.salesRepo.GetSalesOrder(ctx, id)
+	if err != nil {
+		return nil, errors.New("sales order not found")
+	}
+	return order, nil
+}
+
+func (s *SalesService) GetSalesOrders(ctx context.Context, status *string, customerReference *string) ([]models.SalesOrder, error) {
+	return s.salesRepo.GetSalesOrders(ctx, status, customerReference)
+}
+
+func (s *SalesService) DeleteSalesOrder(ctx context.Context, id uint) error {
+	return s.salesRepo.DeleteSalesOrder(ctx, id)
+}
+
+// ConfirmSalesOrder reserves the ordered goods. It fails without reserving
+// anything if any line cannot be covered in full.
+func (s *SalesService) ConfirmSalesOrder(ctx context.Context, id uint, userID uint) (*models.SalesOrder, error) {
+	order, err := s.salesRepo.ConfirmSalesOrder(ctx, id, userID, time.Now().Add(s.reservationTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyReservations(ctx, order)
+	return order, nil
+}
+
+// ReserveSalesOrder renews the reservations of a confirmed order for another
+// reservation period, reserving again whatever lapsed meanwhile.
+func (s *SalesService) ReserveSalesOrder(ctx context.Context, id uint, userID uint) (*models.SalesOrder, error) {
+	order, err := s.salesRepo.ReserveSalesOrder(ctx, id, userID, time.Now().Add(s.reservationTTL))
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyReservations(ctx, order)
+	return order, nil
+}
+
+// GetPickLists groups what is still reserved for an order by warehouse, so
+// each warehouse gets one list of locations to pick from.
+func (s *SalesService) GetPickLists(ctx context.Context, id uint) ([]models.PickListDTO, error) {
+	order, err := s.salesRepo.GetSalesOrder(ctx, id)
+	if err != nil {
+		return nil, errors.New("sales order not found")
+	}
+
+	pickLists := make(map[uint]*models.PickListDTO)
+	now := time.Now()
+	for _, line := range order.Lines {
+		for _, reservation := range line.Reservations {
+			if reservation.Status != models.ReservationStatusActive || !reservation.ExpiresAt.After(now) {
+				continue
+			}
+
+			warehouse := reservation.Location.Warehouse
+			pickList, ok := pickLists[warehouse.ID]
+			if !ok {
+				pickList = &models.PickListDTO{
+					WarehouseID:   warehouse.ID,
+					WarehouseCode: warehouse.Code,
+					WarehouseName: warehouse.Name,
+				}
+				pickLists[warehouse.ID] = pickList
+			}
+
+			pickList.Lines = append(pickList.Lines, models.PickLineDTO{
+				LineID:        line.ID,
+				ReservationID: reservation.ID,
+				ProductID:     line.ProductID,
+				ProductName:   line.Product.Name,
+				SKU:           line.Product.SKU,
+				LocationID:    reservation.LocationID,
+				LocationCode:  reservation.Location.Code,
+				Quantity:      reservation.Quantity,
+			})
+		}
+	}
+
+	result := make([]models.PickListDTO, 0, len(pickLists))
+	for _, pickList := range pickLists {
+		// Walk each warehouse location by location
+		sort.SliceStable(pickList.Lines, func(i, j int) bool {
+			return pickList.Lines[i].LocationCode < pickList.Lines[j].LocationCode
+		})
+		result = append(result, *pickList)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].WarehouseCode < result[j].WarehouseCode })
+	return result, nil
+}
+
+// SalesShipmentLine is a quantity of one sales order line being shipped.
+// Serial-tracked products must name their units and the location they are
+// shipped from.
+type SalesShipmentLine struct {
+	LineID     uint
+	LocationID uint
+	Quantity   models.Quantity
+	Serials    []string
+}
+
+// ShipSalesOrder confirms a shipment and exports the goods from their
+// reservations. Passing no lines ships everything outstanding, which only
+// works for products without serial numbers.
+func (s *SalesService) ShipSalesOrder(ctx context.Context, id uint, lines []SalesShipmentLine, userID uint) (*models.SalesOrder, error) {
+	order, err := s.salesRepo.GetSalesOrder(ctx, id)
+	if err != nil {
+		return nil, errors.New("sales order not found")
+	}
+
+	orderLines := make(map[uint]*models.SalesOrderLine, len(order.Lines))
+	for i := range order.Lines {
+		line := &order.Lines[i]
+		orderLines[line.ID] = line
+		if len(lines) == 0 && line.Outstanding() > 0 && line.Product.TrackingMode == models.TrackingSerial {
+			return nil, fmt.Errorf("line %d: serial numbers are required to ship serial-tracked products", line.ID)
+		}
+	}
+
+	shipments := make([]models.ShipmentLine, 0, len(lines))
+	for _, shipmentLine := range lines {
+		if shipmentLine.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than 0")
+		}
+		orderLine, ok := orderLines[shipmentLine.LineID]
+		if !ok {
+			return nil, fmt.Errorf("line %d does not belong to sales order %d", shipmentLine.LineID, order.ID)
+		}
+
+		serials, err := serialsFor(orderLine.Product, StockChange{Quantity: shipmentLine.Quantity, Serials: shipmentLine.Serials})
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", shipmentLine.LineID, err)
+		}
+		if len(serials) > 0 && shipmentLine.LocationID == 0 {
+			return nil, fmt.Errorf("line %d: location is required when shipping serial numbers", shipmentLine.LineID)
+		}
+
+		shipments = append(shipments, models.ShipmentLine{
+			LineID:     shipmentLine.LineID,
+			LocationID: shipmentLine.LocationID,
+			Quantity:   shipmentLine.Quantity,
+			Serials:    serials,
+		})
+	}
+
+	shipped, err := s.salesRepo.ShipSalesOrder(ctx, id, shipments, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Notify for the reservations as they were before shipping
+	s.notifyReservations(ctx, order)
+	return shipped, nil
+}
+
+// CancelSalesOrder cancels an order and releases its reservations.
+func (s *SalesService) CancelSalesOrder(ctx context.Context, id uint) (*models.SalesOrder, error) {
+	order, err := s.salesRepo.CancelSalesOrder(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.notifyReservations(ctx, order)
+	return order, nil
+}
+
+// notifyReservations tells the observers about every product and location
+// the order has reserved stock at.
+func (s *SalesService) notifyReservations(ctx context.Context, order *models.SalesOrder) {
+	for _, line := range order.Lines {
+		for _, reservation := range line.Reservations {
+			notifyStockChanged(ctx, s.observers, reservation.ProductID, reservation.LocationID)
+		}
+	}
+}
@@ -3,17 +3,32 @@ package usecases
 import (
 	"context"
 	"errors"
+	"fmt"
 	"stock-management/internal/domain/models"
 	"stock-management/internal/domain/repositories"
 	"time"
 )
 
+// StockObserver is told about every committed stock change of a product at a
+// location, e.g. to check reorder points.
+type StockObserver interface {
+	StockChanged(ctx context.Context, productID, locationID uint)
+}
+
+func notifyStockChanged(ctx context.Context, observers []StockObserver, productID, locationID uint) {
+	for _, observer := range observers {
+		observer.StockChanged(ctx, productID, locationID)
+	}
+}
+
 type StockService struct {
-	stockRepo repositories.StockRepository
+	stockRepo  repositories.StockRepository
+	periodRepo repositories.PeriodRepository
+	observers  []StockObserver
 }
 
-func NewStockService(stockRepo repositories.StockRepository) *StockService {
-	return &StockService{stockRepo: stockRepo}
+func NewStockService(stockRepo repositories.StockRepository, periodRepo repositories.PeriodRepository, observers ...StockObserver) *StockService {
+	return &StockService{stockRepo: stockRepo, periodRepo: periodRepo, observers: observers}
 }
 
 func (s *StockService) CreateProduct(ctx context.Context, product *models.Product) error {
@@ -23,34 +38,220 @@ func (s *StockService) CreateProduct(ctx context.Context, product *models.Produc
 	if product.CategoryID == 0 {
 		return errors.New("category is required")
 	}
+	if product.ParentID != nil {
+		return errors.New("variants are generated from the attributes of their parent product")
+	}
+	if err := normalizeTrackingMode(product); err != nil {
+		return err
+	}
+	if err := validateCostingMethod(product); err != nil {
+		return err
+	}
+	if err := validateQuantityDecimals(product); err != nil {
+		return err
+	}
+	if err := s.resolveBaseUnit(ctx, product, nil); err != nil {
+		return err
+	}
 	return s.stockRepo.CreateProduct(ctx, product)
 }
 
-func (s *StockService) UpdateProduct(ctx context.Context, product *models.Product) error {
-	if product.ID == 0 {
-		return errors.New("product ID is required")
+// ProductUpdate holds the product fields to change; nil fields keep their
+// current value.
+type ProductUpdate struct {
+	Name             *string
+	ImageURL         *string
+	Description      *string
+	CategoryID       *uint
+	SKU              *string
+	TrackingMode     *string
+	CostingMethod    *string
+	QuantityDecimals *int
+	BaseUnitID       *uint
+}
+
+func (u ProductUpdate) applyTo(product *models.Product) {
+	if u.Name != nil {
+		product.Name = *u.Name
+	}
+	if u.ImageURL != nil {
+		product.ImageURL = *u.ImageURL
+	}
+	if u.Description != nil {
+		product.Description = *u.Description
+	}
+	if u.CategoryID != nil {
+		product.CategoryID = *u.CategoryID
+	}
+	if u.SKU != nil {
+		product.SKU = *u.SKU
+	}
+	if u.TrackingMode != nil {
+		product.TrackingMode = *u.TrackingMode
+	}
+	if u.CostingMethod != nil {
+		product.CostingMethod = *u.CostingMethod
+	}
+	if u.QuantityDecimals != nil {
+		product.QuantityDecimals = *u.QuantityDecimals
+	}
+	if u.BaseUnitID != nil {
+		product.BaseUnitID = u.BaseUnitID
+	}
+}
+
+// UpdateProduct changes the fields set in update and returns the product as
+// saved. A variant stays with its parent.
+func (s *StockService) UpdateProduct(ctx context.Context, id uint, update ProductUpdate) (*models.Product, error) {
+	existingProduct, err := s.stockRepo.GetProduct(ctx, id)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	product := *existingProduct
+	update.applyTo(&product)
+
+	if err := s.validateProductUpdate(ctx, &product, existingProduct); err != nil {
+		return nil, err
 	}
+	if err := s.stockRepo.UpdateProduct(ctx, &product); err != nil {
+		return nil, err
+	}
+	return &product, nil
+}
+
+func (s *StockService) validateProductUpdate(ctx context.Context, product, existingProduct *models.Product) error {
 	if product.Name == "" {
 		return errors.New("product name is required")
 	}
 	if product.CategoryID == 0 {
 		return errors.New("category is required")
 	}
-
-	// Verify product exists
-	existingProduct, err := s.stockRepo.GetProduct(ctx, product.ID)
-	if err != nil {
+	if err := normalizeTrackingMode(product); err != nil {
 		return err
 	}
-	if existingProduct == nil {
-		return errors.New("product not found")
+	if err := validateCostingMethod(product); err != nil {
+		return err
 	}
+	if err := validateQuantityDecimals(product); err != nil {
+		return err
+	}
+	if err := s.resolveBaseUnit(ctx, product, existingProduct); err != nil {
+		return err
+	}
+	if product.TrackingMode != existingProduct.TrackingMode {
+		// Stock on hand was never recorded under the new mode
+		quantity, err := s.stockRepo.GetProductQuantity(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		if quantity != 0 {
+			return errors.New("tracking mode can only change while the product has no stock")
+		}
+		// Kits and their components cannot be lot or serial tracked
+		inKit, err := s.stockRepo.IsInKit(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		if inKit && product.TrackingMode != models.TrackingNone {
+			return errors.New("kits and kit components cannot be lot or serial tracked")
+		}
+	}
+	if !sameUnit(product.BaseUnitID, existingProduct.BaseUnitID) {
+		// Stock on hand is counted in the old base unit
+		quantity, err := s.stockRepo.GetProductQuantity(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		if quantity != 0 {
+			return errors.New("base unit can only change while the product has no stock")
+		}
+	}
+	if product.QuantityDecimals < existingProduct.QuantityDecimals {
+		// Stock on hand may have more decimals than now allowed
+		quantity, err := s.stockRepo.GetProductQuantity(ctx, product.ID)
+		if err != nil {
+			return err
+		}
+		if quantity != 0 {
+			return errors.New("quantity decimals can only be reduced while the product has no stock")
+		}
+	}
+	return nil
+}
 
-	return s.stockRepo.UpdateProduct(ctx, product)
+func normalizeTrackingMode(product *models.Product) error {
+	switch product.TrackingMode {
+	case "":
+		product.TrackingMode = models.TrackingNone
+	case models.TrackingNone, models.TrackingLot, models.TrackingSerial:
+	default:
+		return errors.New("invalid tracking mode")
+	}
+	return nil
 }
 
-func (s *StockService) GetStockByProductID(ctx context.Context, productID uint) (*models.Stock, error) {
-	return s.stockRepo.GetStock(ctx, productID)
+// validateQuantityDecimals checks the product's precision. Serial-tracked
+// products name every unit, so they are always stocked in whole units.
+func validateQuantityDecimals(product *models.Product) error {
+	if product.QuantityDecimals < 0 || product.QuantityDecimals > models.QuantityDecimals {
+		return fmt.Errorf("quantity decimals must be between 0 and %d", models.QuantityDecimals)
+	}
+	if product.TrackingMode == models.TrackingSerial && product.QuantityDecimals != 0 {
+		return errors.New("serial-tracked products must be stocked in whole units")
+	}
+	return nil
+}
+
+// checkPrecision refuses a quantity with more decimals than the product is
+// stocked in.
+func checkPrecision(product *models.Product, quantity models.Quantity) error {
+	if !product.AllowsQuantity(quantity) {
+		return fmt.Errorf("quantity %s of %s has more than the %d decimals allowed", quantity, product.SKU, product.QuantityDecimals)
+	}
+	return nil
+}
+
+// resolveBaseUnit checks the product's base unit. A new product without one
+// is counted in the default unit; an update without one keeps the old unit.
+func (s *StockService) resolveBaseUnit(ctx context.Context, product, existing *models.Product) error {
+	if product.BaseUnitID == nil {
+		if existing != nil {
+			product.BaseUnitID = existing.BaseUnitID
+			return nil
+		}
+		unit, err := s.stockRepo.GetUnitByCode(ctx, models.DefaultUnitCode)
+		if err != nil {
+			return errors.New("default unit " + models.DefaultUnitCode + " not found")
+		}
+		product.BaseUnitID = &unit.ID
+		return nil
+	}
+
+	if _, err := s.stockRepo.GetUnit(ctx, *product.BaseUnitID); err != nil {
+		return errors.New("base unit not found")
+	}
+	return nil
+}
+
+func sameUnit(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// validateCostingMethod accepts an explicit costing method or none, in which
+// case the product is valued with the configured default.
+func validateCostingMethod(product *models.Product) error {
+	switch product.CostingMethod {
+	case "", models.CostingFIFO, models.CostingAverage:
+		return nil
+	}
+	return errors.New("invalid costing method")
+}
+
+func (s *StockService) GetStockByProductID(ctx context.Context, productID, locationID uint) (*models.Stock, error) {
+	return s.stockRepo.GetStock(ctx, productID, locationID)
 }
 
 func (s *StockService) DeleteProduct(ctx context.Context, id uint) error {
@@ -66,102 +267,595 @@ func (s *StockService) DeleteProduct(ctx context.Context, id uint) error {
 	return s.stockRepo.DeleteProduct(ctx, id)
 }
 
-func (s *StockService) ImportStock(ctx context.Context, productID uint, quantity int, userID uint, notes string) error {
-	if quantity <= 0 {
-		return errors.New("quantity must be greater than 0")
+// StockChange describes a single import or export of one product at one location.
+type StockChange struct {
+	ProductID  uint
+	LocationID uint
+	Quantity   models.Quantity
+	UserID     uint
+	Notes      string
+
+	// Lot details, required when importing a lot-tracked product
+	LotNumber  string
+	ExpiryDate *time.Time
+
+	// Serials names every unit moved, required for serial-tracked products
+	Serials []string
+
+	// ReservationID lets an export draw on stock reserved for it
+	ReservationID *uint
+
+	// ReasonCode optionally explains the change
+	ReasonCode string
+
+	// UnitCost is what one unit cost on import; zero takes the current cost
+	UnitCost models.Money
+
+	// Date backdates an import or export; zero means now
+	Date time.Time
+
+	// Unit is the code of the unit Quantity and UnitCost are given in;
+	// empty means the product's base unit
+	Unit string
+}
+
+// toBaseUnit converts a change booked in one of the product's alternate
+// units to its base unit. It returns the unit booked in, or nil when the
+// change already was in the base unit.
+func toBaseUnit(ctx context.Context, stockRepo repositories.StockRepository, product *models.Product, change *StockChange) (*models.UnitOfMeasure, error) {
+	if change.Unit == "" {
+		return nil, nil
+	}
+	unit, err := stockRepo.GetUnitByCode(ctx, change.Unit)
+	if err != nil {
+		return nil, errors.New("unknown unit " + change.Unit)
+	}
+	if product.BaseUnitID != nil && *product.BaseUnitID == unit.ID {
+		return nil, nil
 	}
 
-	// Create stock movement record
-	movement := &models.StockMovement{
-		ProductID: productID,
-		UserID:    userID,
-		Type:      "import",
-		Quantity:  quantity,
-		Date:      time.Now(),
-		Notes:     notes,
+	productUnit, err := stockRepo.GetProductUnit(ctx, product.ID, unit.ID)
+	if err != nil {
+		return nil, errors.New("product is not handled in " + unit.Code)
+	}
+	quantity, err := change.Quantity.Mul(productUnit.Factor)
+	if err != nil {
+		return nil, err
+	}
+	if quantity == 0 {
+		return nil, fmt.Errorf("%s %s is less than the smallest quantity of the base unit", change.Quantity, unit.Code)
+	}
+	change.Quantity = quantity
+	if change.UnitCost, err = change.UnitCost.DivQuantity(productUnit.Factor); err != nil {
+		return nil, err
+	}
+	return unit, nil
+}
+
+// enteredAs records on a movement the unit and quantity it was booked in.
+func enteredAs(movement *models.StockMovement, unit *models.UnitOfMeasure, quantity models.Quantity) {
+	if unit == nil {
+		return
+	}
+	movement.EnteredUnitID = &unit.ID
+	movement.EnteredQuantity = quantity
+}
+
+// movementDate returns when a change is booked. Backdated changes may not
+// fall into the future or into a closed period.
+func (s *StockService) movementDate(ctx context.Context, change StockChange) (time.Time, error) {
+	now := time.Now()
+	if change.Date.IsZero() {
+		return now, nil
+	}
+	if change.Date.After(now) {
+		return time.Time{}, errors.New("movement date cannot be in the future")
 	}
 
-	// Update stock quantity
-	stock, err := s.stockRepo.GetStock(ctx, productID)
+	closed, err := s.periodRepo.IsPeriodClosed(ctx, change.Date.In(time.Local))
 	if err != nil {
-		// If stock doesn't exist, create new stock
-		stock = &models.Stock{
-			ProductID: productID,
-			Quantity:  quantity,
+		return time.Time{}, err
+	}
+	if closed {
+		return time.Time{}, errors.New("movement date falls in a closed period")
+	}
+	return change.Date, nil
+}
+
+// lotsFor validates the lot details of an incoming change against the
+// product's tracking mode and turns them into the lot the goods arrive in.
+func lotsFor(product *models.Product, change StockChange) ([]models.StockMovementLot, error) {
+	if product.TrackingMode != models.TrackingLot {
+		if change.LotNumber != "" {
+			return nil, errors.New("product is not lot tracked")
 		}
-		return s.stockRepo.CreateStock(stock, movement)
+		return nil, nil
+	}
+	if change.LotNumber == "" || change.ExpiryDate == nil {
+		return nil, errors.New("lot number and expiry date are required for lot-tracked products")
 	}
 
-	// Update existing stock
-	stock.Quantity += quantity
-	return s.stockRepo.UpdateStock(stock, movement)
+	return []models.StockMovementLot{{
+		Lot:      &models.Lot{LotNumber: change.LotNumber, ExpiryDate: change.ExpiryDate},
+		Quantity: change.Quantity,
+	}}, nil
 }
 
-func (s *StockService) ExportStock(ctx context.Context, productID uint, quantity int, userID uint, notes string) error {
-	if quantity <= 0 {
-		return errors.New("quantity must be greater than 0")
+// serialsFor validates the serial numbers of a change against the product's
+// tracking mode and turns them into movement links.
+func serialsFor(product *models.Product, change StockChange) ([]models.StockMovementSerial, error) {
+	if product.TrackingMode != models.TrackingSerial {
+		if len(change.Serials) > 0 {
+			return nil, errors.New("product is not serial tracked")
+		}
+		return nil, nil
 	}
+	if models.Units(len(change.Serials)) != change.Quantity {
+		return nil, errors.New("number of serial numbers must match the quantity")
+	}
+
+	seen := make(map[string]bool, len(change.Serials))
+	serials := make([]models.StockMovementSerial, 0, len(change.Serials))
+	for _, serial := range change.Serials {
+		if serial == "" {
+			return nil, errors.New("serial numbers cannot be empty")
+		}
+		if seen[serial] {
+			return nil, errors.New("duplicate serial number " + serial)
+		}
+		seen[serial] = true
+		serials = append(serials, models.StockMovementSerial{
+			SerialNumber: &models.SerialNumber{Serial: serial},
+		})
+	}
+	return serials, nil
+}
 
-	// Check if we have enough stock
-	stock, err := s.stockRepo.GetStock(ctx, productID)
+func (s *StockService) ImportStock(ctx context.Context, change StockChange) error {
+	if change.Quantity <= 0 {
+		return errors.New("quantity must be greater than 0")
+	}
+	if change.UnitCost < 0 {
+		return errors.New("unit cost cannot be negative")
+	}
+	if _, err := s.stockRepo.GetLocation(ctx, change.LocationID); err != nil {
+		return errors.New("location not found")
+	}
+	product, err := s.stockRepo.GetProduct(ctx, change.ProductID)
 	if err != nil {
-		return errors.New("product not found in stock")
+		return errors.New("product not found")
+	}
+	date, err := s.movementDate(ctx, change)
+	if err != nil {
+		return err
+	}
+	entered := change.Quantity
+	unit, err := toBaseUnit(ctx, s.stockRepo, product, &change)
+	if err != nil {
+		return err
 	}
 
-	if stock.Quantity < quantity {
-		return errors.New("insufficient stock")
+	// Create stock movement record
+	movement := &models.StockMovement{
+		ProductID:  change.ProductID,
+		LocationID: change.LocationID,
+		UserID:     change.UserID,
+		Type:       models.MovementReceipt,
+		Quantity:   change.Quantity,
+		Date:       date,
+		Notes:      change.Notes,
+		UnitCost:   change.UnitCost,
+	}
+	enteredAs(movement, unit, entered)
+	if movement.ReasonCodeID, err = resolveReasonCode(ctx, s.stockRepo, movement.Type, change.ReasonCode); err != nil {
+		return err
+	}
+
+	if movement.Lots, err = lotsFor(product, change); err != nil {
+		return err
+	}
+	if movement.Serials, err = serialsFor(product, change); err != nil {
+		return err
+	}
+
+	// Update stock quantity, creating the stock record on the first import
+	if err := s.stockRepo.ApplyMovement(ctx, movement, change.Quantity); err != nil {
+		return err
+	}
+
+	notifyStockChanged(ctx, s.observers, change.ProductID, change.LocationID)
+	return nil
+}
+
+// ExportStock takes stock out of a location. Lot-tracked products are
+// allocated across lots first-expired-first-out.
+func (s *StockService) ExportStock(ctx context.Context, change StockChange) error {
+	if change.Quantity <= 0 {
+		return errors.New("quantity must be greater than 0")
+	}
+	product, err := s.stockRepo.GetProduct(ctx, change.ProductID)
+	if err != nil {
+		return errors.New("product not found")
+	}
+	date, err := s.movementDate(ctx, change)
+	if err != nil {
+		return err
+	}
+	entered := change.Quantity
+	unit, err := toBaseUnit(ctx, s.stockRepo, product, &change)
+	if err != nil {
+		return err
 	}
 
 	// Create stock movement record
 	movement := &models.StockMovement{
-		ProductID: productID,
-		UserID:    userID,
-		Type:      "export",
-		Quantity:  quantity,
-		Date:      time.Now(),
-		Notes:     notes,
+		ProductID:     change.ProductID,
+		LocationID:    change.LocationID,
+		UserID:        change.UserID,
+		Type:          models.MovementIssue,
+		Quantity:      change.Quantity,
+		Date:          date,
+		Notes:         change.Notes,
+		ReservationID: change.ReservationID,
+	}
+	enteredAs(movement, unit, entered)
+	if movement.ReasonCodeID, err = resolveReasonCode(ctx, s.stockRepo, movement.Type, change.ReasonCode); err != nil {
+		return err
+	}
+
+	if movement.Serials, err = serialsFor(product, change); err != nil {
+		return err
+	}
+
+	// The availability check happens under a row lock in the same
+	// transaction as the update, so concurrent exports cannot oversell
+	if err := s.stockRepo.ApplyMovement(ctx, movement, -change.Quantity); err != nil {
+		return err
 	}
 
-	// Update stock quantity
-	stock.Quantity -= quantity
-	return s.stockRepo.UpdateStock(stock, movement)
+	notifyStockChanged(ctx, s.observers, change.ProductID, change.LocationID)
+	return nil
 }
 
-func (s *StockService) GetStockMovements(ctx context.Context, startDate, endDate time.Time, productID *uint, category *uint) ([]models.MovementDTO, error) {
-	movements, err := s.stockRepo.GetMovements(ctx, startDate, endDate, productID, category)
+func (s *StockService) GetLots(ctx context.Context, productID uint) ([]models.Lot, error) {
+	return s.stockRepo.GetLots(ctx, productID)
+}
+
+func (s *StockService) GetSerialNumbers(ctx context.Context, productID uint, status *string) ([]models.SerialNumber, error) {
+	return s.stockRepo.GetSerialNumbers(ctx, productID, status)
+}
+
+// GetSerialHistory traces every movement of a single serial-tracked unit.
+func (s *StockService) GetSerialHistory(ctx context.Context, productID uint, serial string) (*models.SerialHistoryDTO, error) {
+	unit, movements, err := s.stockRepo.GetSerialHistory(ctx, productID, serial)
+	if err != nil {
+		return nil, errors.New("serial number not found")
+	}
+
+	history := &models.SerialHistoryDTO{SerialNumber: *unit, Movements: []models.MovementDTO{}}
+	for _, movement := range movements {
+		history.Movements = append(history.Movements, toMovementDTO(movement))
+	}
+	return history, nil
+}
+
+func (s *StockService) GetStockMovements(ctx context.Context, filter models.MovementFilter) ([]models.MovementDTO, error) {
+	if filter.Type != nil && !filter.Type.Valid() {
+		return nil, errors.New("invalid movement type " + string(*filter.Type))
+	}
+
+	movements, err := s.stockRepo.GetMovements(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
 	var dtos []models.MovementDTO
 	for _, movement := range movements {
-		dto := models.MovementDTO{
-			Type:     movement.Type,
-			Quantity: movement.Quantity,
-			Date:     movement.Date,
-			Notes:    movement.Notes,
+		dtos = append(dtos, toMovementDTO(movement))
+	}
+
+	return dtos, nil
+}
+
+// ReverseMovement undoes a mistaken movement by posting its compensating
+// movement. The original stays in the ledger untouched.
+func (s *StockService) ReverseMovement(ctx context.Context, id uint, userID uint, notes string) (*models.MovementDTO, error) {
+	reversal, err := s.stockRepo.ReverseMovement(ctx, id, userID, notes)
+	if err != nil {
+		return nil, err
+	}
+
+	notifyStockChanged(ctx, s.observers, reversal.ProductID, reversal.LocationID)
+	dto := toMovementDTO(*reversal)
+	return &dto, nil
+}
+
+func toMovementDTO(movement models.StockMovement) models.MovementDTO {
+	dto := models.MovementDTO{
+		ID:         movement.ID,
+		Type:       movement.Type,
+		Direction:  movement.Direction,
+		Quantity:   movement.Quantity,
+		Date:       movement.Date,
+		Notes:      movement.Notes,
+		UnitCost:   movement.UnitCost,
+		TotalCost:  movement.TotalCost,
+		ReversalOf: movement.ReversalOfID,
+	}
+	if movement.ReasonCode != nil {
+		dto.ReasonCode = movement.ReasonCode.Code
+	}
+	if movement.EnteredUnit != nil {
+		dto.Unit = movement.EnteredUnit.Code
+		dto.EnteredQuantity = movement.EnteredQuantity
+	}
+
+	// Map product info
+	if movement.Product.ID != 0 {
+		dto.Product.Name = movement.Product.Name
+		dto.Product.ImageURL = movement.Product.ImageURL
+		dto.Product.SKU = movement.Product.SKU
+	}
+
+	// Map location info
+	if movement.Location.ID != 0 {
+		dto.Location.ID = movement.Location.ID
+		dto.Location.Code = movement.Location.Code
+		dto.Location.Name = movement.Location.Name
+		if movement.Location.Warehouse != nil {
+			dto.Location.Warehouse = movement.Location.Warehouse.Name
 		}
+	}
 
-		// Map product info
-		if movement.Product.ID != 0 {
-			dto.Product.Name = movement.Product.Name
-			dto.Product.ImageURL = movement.Product.ImageURL
-			dto.Product.SKU = movement.Product.SKU
+	// Map lot info
+	for _, movementLot := range movement.Lots {
+		if movementLot.Lot == nil {
+			continue
 		}
+		dto.Lots = append(dto.Lots, models.MovementLotDTO{
+			LotNumber:  movementLot.Lot.LotNumber,
+			ExpiryDate: movementLot.Lot.ExpiryDate,
+			Quantity:   movementLot.Quantity,
+		})
+	}
 
-		// Map user info
-		if movement.User.ID != 0 {
-			dto.User.Username = movement.User.Username
+	// Map serial info
+	for _, movementSerial := range movement.Serials {
+		if movementSerial.SerialNumber != nil {
+			dto.Serials = append(dto.Serials, movementSerial.SerialNumber.Serial)
 		}
+	}
 
-		dtos = append(dtos, dto)
+	// Map user info
+	if movement.User.ID != 0 {
+		dto.User.Username = movement.User.Username
 	}
 
-	return dtos, nil
+	return dto
+}
+
+func (s *StockService) GetCurrentStock(ctx context.Context) ([]models.Stock, error) {
+	return s.stockRepo.GetCurrentStock()
+}
+
+// GetStockSummary rolls stock up per product with a per-location breakdown.
+// When locationID is set only that location is reported.
+func (s *StockService) GetStockSummary(ctx context.Context, locationID *uint) ([]models.StockSummaryDTO, error) {
+	stocks, err := s.stockRepo.GetStockSummary(locationID)
+	if err != nil {
+		return nil, err
+	}
+	reservedRows, err := s.stockRepo.GetReservedQuantities(ctx, locationID)
+	if err != nil {
+		return nil, err
+	}
+	reserved := make(map[[2]uint]models.Quantity, len(reservedRows))
+	for _, row := range reservedRows {
+		reserved[[2]uint{row.ProductID, row.LocationID}] = row.Quantity
+	}
+
+	summaries := []models.StockSummaryDTO{}
+	index := make(map[uint]int)
+	for _, stock := range stocks {
+		i, ok := index[stock.ProductID]
+		if !ok {
+			summaries = append(summaries, models.StockSummaryDTO{
+				ProductID: stock.ProductID,
+				Name:      stock.Product.Name,
+				SKU:       stock.Product.SKU,
+				Category:  stock.Product.Category,
+				Locations: []models.LocationQuantityDTO{},
+			})
+			i = len(summaries) - 1
+			index[stock.ProductID] = i
+		}
+
+		locationReserved := reserved[[2]uint{stock.ProductID, stock.LocationID}]
+		locationQty := models.LocationQuantityDTO{
+			LocationID:   stock.LocationID,
+			LocationCode: stock.Location.Code,
+			WarehouseID:  stock.Location.WarehouseID,
+			Quantity:     stock.Quantity,
+			Reserved:     locationReserved,
+			Available:    stock.Quantity - locationReserved,
+		}
+		if stock.Location.Warehouse != nil {
+			locationQty.WarehouseName = stock.Location.Warehouse.Name
+		}
+
+		summaries[i].Quantity += stock.Quantity
+		summaries[i].Reserved += locationQty.Reserved
+		summaries[i].Available += locationQty.Available
+		summaries[i].Locations = append(summaries[i].Locations, locationQty)
+	}
+
+	return summaries, nil
+}
+
+// GetStockAsOf reconstructs the stock held at a past moment from the
+// movement ledger, per product with the per-location breakdown.
+func (s *StockService) GetStockAsOf(ctx context.Context, asOf time.Time, productID, locationID *uint) ([]models.StockAsOfDTO, error) {
+	if asOf.After(time.Now()) {
+		return nil, errors.New("date cannot be in the future")
+	}
+
+	rows, err := s.stockRepo.GetBalancesAsOf(ctx, asOf, productID, locationID)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := []models.StockAsOfDTO{}
+	index := make(map[uint]int)
+	for _, row := range rows {
+		i, ok := index[row.ProductID]
+		if !ok {
+			balances = append(balances, models.StockAsOfDTO{
+				ProductID: row.ProductID,
+				Name:      row.ProductName,
+				SKU:       row.SKU,
+				Locations: []models.LocationBalanceDTO{},
+			})
+			i = len(balances) - 1
+			index[row.ProductID] = i
+		}
+
+		balances[i].Quantity += row.Quantity
+		balances[i].Locations = append(balances[i].Locations, models.LocationBalanceDTO{
+			LocationID:    row.LocationID,
+			LocationCode:  row.LocationCode,
+			WarehouseID:   row.WarehouseID,
+			WarehouseName: row.WarehouseName,
+			Quantity:      row.Quantity,
+		})
+	}
+
+	return balances, nil
+}
+
+// resolveReasonCode checks that code is an active reason code usable on
+// movements of movementType and returns its ID. Types that require a reason
+// fail without one; for the others an empty code is fine.
+func resolveReasonCode(ctx context.Context, stockRepo repositories.StockRepository, movementType models.MovementType, code string) (*uint, error) {
+	if !movementType.Valid() {
+		return nil, errors.New("invalid movement type " + string(movementType))
+	}
+	if code == "" {
+		if movementType.RequiresReason() {
+			return nil, errors.New("a reason code is required for " + string(movementType) + " movements")
+		}
+		return nil, nil
+	}
+
+	reasonCode, err := stockRepo.GetReasonCodeByCode(ctx, code)
+	if err != nil {
+		return nil, errors.New("unknown reason code " + code)
+	}
+	if !reasonCode.Active {
+		return nil, errors.New("reason code " + code + " is no longer active")
+	}
+	if !reasonCode.AppliesTo(movementType) {
+		return nil, errors.New("reason code " + code + " cannot be used on " + string(movementType) + " movements")
+	}
+	return &reasonCode.ID, nil
+}
+
+func (s *StockService) GetReasonCodes(ctx context.Context, movementType *models.MovementType) ([]models.ReasonCode, error) {
+	return s.stockRepo.GetReasonCodes(ctx, movementType)
+}
+
+func (s *StockService) CreateReasonCode(ctx context.Context, reasonCode *models.ReasonCode) error {
+	if reasonCode.Code == "" {
+		return errors.New("reason code is required")
+	}
+	if reasonCode.MovementType != "" && !reasonCode.MovementType.Valid() {
+		return errors.New("invalid movement type " + string(reasonCode.MovementType))
+	}
+	reasonCode.Active = true
+	return s.stockRepo.CreateReasonCode(ctx, reasonCode)
+}
+
+// UpdateReasonCode changes a reason code. The code itself is kept, since
+// movements refer to it in reports; retire codes by deactivating them.
+func (s *StockService) UpdateReasonCode(ctx context.Context, reasonCode *models.ReasonCode) error {
+	if reasonCode.ID == 0 {
+		return errors.New("reason code ID is required")
+	}
+	if reasonCode.MovementType != "" && !reasonCode.MovementType.Valid() {
+		return errors.New("invalid movement type " + string(reasonCode.MovementType))
+	}
+
+	existingReasonCode, err := s.stockRepo.GetReasonCode(ctx, reasonCode.ID)
+	if err != nil {
+		return errors.New("reason code not found")
+	}
+
+	reasonCode.Code = existingReasonCode.Code
+	reasonCode.CreatedAt = existingReasonCode.CreatedAt
+	return s.stockRepo.UpdateReasonCode(ctx, reasonCode)
+}
+
+func (s *StockService) GetUnits(ctx context.Context) ([]models.UnitOfMeasure, error) {
+	return s.stockRepo.GetUnits(ctx)
+}
+
+func (s *StockService) CreateUnit(ctx context.Context, unit *models.UnitOfMeasure) error {
+	if unit.Code == "" {
+		return errors.New("unit code is required")
+	}
+	return s.stockRepo.CreateUnit(ctx, unit)
+}
+
+// UpdateUnit renames a unit. The code is kept, requests refer to units by it.
+func (s *StockService) UpdateUnit(ctx context.Context, unit *models.UnitOfMeasure) error {
+	if unit.ID == 0 {
+		return errors.New("unit ID is required")
+	}
+
+	existingUnit, err := s.stockRepo.GetUnit(ctx, unit.ID)
+	if err != nil {
+		return errors.New("unit not found")
+	}
+
+	unit.Code = existingUnit.Code
+	unit.CreatedAt = existingUnit.CreatedAt
+	return s.stockRepo.UpdateUnit(ctx, unit)
+}
+
+func (s *StockService) GetProductUnits(ctx context.Context, productID uint) ([]models.ProductUnit, error) {
+	if _, err := s.stockRepo.GetProduct(ctx, productID); err != nil {
+		return nil, errors.New("product not found")
+	}
+	return s.stockRepo.GetProductUnits(ctx, productID)
 }
 
-func (s *StockService) GetStockSummary(ctx context.Context) ([]models.Stock, error) {
-	return s.stockRepo.GetStockSummary()
+// SetProductUnit lets a product be handled in an alternate unit holding
+// factor base units. Stock is kept in the base unit, so the factor can be
+// changed at any time.
+func (s *StockService) SetProductUnit(ctx context.Context, productID uint, unitCode string, factor models.Quantity) (*models.ProductUnit, error) {
+	if factor <= 0 {
+		return nil, errors.New("factor must be greater than 0")
+	}
+	product, err := s.stockRepo.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, errors.New("product not found")
+	}
+	unit, err := s.stockRepo.GetUnitByCode(ctx, unitCode)
+	if err != nil {
+		return nil, errors.New("unknown unit " + unitCode)
+	}
+	if product.BaseUnitID != nil && *product.BaseUnitID == unit.ID {
+		return nil, errors.New("the base unit cannot also be an alternate unit")
+	}
+
+	productUnit := &models.ProductUnit{ProductID: productID, UnitID: unit.ID, Factor: factor}
+	if err := s.stockRepo.SaveProductUnit(ctx, productUnit); err != nil {
+		return nil, err
+	}
+	return s.stockRepo.GetProductUnit(ctx, productID, unit.ID)
+}
+
+func (s *StockService) DeleteProductUnit(ctx context.Context, productID, unitID uint) error {
+	return s.stockRepo.DeleteProductUnit(ctx, productID, unitID)
 }
 
 func (s *StockService) GetCategories(ctx context.Context) ([]models.Category, error) {
@@ -213,29 +907,154 @@ func (s *StockService) GetProducts(ctx context.Context) ([]models.ProductDTO, er
 	if err != nil {
 		return nil, err
 	}
+	reservedRows, err := s.stockRepo.GetReservedQuantities(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	reserved := make(map[uint]models.Quantity)
+	for _, row := range reservedRows {
+		reserved[row.ProductID] += row.Quantity
+	}
+	quantities, err := s.stockRepo.GetProductQuantities(ctx)
+	if err != nil {
+		return nil, err
+	}
 
+	// Variants are listed under their parent, whose quantities add up
+	// those of its variants
 	var productDTOs []models.ProductDTO
+	variants := make(map[uint][]models.ProductDTO)
 	for _, product := range products {
-		stock, _ := s.stockRepo.GetStock(ctx, product.ID)
-		quantity := 0
-		if stock != nil {
-			quantity = stock.Quantity
-		}
-
-		productDTO := models.ProductDTO{
-			ID:          product.ID,
-			Name:        product.Name,
-			ImageURL:    product.ImageURL,
-			Description: product.Description,
-			CategoryID:  product.CategoryID,
-			Category:    product.Category,
-			SKU:         product.SKU,
-			CreatedAt:   product.CreatedAt,
-			UpdatedAt:   product.UpdatedAt,
-			Quantity:    quantity,
+		productDTO := toProductDTO(product, quantities[product.ID], reserved[product.ID])
+		if product.ParentID != nil {
+			variants[*product.ParentID] = append(variants[*product.ParentID], productDTO)
+			continue
 		}
 		productDTOs = append(productDTOs, productDTO)
 	}
+	for i := range productDTOs {
+		parent := &productDTOs[i]
+		for _, variant := range variants[parent.ID] {
+			parent.Quantity += variant.Quantity
+			parent.Reserved += variant.Reserved
+			parent.Available += variant.Available
+			parent.Variants = append(parent.Variants, variant)
+		}
+	}
 
 	return productDTOs, nil
 }
+
+func toProductDTO(product models.Product, quantity, reserved models.Quantity) models.ProductDTO {
+	productDTO := models.ProductDTO{
+		ID:               product.ID,
+		Name:             product.Name,
+		ImageURL:         product.ImageURL,
+		Description:      product.Description,
+		CategoryID:       product.CategoryID,
+		Category:         product.Category,
+		SKU:              product.SKU,
+		TrackingMode:     product.TrackingMode,
+		QuantityDecimals: product.QuantityDecimals,
+		CreatedAt:        product.CreatedAt,
+		UpdatedAt:        product.UpdatedAt,
+		Quantity:         quantity,
+		Reserved:         reserved,
+		Available:        quantity - reserved,
+		ParentID:         product.ParentID,
+	}
+	if len(product.VariantAttributes) > 0 {
+		productDTO.Attributes = make(map[string]string, len(product.VariantAttributes))
+		for _, variantAttribute := range product.VariantAttributes {
+			if variantAttribute.Attribute != nil && variantAttribute.Value != nil {
+				productDTO.Attributes[variantAttribute.Attribute.Name] = variantAttribute.Value.Value
+			}
+		}
+	}
+	return productDTO
+}
+
+func (s *StockService) GetWarehouses(ctx context.Context) ([]models.Warehouse, error) {
+	return s.stockRepo.GetWarehouses(ctx)
+}
+
+func (s *StockService) CreateWarehouse(ctx context.Context, warehouse *models.Warehouse) error {
+	if warehouse.Code == "" {
+		return errors.New("warehouse code is required")
+	}
+	if warehouse.Name == "" {
+		return errors.New("warehouse name is required")
+	}
+	return s.stockRepo.CreateWarehouse(ctx, warehouse)
+}
+
+func (s *StockService) UpdateWarehouse(ctx context.Context, warehouse *models.Warehouse) error {
+	if warehouse.ID == 0 {
+		return errors.New("warehouse ID is required")
+	}
+	if warehouse.Code == "" {
+		return errors.New("warehouse code is required")
+	}
+	if warehouse.Name == "" {
+		return errors.New("warehouse name is required")
+	}
+
+	// Verify warehouse exists
+	if _, err := s.stockRepo.GetWarehouse(ctx, warehouse.ID); err != nil {
+		return errors.New("warehouse not found")
+	}
+
+	return s.stockRepo.UpdateWarehouse(ctx, warehouse)
+}
+
+func (s *StockService) DeleteWarehouse(ctx context.Context, id uint) error {
+	// Verify warehouse exists
+	if _, err := s.stockRepo.GetWarehouse(ctx, id); err != nil {
+		return errors.New("warehouse not found")
+	}
+
+	return s.stockRepo.DeleteWarehouse(ctx, id)
+}
+
+func (s *StockService) GetLocations(ctx context.Context, warehouseID *uint) ([]models.Location, error) {
+	return s.stockRepo.GetLocations(ctx, warehouseID)
+}
+
+func (s *StockService) CreateLocation(ctx context.Context, location *models.Location) error {
+	if location.Code == "" {
+		return errors.New("location code is required")
+	}
+	if location.WarehouseID == 0 {
+		return errors.New("warehouse is required")
+	}
+	if _, err := s.stockRepo.GetWarehouse(ctx, location.WarehouseID); err != nil {
+		return errors.New("warehouse not found")
+	}
+	return s.stockRepo.CreateLocation(ctx, location)
+}
+
+func (s *StockService) UpdateLocation(ctx context.Context, location *models.Location) error {
+	if location.ID == 0 {
+		return errors.New("location ID is required")
+	}
+	if location.Code == "" {
+		return errors.New("location code is required")
+	}
+
+	existingLocation, err := s.stockRepo.GetLocation(ctx, location.ID)
+	if err != nil {
+		return errors.New("location not found")
+	}
+
+	// Locations cannot be moved between warehouses, their stock would move with them
+	location.WarehouseID = existingLocation.WarehouseID
+	return s.stockRepo.UpdateLocation(ctx, location)
+}
+
+func (s *StockService) DeleteLocation(ctx context.Context, id uint) error {
+	if _, err := s.stockRepo.GetLocation(ctx, id); err != nil {
+		return errors.New("location not found")
+	}
+
+	return s.stockRepo.DeleteLocation(ctx, id)
+}
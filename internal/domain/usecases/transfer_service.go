@@ -0,0 +1,123 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+)
+
+type TransferService struct {
+	transferRepo repositories.TransferRepository
+	stockRepo    repositories.StockRepository
+	observers    []StockObserver
+}
+
+func NewTransferService(transferRepo repositories.TransferRepository, stockRepo repositories.StockRepository, observers ...StockObserver) *TransferService {
+	return &TransferService{
+		transferRepo: transferRepo,
+		stockRepo:    stockRepo,
+		observers:    observers,
+	}
+}
+
+// CreateTransfer dispatches goods from the source location. The goods stay in
+// transit until ReceiveTransfer or CancelTransfer books them somewhere.
+func (s *TransferService) CreateTransfer(ctx context.Context, transfer *models.StockTransfer, userID uint) error {
+	if transfer.SourceLocationID == 0 || transfer.DestinationLocationID == 0 {
+		return errors.New("source and destination locations are required")
+	}
+	if transfer.SourceLocationID == transfer.DestinationLocationID {
+		return errors.New("source and destination locations must differ")
+	}
+	if len(transfer.Lines) == 0 {
+		return errors.New("transfer needs at least one line")
+	}
+
+	if _, err := s.stockRepo.GetLocation(ctx, transfer.SourceLocationID); err != nil {
+		return errors.New("source location not found")
+	}
+	if _, err := s.stockRepo.GetLocation(ctx, transfer.DestinationLocationID); err != nil {
+		return errors.New("destination location not found")
+	}
+
+	seen := make(map[uint]bool)
+	for _, line := range transfer.Lines {
+		if line.Quantity <= 0 {
+			return errors.New("quantity must be greater than 0")
+		}
+		if len(line.Serials) > 0 && models.Units(len(line.Serials)) != line.Quantity {
+			return errors.New("number of serial numbers must match the quantity")
+		}
+		if seen[line.ProductID] {
+			return errors.New("each product may appear only once per transfer")
+		}
+		seen[line.ProductID] = true
+
+		product, err := s.stockRepo.GetProduct(ctx, line.ProductID)
+		if err != nil {
+			return fmt.Errorf("product %d not found", line.ProductID)
+		}
+		if err := checkPrecision(product, line.Quantity); err != nil {
+			return err
+		}
+	}
+
+	transfer.CreatedByID = userID
+	if err := s.transferRepo.CreateTransfer(ctx, transfer); err != nil {
+		return err
+	}
+
+	for _, line := range transfer.Lines {
+		notifyStockChanged(ctx, s.observers, line.ProductID, transfer.SourceLocationID)
+	}
+	return nil
+}
+
+func (s *TransferService) GetTransfer(ctx context.Context, id uint) (*models.StockTransfer, error) {
+	transfer, err := s.transferRepo.GetTransfer(ctx, id)
+	if err != nil {
+		return nil, errors.New("transfer not found")
+	}
+	return transfer, nil
+}
+
+func (s *TransferService) GetTransfers(ctx context.Context, status *string) ([]models.StockTransfer, error) {
+	return s.transferRepo.GetTransfers(ctx, status)
+}
+
+// ReceiveTransfer books quantities per transfer line into the destination.
+// Passing no receipts receives everything still in transit.
+func (s *TransferService) ReceiveTransfer(ctx context.Context, id uint, receipts map[uint]models.TransferReceipt, userID uint) (*models.StockTransfer, error) {
+	for _, receipt := range receipts {
+		if receipt.Quantity <= 0 {
+			return nil, errors.New("quantity must be greater than 0")
+		}
+		if len(receipt.Serials) > 0 && models.Units(len(receipt.Serials)) != receipt.Quantity {
+			return nil, errors.New("number of serial numbers must match the quantity")
+		}
+	}
+	transfer, err := s.transferRepo.ReceiveTransfer(ctx, id, receipts, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range transfer.Lines {
+		notifyStockChanged(ctx, s.observers, line.ProductID, transfer.DestinationLocationID)
+	}
+	return transfer, nil
+}
+
+// CancelTransfer returns whatever is still in transit to the source location.
+func (s *TransferService) CancelTransfer(ctx context.Context, id uint, userID uint) (*models.StockTransfer, error) {
+	transfer, err := s.transferRepo.CancelTransfer(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range transfer.Lines {
+		notifyStockChanged(ctx, s.observers, line.ProductID, transfer.SourceLocationID)
+	}
+	return transfer, nil
+}
@@ -0,0 +1,103 @@
+package usecases
+
+import (
+	"context"
+	"sort"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"time"
+)
+
+type ValuationService struct {
+	valuationRepo repositories.ValuationRepository
+}
+
+func NewValuationService(valuationRepo repositories.ValuationRepository) *ValuationService {
+	return &ValuationService{valuationRepo: valuationRepo}
+}
+
+// GetValuation values the stock on hand at the cost of its open cost layers
+// and totals it by product, category and location.
+func (s *ValuationService) GetValuation(ctx context.Context, filter models.ValuationFilter) (*models.ValuationReport, error) {
+	rows, err := s.valuationRepo.GetValuationRows(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.ValuationReport{
+		Products:    []models.ProductValuation{},
+		Categories:  []models.CategoryValuation{},
+		Locations:   []models.LocationValuation{},
+		GeneratedAt: time.Now(),
+	}
+	products := make(map[uint]*models.ProductValuation)
+	categories := make(map[uint]*models.CategoryValuation)
+	locations := make(map[uint]*models.LocationValuation)
+
+	for _, row := range rows {
+		report.TotalQuantity += row.Quantity
+		report.TotalValue += row.Value
+
+		product, ok := products[row.ProductID]
+		if !ok {
+			product = &models.ProductValuation{
+				ProductID:     row.ProductID,
+				SKU:           row.SKU,
+				Name:          row.ProductName,
+				CategoryID:    row.CategoryID,
+				CostingMethod: row.CostingMethod,
+			}
+			products[row.ProductID] = product
+		}
+		product.Quantity += row.Quantity
+		product.Value += row.Value
+
+		category, ok := categories[row.CategoryID]
+		if !ok {
+			category = &models.CategoryValuation{CategoryID: row.CategoryID, Name: row.CategoryName}
+			categories[row.CategoryID] = category
+		}
+		category.Quantity += row.Quantity
+		category.Value += row.Value
+
+		location, ok := locations[row.LocationID]
+		if !ok {
+			location = &models.LocationValuation{
+				LocationID: row.LocationID,
+				Code:       row.LocationCode,
+				Name:       row.LocationName,
+				Warehouse:  row.WarehouseName,
+			}
+			locations[row.LocationID] = location
+		}
+		location.Quantity += row.Quantity
+		location.Value += row.Value
+	}
+
+	for _, product := range products {
+		if product.Quantity > 0 {
+			unitCost, err := product.Value.DivQuantity(product.Quantity)
+			if err != nil {
+				return nil, err
+			}
+			product.UnitCost = unitCost
+		}
+		report.Products = append(report.Products, *product)
+	}
+	for _, category := range categories {
+		report.Categories = append(report.Categories, *category)
+	}
+	for _, location := range locations {
+		report.Locations = append(report.Locations, *location)
+	}
+
+	sort.Slice(report.Products, func(i, j int) bool { return report.Products[i].SKU < report.Products[j].SKU })
+	sort.Slice(report.Categories, func(i, j int) bool { return report.Categories[i].Name < report.Categories[j].Name })
+	sort.Slice(report.Locations, func(i, j int) bool {
+		if report.Locations[i].Warehouse != report.Locations[j].Warehouse {
+			return report.Locations[i].Warehouse < report.Locations[j].Warehouse
+		}
+		return report.Locations[i].Code < report.Locations[j].Code
+	})
+	return report, nil
+}
@@ -0,0 +1,127 @@
+package usecases
+
+import (
+	"context"
+	"errors"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"strings"
+)
+
+type VariantService struct {
+	variantRepo repositories.VariantRepository
+	stockRepo   repositories.StockRepository
+}
+
+func NewVariantService(variantRepo repositories.VariantRepository, stockRepo repositories.StockRepository) *VariantService {
+	return &VariantService{variantRepo: variantRepo, stockRepo: stockRepo}
+}
+
+// CreateAttribute adds an attribute such as size or colour to a parent
+// product. Values without a code use the value itself, upper-cased and
+// without spaces, in variant SKUs.
+func (s *VariantService) CreateAttribute(ctx context.Context, attribute *models.ProductAttribute) error {
+	attribute.Name = strings.TrimSpace(attribute.Name)
+	if attribute.Name == "" {
+		return errors.New("attribute name is required")
+	}
+	if len(attribute.Values) == 0 {
+		return errors.New("attribute needs at least one value")
+	}
+
+	seen := make(map[string]bool, len(attribute.Values))
+	for i := range attribute.Values {
+		value := &attribute.Values[i]
+		if err := normalizeAttributeValue(value); err != nil {
+			return err
+		}
+		if seen[value.Value] {
+			return errors.New("value " + value.Value + " is listed twice")
+		}
+		seen[value.Value] = true
+		if value.Position == 0 {
+			value.Position = i + 1
+		}
+	}
+	return s.variantRepo.CreateAttribute(ctx, attribute)
+}
+
+func (s *VariantService) AddAttributeValue(ctx context.Context, productID uint, value *models.ProductAttributeValue) error {
+	if err := normalizeAttributeValue(value); err != nil {
+		return err
+	}
+	return s.variantRepo.AddAttributeValue(ctx, productID, value)
+}
+
+func normalizeAttributeValue(value *models.ProductAttributeValue) error {
+	value.Value = strings.TrimSpace(value.Value)
+	if value.Value == "" {
+		return errors.New("attribute value is required")
+	}
+	value.Code = strings.ToUpper(strings.TrimSpace(value.Code))
+	if value.Code == "" {
+		value.Code = strings.ToUpper(strings.ReplaceAll(value.Value, " ", ""))
+	}
+	return nil
+}
+
+func (s *VariantService) GetAttributes(ctx context.Context, productID uint) ([]models.ProductAttribute, error) {
+	if _, err := s.stockRepo.GetProduct(ctx, productID); err != nil {
+		return nil, errors.New("product not found")
+	}
+	return s.variantRepo.GetAttributes(ctx, productID)
+}
+
+// GenerateVariants creates the variants of a parent product that are still
+// missing from its attribute matrix and returns the new ones.
+func (s *VariantService) GenerateVariants(ctx context.Context, productID uint) ([]models.ProductDTO, error) {
+	created, err := s.variantRepo.GenerateVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	isNew := make(map[uint]bool, len(created))
+	for _, variant := range created {
+		isNew[variant.ID] = true
+	}
+
+	variants, err := s.GetVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	newVariants := []models.ProductDTO{}
+	for _, variant := range variants {
+		if isNew[variant.ID] {
+			newVariants = append(newVariants, variant)
+		}
+	}
+	return newVariants, nil
+}
+
+// GetVariants returns the variants of a parent product with their stock.
+func (s *VariantService) GetVariants(ctx context.Context, productID uint) ([]models.ProductDTO, error) {
+	if _, err := s.stockRepo.GetProduct(ctx, productID); err != nil {
+		return nil, errors.New("product not found")
+	}
+	variants, err := s.variantRepo.GetVariants(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	reservedRows, err := s.stockRepo.GetReservedQuantities(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	reserved := make(map[uint]models.Quantity)
+	for _, row := range reservedRows {
+		reserved[row.ProductID] += row.Quantity
+	}
+
+	variantDTOs := []models.ProductDTO{}
+	for _, variant := range variants {
+		quantity, err := s.stockRepo.GetProductQuantity(ctx, variant.ID)
+		if err != nil {
+			return nil, err
+		}
+		variantDTOs = append(variantDTOs, toProductDTO(variant, quantity, reserved[variant.ID]))
+	}
+	return variantDTOs, nil
+}
@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"os"
 	"stock-management/internal/domain/models"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func NewDatabase() (*gorm.DB, error) {
@@ -29,16 +31,283 @@ func NewDatabase() (*gorm.DB, error) {
 		return nil, err
 	}
 
+	if err := Migrate(db); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrate brings the schema up to date and runs the data migrations.
+func Migrate(db *gorm.DB) error {
+	// Duplicates have to go before the unique index can be created
+	if err := dedupeReorderRules(db); err != nil {
+		return err
+	}
+	// SKUs are unique among live products only; the old index also covered
+	// archived ones
+	if db.Migrator().HasIndex(&models.Product{}, "idx_products_sku") {
+		if err := db.Migrator().DropIndex(&models.Product{}, "idx_products_sku"); err != nil {
+			return err
+		}
+	}
+
 	// Auto migrate the schema
-	err = db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.User{},
+		&models.RefreshToken{},
+		&models.UnitOfMeasure{},
 		&models.Product{},
+		&models.ProductUnit{},
+		&models.Barcode{},
+		&models.ProductAttribute{},
+		&models.ProductAttributeValue{},
+		&models.VariantAttribute{},
+		&models.Warehouse{},
+		&models.Location{},
 		&models.Stock{},
+		&models.Lot{},
+		&models.LotStock{},
+		&models.ReasonCode{},
 		&models.StockMovement{},
+		&models.StockMovementLot{},
+		&models.SerialNumber{},
+		&models.StockMovementSerial{},
+		&models.StockTransfer{},
+		&models.StockTransferLine{},
+		&models.IdempotencyKey{},
+		&models.Reservation{},
+		&models.ReorderRule{},
+		&models.StockAlert{},
+		&models.Supplier{},
+		&models.PurchaseOrder{},
+		&models.PurchaseOrderLine{},
+		&models.SalesOrder{},
+		&models.SalesOrderLine{},
+		&models.ReturnAuthorization{},
+		&models.ReturnLine{},
+		&models.CountSession{},
+		&models.CountLine{},
+		&models.CountEntry{},
+		&models.AdjustmentRequest{},
+		&models.AdjustmentSerial{},
+		&models.CostLayer{},
+		&models.StockSnapshot{},
+		&models.ClosedPeriod{},
+		&models.KitComponent{},
+		&models.KitBuild{},
+		&dataMigration{},
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return db, nil
+	if err := seedDefaultLocation(db); err != nil {
+		return err
+	}
+
+	if err := migrateMovementTypes(db); err != nil {
+		return err
+	}
+	if err := runOnce(db, "seed_opening_cost_layers", seedOpeningCostLayers); err != nil {
+		return err
+	}
+	if err := runOnce(db, "canonical_upc_barcodes", canonicalUPCBarcodes); err != nil {
+		return err
+	}
+	return seedDefaultUnit(db)
+}
+
+// seedDefaultLocation makes sure at least one warehouse location exists and
+// moves stock recorded before locations existed into it. Products without
+// any stock record get an empty one there.
+func seedDefaultLocation(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var location models.Location
+		err := tx.Order("id").First(&location).Error
+		if err == gorm.ErrRecordNotFound {
+			warehouse := &models.Warehouse{Code: "MAIN", Name: "Main Warehouse"}
+			if err := tx.Create(warehouse).Error; err != nil {
+				return err
+			}
+			location = models.Location{WarehouseID: warehouse.ID, Code: "DEFAULT", Name: "Default location"}
+			if err := tx.Create(&location).Error; err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Stock{}).
+			Where("location_id IS NULL OR location_id = 0").
+			Update("location_id", location.ID).Error; err != nil {
+			return err
+		}
+
+		// Products created without a stock record get an empty one, so
+		// they show up in stock reports
+		err = tx.Exec(`INSERT INTO stocks (product_id, location_id, quantity, created_at, updated_at)
+			SELECT products.id, ?, 0, NOW(), NOW() FROM products
+			WHERE products.deleted_at IS NULL
+			AND NOT EXISTS (SELECT 1 FROM stocks WHERE stocks.product_id = products.id)`,
+			location.ID).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&models.StockMovement{}).
+			Where("location_id IS NULL OR location_id = 0").
+			Update("location_id", location.ID).Error
+	})
+}
+
+// dedupeReorderRules keeps the oldest rule of each product and location.
+// Open alerts of the duplicates are dropped; their history moves over to
+// the rule that is kept.
+func dedupeReorderRules(db *gorm.DB) error {
+	if !db.Migrator().HasTable(&models.ReorderRule{}) {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		var duplicates []struct {
+			ID     uint
+			KeepID uint
+		}
+		err := tx.Raw(`SELECT id, keep_id FROM (
+				SELECT id, MIN(id) OVER (PARTITION BY product_id, location_id) AS keep_id FROM reorder_rules
+			) AS rules WHERE id <> keep_id`).
+			Scan(&duplicates).Error
+		if err != nil {
+			return err
+		}
+
+		for _, duplicate := range duplicates {
+			err := tx.Where("rule_id = ? AND status = ?", duplicate.ID, models.AlertStatusOpen).
+				Delete(&models.StockAlert{}).Error
+			if err != nil {
+				return err
+			}
+			err = tx.Model(&models.StockAlert{}).Where("rule_id = ?", duplicate.ID).
+				Update("rule_id", duplicate.KeepID).Error
+			if err != nil {
+				return err
+			}
+			if err := tx.Delete(&models.ReorderRule{}, duplicate.ID).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// migrateMovementTypes rewrites movements recorded with the old free-form
+// types onto the enumerated movement types. The old types carried the
+// direction, so it is set first.
+func migrateMovementTypes(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.StockMovement{}).
+			Where("type IN ?", []string{"export", "transfer_out"}).
+			Update("direction", models.MovementOut).Error
+		if err != nil {
+			return err
+		}
+
+		renames := map[string]models.MovementType{
+			"import":       models.MovementReceipt,
+			"export":       models.MovementIssue,
+			"transfer_in":  models.MovementTransfer,
+			"transfer_out": models.MovementTransfer,
+		}
+		for from, to := range renames {
+			if err := tx.Model(&models.StockMovement{}).Where("type = ?", from).Update("type", to).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// dataMigration records a one-off data migration that has been applied.
+type dataMigration struct {
+	Name      string    `gorm:"primaryKey"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// runOnce applies a data migration unless it was applied before. The record
+// is written in the migration's transaction, so a failed migration is tried
+// again on the next start and concurrent starts apply it only once.
+func runOnce(db *gorm.DB, name string, migrate func(tx *gorm.DB) error) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&dataMigration{Name: name, AppliedAt: time.Now()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		return migrate(tx)
+	})
+}
+
+// seedOpeningCostLayers gives stock that was booked before costs were
+// tracked an opening cost layer, valued at the product's last purchase cost.
+// Stock that already has layers is left alone.
+func seedOpeningCostLayers(tx *gorm.DB) error {
+	var stocks []models.Stock
+	err := tx.Where("quantity > 0").
+		Where("NOT EXISTS (SELECT 1 FROM cost_layers WHERE cost_layers.product_id = stocks.product_id AND cost_layers.location_id = stocks.location_id)").
+		Find(&stocks).Error
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, stock := range stocks {
+		var line models.PurchaseOrderLine
+		var unitCost models.Money
+		err := tx.Where("product_id = ? AND received_quantity > 0", stock.ProductID).Order("id DESC").First(&line).Error
+		if err == nil {
+			unitCost = line.UnitCost
+		} else if err != gorm.ErrRecordNotFound {
+			return err
+		}
+
+		layer := &models.CostLayer{
+			ProductID:  stock.ProductID,
+			LocationID: stock.LocationID,
+			ReceivedAt: now,
+			Quantity:   stock.Quantity,
+			Remaining:  stock.Quantity,
+			UnitCost:   unitCost,
+		}
+		if err := tx.Create(layer).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canonicalUPCBarcodes stores UPC-A barcodes entered before GTINs were
+// normalised in their 13-digit form. A code whose 13-digit form is already
+// stored is left as it was.
+func canonicalUPCBarcodes(tx *gorm.DB) error {
+	return tx.Exec(`UPDATE barcodes SET code = '0' || code
+		WHERE symbology = ? AND LENGTH(code) = 12
+		AND NOT EXISTS (SELECT 1 FROM barcodes AS other WHERE other.code = '0' || barcodes.code)`,
+		models.SymbologyUPC).Error
+}
+
+// seedDefaultUnit makes sure the default unit exists and counts products
+// created before units of measure in it.
+func seedDefaultUnit(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		unit := models.UnitOfMeasure{Code: models.DefaultUnitCode, Name: "Each"}
+		if err := tx.Where("code = ?", unit.Code).FirstOrCreate(&unit).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&models.Product{}).
+			Where("base_unit_id IS NULL").
+			Update("base_unit_id", unit.ID).Error
+	})
 }
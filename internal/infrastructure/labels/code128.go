@@ -0,0 +1,97 @@
+package labels
+
+import (
+	"errors"
+	"strings"
+)
+
+// code128Patterns holds the bar and space widths of each Code 128 symbol,
+// starting with a bar. Every symbol is 11 modules wide except the stop
+// symbol, which has a final 2-module bar.
+var code128Patterns = [...]string{
+	"212222", "222122", "222221", "121223", "121322", "131222", "122213", "122312", "132212", "221213",
+	"221312", "231212", "112232", "122132", "122231", "113222", "123122", "123221", "223211", "221132",
+	"221231", "213212", "223112", "312131", "311222", "321122", "321221", "312212", "322112", "322211",
+	"212123", "212321", "232121", "111323", "131123", "131321", "112313", "132113", "132311", "211313",
+	"231113", "231311", "112133", "112331", "132131", "113123", "113321", "133121", "313121", "211331",
+	"231131", "213113", "213311", "213131", "311123", "311321", "331121", "312113", "312311", "332111",
+	"314111", "221411", "431111", "111224", "111422", "121124", "121421", "141122", "141221", "112214",
+	"112412", "122114", "122411", "142112", "142211", "241211", "221114", "413111", "241112", "134111",
+	"111242", "121142", "121241", "114212", "124112", "124211", "411212", "421112", "421211", "212141",
+	"214121", "412121", "111143", "111341", "131141", "114113", "114311", "411113", "411311", "113141",
+	"114131", "311141", "411131", "211412", "211214", "211232", "2331112",
+}
+
+const (
+	code128CodeC  = 99
+	code128CodeB  = 100
+	code128StartB = 104
+	code128StartC = 105
+	code128Stop   = 106
+)
+
+// encodeCode128 returns the modules of a Code 128 symbol for text, true for
+// a bar, without quiet zones. Runs of four or more digits are packed in code
+// set C, everything else uses code set B.
+func encodeCode128(text string) ([]bool, error) {
+	if text == "" {
+		return nil, errors.New("nothing to encode")
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] < ' ' || text[i] > '~' {
+			return nil, errors.New("code 128 labels can only hold printable ASCII")
+		}
+	}
+
+	var symbols []int
+	codeSet := 0
+	for i := 0; i < len(text); {
+		digits := len(text[i:]) - len(strings.TrimLeft(text[i:], "0123456789"))
+		if digits >= 4 || (codeSet == code128CodeC && digits >= 2) {
+			// An odd run leaves its first digit to code set B
+			if digits%2 == 1 && codeSet != code128CodeC {
+				symbols, codeSet = switchCode128(symbols, codeSet, code128CodeB)
+				symbols = append(symbols, int(text[i]-' '))
+				i++
+				digits--
+			}
+			symbols, codeSet = switchCode128(symbols, codeSet, code128CodeC)
+			for end := i + digits - digits%2; i < end; i += 2 {
+				symbols = append(symbols, int(text[i]-'0')*10+int(text[i+1]-'0'))
+			}
+			continue
+		}
+		symbols, codeSet = switchCode128(symbols, codeSet, code128CodeB)
+		symbols = append(symbols, int(text[i]-' '))
+		i++
+	}
+
+	checksum := symbols[0]
+	for i, symbol := range symbols[1:] {
+		checksum += (i + 1) * symbol
+	}
+	symbols = append(symbols, checksum%103, code128Stop)
+
+	var modules []bool
+	for _, symbol := range symbols {
+		for i, width := range code128Patterns[symbol] {
+			for n := 0; n < int(width-'0'); n++ {
+				modules = append(modules, i%2 == 0)
+			}
+		}
+	}
+	return modules, nil
+}
+
+// switchCode128 starts the symbol in, or switches it to, the given code set.
+func switchCode128(symbols []int, current, codeSet int) ([]int, int) {
+	switch {
+	case current == codeSet:
+		return symbols, codeSet
+	case current == 0 && codeSet == code128CodeB:
+		return append(symbols, code128StartB), codeSet
+	case current == 0 && codeSet == code128CodeC:
+		return append(symbols, code128StartC), codeSet
+	}
+	return append(symbols, codeSet), codeSet
+}
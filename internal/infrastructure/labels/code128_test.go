@@ -0,0 +1,68 @@
+package labels
+
+import (
+	"strconv"
+	"testing"
+)
+
+// decodeCode128 reads the symbol values back from the modules by their bar
+// and space widths.
+func decodeCode128(t *testing.T, modules []bool) []int {
+	t.Helper()
+	var symbols []int
+	for start := 0; start < len(modules); {
+		var widths string
+		end := start
+		for len(widths) < 6 || (len(symbols) > 0 && len(modules)-start == 13 && len(widths) < 7) {
+			run := end
+			for run < len(modules) && modules[run] == modules[end] {
+				run++
+			}
+			widths += strconv.Itoa(run - end)
+			end = run
+		}
+		symbol := -1
+		for value, pattern := range code128Patterns {
+			if pattern == widths {
+				symbol = value
+			}
+		}
+		if symbol < 0 {
+			t.Fatalf("no symbol has the widths %s", widths)
+		}
+		symbols = append(symbols, symbol)
+		start = end
+	}
+	return symbols
+}
+
+func TestCode128CheckSymbol(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int
+	}{
+		// Start B, P J J 1 2 3 C: 104 + 1*48 + 2*42 + 3*42 + 4*17 + 5*18 + 6*19 + 7*35 = 879 = 8*103 + 55
+		{"PJJ123C", []int{104, 48, 42, 42, 17, 18, 19, 35, 55, 106}},
+		// Start C, 12 34 56 78: 105 + 1*12 + 2*34 + 3*56 + 4*78 = 665 = 6*103 + 47
+		{"12345678", []int{105, 12, 34, 56, 78, 47, 106}},
+		// Start B, A, code C, 12 34: 104 + 1*33 + 2*99 + 3*12 + 4*34 = 507 = 4*103 + 95
+		{"A1234", []int{104, 33, 99, 12, 34, 95, 106}},
+	}
+	for _, tt := range tests {
+		modules, err := encodeCode128(tt.text)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.text, err)
+		}
+		got := decodeCode128(t, modules)
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: symbols %v, want %v", tt.text, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("%s: symbols %v, want %v", tt.text, got, tt.want)
+				break
+			}
+		}
+	}
+}
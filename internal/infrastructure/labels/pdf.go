@@ -0,0 +1,85 @@
+package labels
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// pdfPage collects the drawing operators of one PDF page. The page works
+// in dots from the top left like the raster sheets; the transformation to
+// PDF points is applied when the document is written.
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+func (p *pdfPage) fillRect(x, y, width, height int) {
+	fmt.Fprintf(&p.content, "%d %d %d %d re f\n", x, y, width, height)
+}
+
+// text uses the standard Helvetica font. The y axis of the page points
+// down, so the text matrix flips the glyphs back upright.
+func (p *pdfPage) text(x, y, size int, s string) {
+	baseline := y + size*3/4
+	fmt.Fprintf(&p.content, "BT /F1 %d Tf 1 0 0 -1 %d %d Tm (%s) Tj ET\n", size, x, baseline, pdfString(s))
+}
+
+// pdfString escapes text for a PDF string literal. Only printable ASCII is
+// kept, which reads the same in the font's WinAnsi encoding.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < ' ' || r > '~':
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// pdfDocument writes pages as a minimal PDF 1.4 file.
+type pdfDocument struct {
+	pages []*pdfPage
+}
+
+func (d *pdfDocument) bytes() []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	// Objects 1 to 3 are the catalog, the page tree and the font; each page
+	// is followed by its content stream
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+2*i)
+	}
+	// A4 in points; a dot is 72/300 of a point
+	width, height := float64(sheetWidth)*0.24, float64(sheetHeight)*0.24
+
+	buf.WriteString("%PDF-1.4\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			width, height, 5+2*i))
+		content := fmt.Sprintf("0.24 0 0 -0.24 0 %.2f cm\n%s", height, strings.TrimSuffix(page.content.String(), "\n"))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
@@ -0,0 +1,96 @@
+package labels
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestQRErrorCorrection checks the Reed-Solomon codewords against the
+// version 1-M example of ISO/IEC 18004 Annex I, which encodes "01234567".
+func TestQRErrorCorrection(t *testing.T) {
+	data := []byte{0x10, 0x20, 0x0C, 0x56, 0x61, 0x80, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11, 0xEC, 0x11}
+	want := append(append([]byte{}, data...), 0xA5, 0x24, 0xD4, 0xC1, 0xED, 0x36, 0xC7, 0x87, 0x2C, 0x55)
+
+	if got := qrInterleave(data, qrLevelM[0]); !bytes.Equal(got, want) {
+		t.Errorf("codewords\n got % X\nwant % X", got, want)
+	}
+}
+
+// qrSKU12345 is "SKU-12345" as a version 1-M symbol with mask 0, checked
+// by decoding it: the format information is valid, the Reed-Solomon
+// syndromes are zero and the byte-mode payload reads back.
+var qrSKU12345 = []string{
+	"#######....#..#######",
+	"#.....#.#####.#.....#",
+	"#.###.#..#.##.#.###.#",
+	"#.###.#...#.#.#.###.#",
+	"#.###.#.###.#.#.###.#",
+	"#.....#.....#.#.....#",
+	"#######.#.#.#.#######",
+	".........#.##........",
+	"#.#.#.#..#.#....#..#.",
+	"....##....#.....####.",
+	"#.#...#.###.#.#.##.##",
+	"######..###..#..#...#",
+	".#....#...#.##..###.#",
+	"........##.#....#....",
+	"#######..#.#.##.#####",
+	"#.....#....###.###...",
+	"#.###.#.##.#..#.#....",
+	"#.###.#...#...#.#.##.",
+	"#.###.#.###.#...###.#",
+	"#.....#..#....#..#.#.",
+	"#######.#.#.#.#.##.##",
+}
+
+func TestEncodeQRVersion1M(t *testing.T) {
+	qr, err := encodeQR("SKU-12345")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if qr.size != len(qrSKU12345) {
+		t.Fatalf("size %d, want %d", qr.size, len(qrSKU12345))
+	}
+
+	for y, row := range qr.modules {
+		var line strings.Builder
+		for _, dark := range row {
+			if dark {
+				line.WriteByte('#')
+			} else {
+				line.WriteByte('.')
+			}
+		}
+		if line.String() != qrSKU12345[y] {
+			t.Errorf("row %d\n got %s\nwant %s", y, line.String(), qrSKU12345[y])
+		}
+	}
+}
+
+// TestQRFormatBits checks the format information against the level M row
+// of the table in ISO/IEC 18004 Annex C.
+func TestQRFormatBits(t *testing.T) {
+	want := []string{
+		"101010000010010", "101000100100101", "101111001111100", "101101101001011",
+		"100010111111001", "100000011001110", "100111110010111", "100101010100000",
+	}
+	// Bits 14 to 0 of the copy around the top left finder pattern
+	positions := [][2]int{{0, 8}, {1, 8}, {2, 8}, {3, 8}, {4, 8}, {5, 8}, {7, 8}, {8, 8}, {8, 7}, {8, 5}, {8, 4}, {8, 3}, {8, 2}, {8, 1}, {8, 0}}
+
+	for mask, bits := range want {
+		qr := newQRCode(1)
+		qr.drawFormatBits(mask)
+		var got strings.Builder
+		for _, p := range positions {
+			if qr.modules[p[1]][p[0]] {
+				got.WriteByte('1')
+			} else {
+				got.WriteByte('0')
+			}
+		}
+		if got.String() != bits {
+			t.Errorf("mask %d: format bits %s, want %s", mask, got.String(), bits)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package labels
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"stock-management/internal/domain/services"
+)
+
+// Sheets are laid out in dots of 1/300 inch, the resolution of PNG sheets.
+const (
+	sheetWidth    = 2480 // A4, 210mm
+	sheetHeight   = 3508 // A4, 297mm
+	labelColumns  = 3
+	labelRows     = 8
+	labelWidth    = 750
+	labelHeight   = 400
+	labelPadding  = 30
+	labelsPerPage = labelColumns * labelRows
+	sheetMarginX  = (sheetWidth - labelColumns*labelWidth) / 2
+	sheetMarginY  = (sheetHeight - labelRows*labelHeight) / 2
+
+	// maxLabelPages bounds the size of one PDF document
+	maxLabelPages = 20
+)
+
+// labelCanvas is a page being drawn on, in dots from its top left corner.
+type labelCanvas interface {
+	fillRect(x, y, width, height int)
+	// text draws a line of text whose top is at y and whose capitals are
+	// about size dots high
+	text(x, y, size int, s string)
+}
+
+type labelServiceImpl struct{}
+
+// NewLabelService creates a new LabelService. Barcodes, QR codes, PNG and
+// PDF output are all produced without external dependencies.
+func NewLabelService() services.LabelService {
+	return &labelServiceImpl{}
+}
+
+func (s *labelServiceImpl) Render(labels []services.Label, symbology, format string) ([]byte, string, error) {
+	if len(labels) == 0 {
+		return nil, "", errors.New("no labels to print")
+	}
+	if symbology != services.LabelCode128 && symbology != services.LabelQR {
+		return nil, "", errors.New("invalid label symbology " + symbology)
+	}
+	pages := (len(labels) + labelsPerPage - 1) / labelsPerPage
+
+	switch format {
+	case services.LabelPNG:
+		if pages > 1 {
+			return nil, "", fmt.Errorf("a PNG sheet holds at most %d labels, use PDF for more", labelsPerPage)
+		}
+		sheet := newRasterPage()
+		if err := drawLabels(sheet, labels, symbology); err != nil {
+			return nil, "", err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, sheet.img); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), "image/png", nil
+
+	case services.LabelPDF:
+		if pages > maxLabelPages {
+			return nil, "", fmt.Errorf("at most %d labels can be printed at once", maxLabelPages*labelsPerPage)
+		}
+		var document pdfDocument
+		for page := 0; page < pages; page++ {
+			onPage := labels[page*labelsPerPage : min((page+1)*labelsPerPage, len(labels))]
+			canvas := &pdfPage{}
+			if err := drawLabels(canvas, onPage, symbology); err != nil {
+				return nil, "", err
+			}
+			document.pages = append(document.pages, canvas)
+		}
+		return document.bytes(), "application/pdf", nil
+	}
+	return nil, "", errors.New("invalid label format " + format)
+}
+
+// drawLabels fills one sheet row by row.
+func drawLabels(canvas labelCanvas, labels []services.Label, symbology string) error {
+	for i, label := range labels {
+		x := sheetMarginX + i%labelColumns*labelWidth
+		y := sheetMarginY + i/labelColumns*labelHeight
+
+		var err error
+		if symbology == services.LabelQR {
+			err = drawQRLabel(canvas, x, y, label)
+		} else {
+			err = drawCode128Label(canvas, x, y, label)
+		}
+		if err != nil {
+			return fmt.Errorf("label for %s: %w", label.Code, err)
+		}
+	}
+	return nil
+}
+
+// drawCode128Label prints the title, the bars with the code under them and
+// the caption. Bars are a whole number of dots wide so they stay sharp.
+func drawCode128Label(canvas labelCanvas, x, y int, label services.Label) error {
+	modules, err := encodeCode128(label.Code)
+	if err != nil {
+		return err
+	}
+	// The quiet zone on either side is ten modules wide
+	width := labelWidth - 2*labelPadding
+	moduleWidth := min(4, width/(len(modules)+20))
+	if moduleWidth == 0 {
+		return errors.New("code is too long for a label")
+	}
+
+	drawText(canvas, x+labelPadding, y+labelPadding, 36, width, label.Title)
+	left := x + (labelWidth-len(modules)*moduleWidth)/2
+	for start := 0; start < len(modules); {
+		if !modules[start] {
+			start++
+			continue
+		}
+		end := start
+		for end < len(modules) && modules[end] {
+			end++
+		}
+		canvas.fillRect(left+start*moduleWidth, y+85, (end-start)*moduleWidth, 190)
+		start = end
+	}
+	drawText(canvas, x+labelPadding, y+290, 30, width, label.Code)
+	if label.Caption != label.Code {
+		drawText(canvas, x+labelPadding, y+335, 30, width, label.Caption)
+	}
+	return nil
+}
+
+// drawQRLabel prints the QR code on the left and the text beside it.
+func drawQRLabel(canvas labelCanvas, x, y int, label services.Label) error {
+	qr, err := encodeQR(label.Code)
+	if err != nil {
+		return err
+	}
+	// The quiet zone is four modules wide on every side
+	moduleSize := (labelHeight - 2*labelPadding) / (qr.size + 8)
+	left, top := x+labelPadding+4*moduleSize, y+labelPadding+4*moduleSize
+	for row := 0; row < qr.size; row++ {
+		for start := 0; start < qr.size; {
+			if !qr.modules[row][start] {
+				start++
+				continue
+			}
+			end := start
+			for end < qr.size && qr.modules[row][end] {
+				end++
+			}
+			canvas.fillRect(left+start*moduleSize, top+row*moduleSize, (end-start)*moduleSize, moduleSize)
+			start = end
+		}
+	}
+
+	textLeft := x + labelPadding + (qr.size+8)*moduleSize
+	width := x + labelWidth - labelPadding - textLeft
+	drawText(canvas, textLeft, y+labelPadding+20, 36, width, label.Title)
+	drawText(canvas, textLeft, y+labelPadding+100, 30, width, label.Code)
+	if label.Caption != label.Code {
+		drawText(canvas, textLeft, y+labelPadding+150, 30, width, label.Caption)
+	}
+	return nil
+}
+
+// drawText prints text cut to fit the width. Characters are taken to be
+// three quarters of the size wide, which holds for the bitmap font and
+// leaves room for Helvetica.
+func drawText(canvas labelCanvas, x, y, size, width int, text string) {
+	if text == "" {
+		return
+	}
+	runes := []rune(text)
+	if fits := width * 4 / (size * 3); len(runes) > fits {
+		runes = append(runes[:max(fits-1, 0)], '~')
+	}
+	canvas.text(x, y, size, string(runes))
+}
+
+// rasterPage draws a sheet into a grayscale image at 300 dpi.
+type rasterPage struct {
+	img *image.Gray
+}
+
+func newRasterPage() *rasterPage {
+	img := image.NewGray(image.Rect(0, 0, sheetWidth, sheetHeight))
+	for i := range img.Pix {
+		img.Pix[i] = 0xFF
+	}
+	return &rasterPage{img: img}
+}
+
+func (p *rasterPage) fillRect(x, y, width, height int) {
+	rect := image.Rect(x, y, x+width, y+height).Intersect(p.img.Bounds())
+	for row := rect.Min.Y; row < rect.Max.Y; row++ {
+		for col := rect.Min.X; col < rect.Max.X; col++ {
+			p.img.SetGray(col, row, color.Gray{})
+		}
+	}
+}
+
+// text draws with the bitmap font, scaled so a glyph cell of 6 by 8 font
+// pixels is about size dots high.
+func (p *rasterPage) text(x, y, size int, s string) {
+	scale := max(size/8, 1)
+	for _, r := range s {
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+		for column, bits := range labelFont[r-' '] {
+			for row := 0; row < 8; row++ {
+				if bits>>row&1 == 1 {
+					p.fillRect(x+column*scale, y+row*scale, scale, scale)
+				}
+			}
+		}
+		x += 6 * scale
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AdjustmentReviewRequest struct {
+	Notes string `json:"notes"`
+}
+
+func (s *Server) handleGetAdjustments(c *gin.Context) {
+	var status *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+
+	adjustments, err := s.adjustmentService.GetAdjustments(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustments)
+}
+
+func (s *Server) handleGetAdjustment(c *gin.Context) {
+	adjustment, err := s.adjustmentService.GetAdjustment(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustment)
+}
+
+// handleRequestAdjustment takes a signed quantity; the response status tells
+// whether the adjustment was posted or is waiting for approval.
+func (s *Server) handleRequestAdjustment(c *gin.Context) {
+	var req StockMovementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adjustment, err := s.adjustmentService.RequestAdjustment(c.Request.Context(), req.toStockChange(c.GetUint("user_id")))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, adjustment)
+}
+
+func (s *Server) handleApproveAdjustment(c *gin.Context) {
+	var req AdjustmentReviewRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	adjustment, err := s.adjustmentService.ApproveAdjustment(c.Request.Context(), uint(parseUint(c.Param("id"))), c.GetUint("user_id"), req.Notes)
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustment)
+}
+
+func (s *Server) handleRejectAdjustment(c *gin.Context) {
+	var req AdjustmentReviewRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	adjustment, err := s.adjustmentService.RejectAdjustment(c.Request.Context(), uint(parseUint(c.Param("id"))), c.GetUint("user_id"), req.Notes)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, adjustment)
+}
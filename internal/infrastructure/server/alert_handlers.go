@@ -0,0 +1,83 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReorderRuleRequest struct {
+	ProductID   uint            `json:"productId" binding:"required"`
+	LocationID  *uint           `json:"locationId"`
+	MinQuantity models.Quantity `json:"minQuantity"`
+	MaxQuantity models.Quantity `json:"maxQuantity" binding:"required"`
+}
+
+func (s *Server) handleGetReorderRules(c *gin.Context) {
+	rules, err := s.alertService.GetRules(c.Request.Context(), parseOptionalUint(c.Query("productId")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func (s *Server) handleSaveReorderRule(c *gin.Context) {
+	var req ReorderRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rule := models.ReorderRule{
+		ProductID:   req.ProductID,
+		LocationID:  req.LocationID,
+		MinQuantity: req.MinQuantity,
+		MaxQuantity: req.MaxQuantity,
+	}
+
+	err := s.alertService.SaveRule(c.Request.Context(), &rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rule)
+}
+
+func (s *Server) handleDeleteReorderRule(c *gin.Context) {
+	err := s.alertService.DeleteRule(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reorder rule deleted successfully"})
+}
+
+func (s *Server) handleGetLowStockAlerts(c *gin.Context) {
+	lowStock, err := s.alertService.GetLowStock(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, lowStock)
+}
+
+func (s *Server) handleGetAlertEvents(c *gin.Context) {
+	var status *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+
+	alerts, err := s.alertService.GetAlerts(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
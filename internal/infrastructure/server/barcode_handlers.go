@@ -0,0 +1,100 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BarcodeRequest struct {
+	Code      string `json:"code" binding:"required"`
+	Symbology string `json:"symbology" binding:"required"`
+}
+
+type LabelRequest struct {
+	ProductIDs []uint `json:"productIds" binding:"required,min=1"`
+	Symbology  string `json:"symbology"` // code128 (default) or qr
+	Format     string `json:"format"`    // pdf (default) or png
+	Copies     int    `json:"copies"`
+}
+
+func (s *Server) handleLookupBarcode(c *gin.Context) {
+	product, err := s.barcodeService.Lookup(c.Request.Context(), c.Query("barcode"))
+	if errors.Is(err, repositories.ErrAmbiguousBarcode) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+func (s *Server) handleGetBarcodes(c *gin.Context) {
+	barcodes, err := s.barcodeService.GetBarcodes(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, barcodes)
+}
+
+func (s *Server) handleAddBarcode(c *gin.Context) {
+	var req BarcodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	barcode := models.Barcode{
+		ProductID: uint(parseUint(c.Param("id"))),
+		Code:      req.Code,
+		Symbology: req.Symbology,
+	}
+	if err := s.barcodeService.AddBarcode(c.Request.Context(), &barcode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, barcode)
+}
+
+func (s *Server) handleDeleteBarcode(c *gin.Context) {
+	err := s.barcodeService.DeleteBarcode(c.Request.Context(), uint(parseUint(c.Param("id"))), uint(parseUint(c.Param("barcodeId"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Barcode deleted successfully"})
+}
+
+// handlePrintLabels returns a label sheet as a file download rather than JSON.
+func (s *Server) handlePrintLabels(c *gin.Context) {
+	var req LabelRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	content, contentType, err := s.barcodeService.RenderLabels(c.Request.Context(),
+		req.ProductIDs, req.Symbology, req.Format, req.Copies)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	extension := "pdf"
+	if contentType == "image/png" {
+		extension = "png"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="labels.%s"`, extension))
+	c.Data(http.StatusOK, contentType, content)
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CountSessionRequest struct {
+	LocationID *uint  `json:"locationId"`
+	CategoryID *uint  `json:"categoryId"`
+	Notes      string `json:"notes"`
+}
+
+type CountEntriesRequest struct {
+	Lines []struct {
+		ProductID  uint             `json:"productId" binding:"required"`
+		LocationID uint             `json:"locationId" binding:"required"`
+		Quantity   *models.Quantity `json:"quantity" binding:"required"`
+	} `json:"lines" binding:"required,dive"`
+}
+
+type CountApprovalRequest struct {
+	LineIDs []uint `json:"lineIds"`
+}
+
+type CountPostRequest struct {
+	ReasonCode string `json:"reasonCode" binding:"required"`
+}
+
+func (s *Server) handleGetCountSessions(c *gin.Context) {
+	var status *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+
+	sessions, err := s.countService.GetSessions(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+func (s *Server) handleGetCountSession(c *gin.Context) {
+	session, err := s.countService.GetSession(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) handleGetCountVariances(c *gin.Context) {
+	variances, err := s.countService.GetVariances(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, variances)
+}
+
+func (s *Server) handleOpenCountSession(c *gin.Context) {
+	var req CountSessionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session := models.CountSession{
+		LocationID: req.LocationID,
+		CategoryID: req.CategoryID,
+		Notes:      req.Notes,
+	}
+	err := s.countService.OpenSession(c.Request.Context(), &session, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, session)
+}
+
+func (s *Server) handleRecordCounts(c *gin.Context) {
+	var req CountEntriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines := make([]models.CountLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		lines = append(lines, models.CountLine{
+			ProductID:       line.ProductID,
+			LocationID:      line.LocationID,
+			CountedQuantity: line.Quantity,
+		})
+	}
+
+	session, err := s.countService.RecordCounts(c.Request.Context(), uint(parseUint(c.Param("id"))), lines, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) handleReviewCountSession(c *gin.Context) {
+	session, err := s.countService.StartReview(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) handleReopenCountSession(c *gin.Context) {
+	session, err := s.countService.ReopenCounting(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) handleApproveCountLines(c *gin.Context) {
+	// An empty body approves every counted line
+	var req CountApprovalRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	session, err := s.countService.ApproveLines(c.Request.Context(), uint(parseUint(c.Param("id"))), req.LineIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) handlePostCountSession(c *gin.Context) {
+	var req CountPostRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	session, err := s.countService.PostSession(c.Request.Context(), uint(parseUint(c.Param("id"))), req.ReasonCode, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
+
+func (s *Server) handleCancelCountSession(c *gin.Context) {
+	session, err := s.countService.CancelSession(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
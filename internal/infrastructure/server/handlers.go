@@ -1,8 +1,11 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/repositories"
+	"stock-management/internal/domain/usecases"
 	"strconv"
 	"time"
 
@@ -21,7 +24,7 @@ func (s *Server) handleLogin(c *gin.Context) {
 		return
 	}
 
-	userDTO, token, err := s.authService.Login(c.Request.Context(), loginReq.Username, loginReq.Password)
+	userDTO, tokens, err := s.authService.Login(c.Request.Context(), loginReq.Username, loginReq.Password)
 	if err != nil {
 		// AuthService returns specific errors we can check
 		if err.Error() == "invalid credentials" {
@@ -36,12 +39,36 @@ func (s *Server) handleLogin(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"user":    userDTO,
-		"token":   token,
+		"message":      "Login successful",
+		"user":         userDTO,
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
 	})
 }
 
+func (s *Server) handleRefresh(c *gin.Context) {
+	var refreshReq struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&refreshReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := s.authService.Refresh(c.Request.Context(), refreshReq.RefreshToken)
+	if err != nil {
+		if errors.Is(err, usecases.ErrInvalidRefreshToken) || errors.Is(err, usecases.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Refresh failed: an unexpected error occurred"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
 func (s *Server) handleRegister(c *gin.Context) {
 	var registerReq struct {
 		Username string `json:"username" binding:"required"`
@@ -64,14 +91,97 @@ func (s *Server) handleRegister(c *gin.Context) {
 }
 
 func (s *Server) handleLogout(c *gin.Context) {
+	var logoutReq struct {
+		RefreshToken string `json:"refreshToken" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&logoutReq); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.authService.Logout(c.Request.Context(), logoutReq.RefreshToken); err != nil {
+		if errors.Is(err, usecases.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Logout failed: an unexpected error occurred"})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
 }
 
+// User handlers
+func (s *Server) handleSetUserRole(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "user ID is required"})
+		return
+	}
+
+	var req struct {
+		Role models.Role `json:"role" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := s.authService.SetRole(c.Request.Context(), uint(parseUint(id)), req.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
 // Stock handlers
 type StockMovementRequest struct {
-	ProductID uint   `json:"productId" binding:"required"`
-	Quantity  int    `json:"quantity" binding:"required"`
-	Notes     string `json:"notes"`
+	ProductID  uint            `json:"productId" binding:"required"`
+	LocationID uint            `json:"locationId" binding:"required"`
+	Quantity   models.Quantity `json:"quantity" binding:"required"`
+	Notes      string          `json:"notes"`
+	LotNumber  string          `json:"lotNumber"`
+	ExpiryDate *time.Time      `json:"expiryDate"`
+	Serials    []string        `json:"serials"`
+	ReasonCode string          `json:"reasonCode"`
+
+	// UnitCost is only honoured on import
+	UnitCost models.Money `json:"unitCost"`
+
+	// Date backdates an import or export, it defaults to now
+	Date *time.Time `json:"date"`
+
+	// Unit is the unit code quantity and unit cost are given in, it
+	// defaults to the product's base unit
+	Unit string `json:"unit"`
+
+	// ReservationID is only honoured on export
+	ReservationID *uint `json:"reservationId"`
+}
+
+func (req *StockMovementRequest) toStockChange(userID uint) usecases.StockChange {
+	change := usecases.StockChange{
+		ProductID:  req.ProductID,
+		LocationID: req.LocationID,
+		Quantity:   req.Quantity,
+		UserID:     userID,
+		Notes:      req.Notes,
+		LotNumber:  req.LotNumber,
+		ExpiryDate: req.ExpiryDate,
+		Serials:    req.Serials,
+		ReasonCode: req.ReasonCode,
+		UnitCost:   req.UnitCost,
+		Unit:       req.Unit,
+
+		ReservationID: req.ReservationID,
+	}
+	if req.Date != nil {
+		change.Date = *req.Date
+	}
+	return change
 }
 
 func (s *Server) handleImportStock(c *gin.Context) {
@@ -84,9 +194,9 @@ func (s *Server) handleImportStock(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID := c.GetUint("user_id")
 
-	err := s.stockService.ImportStock(c.Request.Context(), req.ProductID, req.Quantity, userID, req.Notes)
+	err := s.stockService.ImportStock(c.Request.Context(), req.toStockChange(userID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -103,9 +213,9 @@ func (s *Server) handleExportStock(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID := c.GetUint("user_id")
 
-	err := s.stockService.ExportStock(c.Request.Context(), req.ProductID, req.Quantity, userID, req.Notes)
+	err := s.stockService.ExportStock(c.Request.Context(), req.toStockChange(userID))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -113,7 +223,7 @@ func (s *Server) handleExportStock(c *gin.Context) {
 }
 
 func (s *Server) handleGetCurrentStock(c *gin.Context) {
-	stocks, err := s.stockService.GetStockSummary(c.Request.Context())
+	stocks, err := s.stockService.GetCurrentStock(c.Request.Context())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -124,26 +234,39 @@ func (s *Server) handleGetCurrentStock(c *gin.Context) {
 
 func (s *Server) handleGetStockMovements(c *gin.Context) {
 	var req struct {
-		StartDate  *time.Time `json:"startDate"`
-		EndDate    *time.Time `json:"endDate"`
-		ProductID  *uint      `json:"productId"`
-		CategoryID *uint      `json:"categoryId"`
+		StartDate  *time.Time           `json:"startDate"`
+		EndDate    *time.Time           `json:"endDate"`
+		ProductID  *uint                `json:"productId"`
+		CategoryID *uint                `json:"categoryId"`
+		LocationID *uint                `json:"locationId"`
+		Type       *models.MovementType `json:"type"`
+		ReasonCode *string              `json:"reasonCode"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if req.Type != nil && !req.Type.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid movement type " + string(*req.Type)})
+		return
+	}
 
-	var startDate, endDate time.Time
+	filter := models.MovementFilter{
+		ProductID:  req.ProductID,
+		CategoryID: req.CategoryID,
+		LocationID: req.LocationID,
+		Type:       req.Type,
+		ReasonCode: req.ReasonCode,
+	}
 	if req.StartDate != nil {
-		startDate = *req.StartDate
+		filter.StartDate = *req.StartDate
 	}
 	if req.EndDate != nil {
-		endDate = *req.EndDate
+		filter.EndDate = *req.EndDate
 	}
 
-	movements, err := s.stockService.GetStockMovements(c.Request.Context(), startDate, endDate, req.ProductID, req.CategoryID)
+	movements, err := s.stockService.GetStockMovements(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -152,8 +275,28 @@ func (s *Server) handleGetStockMovements(c *gin.Context) {
 	c.JSON(http.StatusOK, movements)
 }
 
+func (s *Server) handleReverseMovement(c *gin.Context) {
+	var req struct {
+		Notes string `json:"notes"`
+	}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	reversal, err := s.stockService.ReverseMovement(c.Request.Context(), uint(parseUint(c.Param("id"))), c.GetUint("user_id"), req.Notes)
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reversal)
+}
+
 func (s *Server) handleGetStockSummary(c *gin.Context) {
-	stocks, err := s.stockService.GetStockSummary(c.Request.Context())
+	stocks, err := s.stockService.GetStockSummary(c.Request.Context(), parseOptionalUint(c.Query("locationId")))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
@@ -162,6 +305,25 @@ func (s *Server) handleGetStockSummary(c *gin.Context) {
 	c.JSON(http.StatusOK, stocks)
 }
 
+// handleGetStockAsOf answers what was held at a past moment. A bare date
+// means the end of that day.
+func (s *Server) handleGetStockAsOf(c *gin.Context) {
+	asOf, err := parseAsOf(c.Query("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	balances, err := s.stockService.GetStockAsOf(c.Request.Context(), asOf,
+		parseOptionalUint(c.Query("productId")), parseOptionalUint(c.Query("locationId")))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"asOf": asOf, "products": balances})
+}
+
 // Product handlers
 
 func (s *Server) handleCreateProduct(c *gin.Context) {
@@ -180,6 +342,20 @@ func (s *Server) handleCreateProduct(c *gin.Context) {
 	c.JSON(http.StatusCreated, product)
 }
 
+// UpdateProductRequest changes the fields it names; omitted fields keep
+// their current value
+type UpdateProductRequest struct {
+	Name             *string `json:"name"`
+	ImageURL         *string `json:"imageURL"`
+	Description      *string `json:"description"`
+	CategoryID       *uint   `json:"categoryId"`
+	SKU              *string `json:"sku"`
+	TrackingMode     *string `json:"trackingMode"`
+	CostingMethod    *string `json:"costingMethod"`
+	QuantityDecimals *int    `json:"quantityDecimals"`
+	BaseUnitID       *uint   `json:"baseUnitId"`
+}
+
 func (s *Server) handleUpdateProduct(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -187,16 +363,13 @@ func (s *Server) handleUpdateProduct(c *gin.Context) {
 		return
 	}
 
-	var product models.Product
-	if err := c.ShouldBindJSON(&product); err != nil {
+	var req UpdateProductRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Set the ID from the URL parameter
-	product.ID = uint(parseUint(id))
-
-	err := s.stockService.UpdateProduct(c.Request.Context(), &product)
+	product, err := s.stockService.UpdateProduct(c.Request.Context(), uint(parseUint(id)), usecases.ProductUpdate(req))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -221,6 +394,41 @@ func (s *Server) handleDeleteProduct(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
 }
 
+func (s *Server) handleGetProductLots(c *gin.Context) {
+	lots, err := s.stockService.GetLots(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, lots)
+}
+
+func (s *Server) handleGetProductSerials(c *gin.Context) {
+	var status *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+
+	serials, err := s.stockService.GetSerialNumbers(c.Request.Context(), uint(parseUint(c.Param("id"))), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, serials)
+}
+
+func (s *Server) handleGetSerialHistory(c *gin.Context) {
+	history, err := s.stockService.GetSerialHistory(c.Request.Context(), uint(parseUint(c.Param("id"))), c.Param("serial"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
+
 func (s *Server) handleGetProducts(c *gin.Context) {
 	products, err := s.stockService.GetProducts(c.Request.Context())
 	if err != nil {
@@ -299,8 +507,240 @@ func (s *Server) handleDeleteCategory(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
 }
 
+// Reason code handlers
+func (s *Server) handleGetReasonCodes(c *gin.Context) {
+	var movementType *models.MovementType
+	if value := c.Query("type"); value != "" {
+		t := models.MovementType(value)
+		movementType = &t
+	}
+
+	reasonCodes, err := s.stockService.GetReasonCodes(c.Request.Context(), movementType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reasonCodes)
+}
+
+func (s *Server) handleCreateReasonCode(c *gin.Context) {
+	var reasonCode models.ReasonCode
+	if err := c.ShouldBindJSON(&reasonCode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := s.stockService.CreateReasonCode(c.Request.Context(), &reasonCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reasonCode)
+}
+
+func (s *Server) handleUpdateReasonCode(c *gin.Context) {
+	// Codes stay active unless the request says otherwise
+	reasonCode := models.ReasonCode{Active: true}
+	if err := c.ShouldBindJSON(&reasonCode); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set the ID from the URL parameter
+	reasonCode.ID = uint(parseUint(c.Param("id")))
+
+	err := s.stockService.UpdateReasonCode(c.Request.Context(), &reasonCode)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reasonCode)
+}
+
+// Warehouse handlers
+func (s *Server) handleGetWarehouses(c *gin.Context) {
+	warehouses, err := s.stockService.GetWarehouses(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouses)
+}
+
+func (s *Server) handleCreateWarehouse(c *gin.Context) {
+	var warehouse models.Warehouse
+	if err := c.ShouldBindJSON(&warehouse); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := s.stockService.CreateWarehouse(c.Request.Context(), &warehouse)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, warehouse)
+}
+
+func (s *Server) handleUpdateWarehouse(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "warehouse ID is required"})
+		return
+	}
+
+	var warehouse models.Warehouse
+	if err := c.ShouldBindJSON(&warehouse); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set the ID from the URL parameter
+	warehouse.ID = uint(parseUint(id))
+
+	err := s.stockService.UpdateWarehouse(c.Request.Context(), &warehouse)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, warehouse)
+}
+
+func (s *Server) handleDeleteWarehouse(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "warehouse ID is required"})
+		return
+	}
+
+	err := s.stockService.DeleteWarehouse(c.Request.Context(), uint(parseUint(id)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Warehouse deleted successfully"})
+}
+
+// Location handlers
+func (s *Server) handleGetLocations(c *gin.Context) {
+	locations, err := s.stockService.GetLocations(c.Request.Context(), parseOptionalUint(c.Query("warehouseId")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, locations)
+}
+
+func (s *Server) handleCreateLocation(c *gin.Context) {
+	var location models.Location
+	if err := c.ShouldBindJSON(&location); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := s.stockService.CreateLocation(c.Request.Context(), &location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, location)
+}
+
+func (s *Server) handleUpdateLocation(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "location ID is required"})
+		return
+	}
+
+	var location models.Location
+	if err := c.ShouldBindJSON(&location); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set the ID from the URL parameter
+	location.ID = uint(parseUint(id))
+
+	err := s.stockService.UpdateLocation(c.Request.Context(), &location)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, location)
+}
+
+func (s *Server) handleDeleteLocation(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "location ID is required"})
+		return
+	}
+
+	err := s.stockService.DeleteLocation(c.Request.Context(), uint(parseUint(id)))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Location deleted successfully"})
+}
+
+// stockErrorStatus maps stock change errors to a response status. Changes that
+// conflict with the current stock level or with a concurrent change are 409.
+func stockErrorStatus(err error) int {
+	var insufficient *repositories.InsufficientStockError
+	if errors.As(err, &insufficient) || errors.Is(err, repositories.ErrStockConflict) || errors.Is(err, repositories.ErrAlreadyReversed) {
+		return http.StatusConflict
+	}
+	return http.StatusBadRequest
+}
+
+// Helper function to parse a point in time given as RFC 3339 or as a bare
+// date, which is taken as the end of that day, or as now for today
+func parseAsOf(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, errors.New("date is required")
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	day, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("date must be YYYY-MM-DD or RFC 3339")
+	}
+	endOfDay := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	if now := time.Now(); endOfDay.After(now) && !day.After(now) {
+		return now, nil
+	}
+	return endOfDay, nil
+}
+
 // Helper function to parse uint from string
 func parseUint(s string) uint64 {
 	u, _ := strconv.ParseUint(s, 10, 64)
 	return u
 }
+
+// Helper function to parse an optional uint filter, nil when absent or invalid
+func parseOptionalUint(s string) *uint {
+	if s == "" {
+		return nil
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return nil
+	}
+	v := uint(u)
+	return &v
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAsOf(t *testing.T) {
+	now := time.Now()
+
+	today, err := parseAsOf(now.Format("2006-01-02"))
+	if err != nil {
+		t.Fatalf("today: %v", err)
+	}
+	if today.Before(now) || today.After(time.Now()) {
+		t.Errorf("today is read as %s, want the current time", today)
+	}
+
+	yesterday := now.AddDate(0, 0, -1)
+	got, err := parseAsOf(yesterday.Format("2006-01-02"))
+	if err != nil {
+		t.Fatalf("yesterday: %v", err)
+	}
+	want := time.Date(yesterday.Year(), yesterday.Month(), yesterday.Day(), 23, 59, 59, 999999999, time.Local)
+	if !got.Equal(want) {
+		t.Errorf("yesterday is read as %s, want %s", got, want)
+	}
+
+	tomorrow := now.AddDate(0, 0, 1)
+	if got, err := parseAsOf(tomorrow.Format("2006-01-02")); err != nil || !got.After(now) {
+		t.Errorf("tomorrow is read as %s, %v, want the end of that day", got, err)
+	}
+
+	if got, err := parseAsOf("2024-03-01T12:00:00Z"); err != nil || !got.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("RFC 3339 is read as %s, %v", got, err)
+	}
+	for _, invalid := range []string{"", "01.03.2024", "2024-13-01"} {
+		if _, err := parseAsOf(invalid); err == nil {
+			t.Errorf("parseAsOf(%q) succeeded, want an error", invalid)
+		}
+	}
+}
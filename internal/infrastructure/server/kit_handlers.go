@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type KitComponentsRequest struct {
+	Components []struct {
+		ComponentID uint            `json:"componentId" binding:"required"`
+		Quantity    models.Quantity `json:"quantity" binding:"required"`
+	} `json:"components" binding:"dive"`
+}
+
+type KitBuildRequest struct {
+	LocationID uint            `json:"locationId" binding:"required"`
+	Quantity   models.Quantity `json:"quantity" binding:"required"`
+	Notes      string          `json:"notes"`
+}
+
+func (s *Server) handleGetKit(c *gin.Context) {
+	kit, err := s.kitService.GetKit(c.Request.Context(), uint(parseUint(c.Param("id"))), parseOptionalUint(c.Query("locationId")))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, kit)
+}
+
+func (s *Server) handleSetKitComponents(c *gin.Context) {
+	var req KitComponentsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	components := make([]models.KitComponent, 0, len(req.Components))
+	for _, component := range req.Components {
+		components = append(components, models.KitComponent{ComponentID: component.ComponentID, Quantity: component.Quantity})
+	}
+
+	kit, err := s.kitService.SetComponents(c.Request.Context(), uint(parseUint(c.Param("id"))), components)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, kit)
+}
+
+func (s *Server) handleAssembleKit(c *gin.Context) {
+	var req KitBuildRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	build, err := s.kitService.Assemble(c.Request.Context(), uint(parseUint(c.Param("id"))), req.LocationID, req.Quantity, c.GetUint("user_id"), req.Notes)
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, build)
+}
+
+func (s *Server) handleDisassembleKit(c *gin.Context) {
+	var req KitBuildRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	build, err := s.kitService.Disassemble(c.Request.Context(), uint(parseUint(c.Param("id"))), req.LocationID, req.Quantity, c.GetUint("user_id"), req.Notes)
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, build)
+}
@@ -1,14 +1,20 @@
 package server
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
 	"net/http"
+	"stock-management/internal/domain/models"
 	"stock-management/internal/domain/services"
+	"stock-management/internal/domain/usecases"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(jwtService services.JWTService) gin.HandlerFunc {
+func AuthMiddleware(jwtService services.JWTService, authService *usecases.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -32,7 +38,143 @@ func AuthMiddleware(jwtService services.JWTService) gin.HandlerFunc {
 			return
 		}
 
+		// Reject tokens whose session was logged out or revoked for reuse
+		sessionID, _ := claims["sid"].(string)
+		active, err := authService.IsSessionActive(c.Request.Context(), sessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not verify session"})
+			c.Abort()
+			return
+		}
+		if !active {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
 		c.Set("user_id", uint(claims["user_id"].(float64))) // JWT standard decodes numbers as float64
+
+		// Tokens issued before roles existed carry no role claim and are
+		// treated as the least privileged role.
+		role := models.RoleViewer
+		if claimRole, ok := claims["role"].(string); ok && models.Role(claimRole).Valid() {
+			role = models.Role(claimRole)
+		}
+		c.Set("role", role)
+		c.Next()
+	}
+}
+
+// RequireRole only lets the request through when the authenticated user holds
+// minRole or a higher role. It must run after AuthMiddleware.
+func RequireRole(minRole models.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, ok := c.Get("role")
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			c.Abort()
+			return
+		}
+
+		if !role.(models.Role).AtLeast(minRole) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions: requires " + string(minRole) + " role"})
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
+
+// bodyCaptureWriter keeps a copy of everything written to the response.
+type bodyCaptureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *bodyCaptureWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *bodyCaptureWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// IdempotencyMiddleware honours the Idempotency-Key header. The first request
+// with a key runs normally and its response is stored; a retry with the same
+// key and payload gets the stored response back without running the handler
+// again, while a different payload under the same key is rejected with 422.
+// Requests without the header are not affected. It must run after AuthMiddleware.
+func IdempotencyMiddleware(idempotencyService *usecases.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key must be at most 255 characters"})
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Could not read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		endpoint := c.Request.Method + " " + c.FullPath()
+		claimed, replay, err := idempotencyService.Begin(c.Request.Context(), c.GetUint("user_id"), key, endpoint, body)
+		switch {
+		case errors.Is(err, usecases.ErrIdempotencyKeyReused):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case errors.Is(err, usecases.ErrIdempotencyKeyInProgress):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Could not check idempotency key"})
+			c.Abort()
+			return
+		case replay != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(replay.ResponseStatus, "application/json; charset=utf-8", replay.ResponseBody)
+			c.Abort()
+			return
+		}
+
+		// Finish the key even if the client went away mid-request, and hold
+		// it for as long as the handler runs
+		ctx := context.WithoutCancel(c.Request.Context())
+		stopRenewing := idempotencyService.KeepAlive(ctx, claimed)
+		defer func() {
+			// A panicking handler never stored a response; give the key up
+			// before Recovery turns the panic into a 500
+			if r := recover(); r != nil {
+				stopRenewing()
+				_ = idempotencyService.Release(ctx, claimed)
+				panic(r)
+			}
+		}()
+
+		writer := &bodyCaptureWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+		stopRenewing()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError || status == http.StatusConflict {
+			// Server errors and stock conflicts are transient, let a retry run again
+			_ = idempotencyService.Release(ctx, claimed)
+			return
+		}
+		_ = idempotencyService.Complete(ctx, claimed, status, writer.body.Bytes())
+	}
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/usecases"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// memoryIdempotencyRepository keeps idempotency keys in memory the way the
+// database does: one live record per user and key, a new ID per claim.
+type memoryIdempotencyRepository struct {
+	mu      sync.Mutex
+	nextID  uint
+	records map[uint]*models.IdempotencyKey
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{records: map[uint]*models.IdempotencyKey{}}
+}
+
+func (r *memoryIdempotencyRepository) Claim(ctx context.Context, record *models.IdempotencyKey) (bool, *models.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for id, existing := range r.records {
+		if existing.UserID != record.UserID || existing.Key != record.Key {
+			continue
+		}
+		if existing.ExpiresAt.Before(time.Now()) {
+			delete(r.records, id)
+			break
+		}
+		copied := *existing
+		return false, &copied, nil
+	}
+	r.nextID++
+	record.ID = r.nextID
+	copied := *record
+	r.records[record.ID] = &copied
+	return true, nil, nil
+}
+
+func (r *memoryIdempotencyRepository) Renew(ctx context.Context, id uint, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[id]
+	if !ok || record.Completed {
+		return false, nil
+	}
+	record.ExpiresAt = expiresAt
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(ctx context.Context, id uint, status int, body []byte, expiresAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[id]
+	if !ok || record.Completed {
+		return false, nil
+	}
+	record.Completed = true
+	record.ResponseStatus = status
+	record.ResponseBody = body
+	record.ExpiresAt = expiresAt
+	return true, nil
+}
+
+func (r *memoryIdempotencyRepository) Release(ctx context.Context, id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	record, ok := r.records[id]
+	if !ok || record.Completed {
+		return false, nil
+	}
+	delete(r.records, id)
+	return true, nil
+}
+
+// newIdempotentRouter serves POST /import behind IdempotencyMiddleware with
+// the given handler, as user 1.
+func newIdempotentRouter(handler gin.HandlerFunc) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	service := usecases.NewIdempotencyService(newMemoryIdempotencyRepository())
+	router := gin.New()
+	router.POST("/import", func(c *gin.Context) { c.Set("user_id", uint(1)) }, IdempotencyMiddleware(service), handler)
+	return router
+}
+
+func postWithKey(router *gin.Engine, key, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/import", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Idempotency-Key", key)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestIdempotencyMiddleware(t *testing.T) {
+	calls := 0
+	router := newIdempotentRouter(func(c *gin.Context) {
+		calls++
+		c.JSON(http.StatusCreated, gin.H{"movement": calls})
+	})
+
+	first := postWithKey(router, "scan-1", `{"productId":1,"quantity":5}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("first request: status %d, want 201", first.Code)
+	}
+
+	replay := postWithKey(router, "scan-1", `{"productId":1,"quantity":5}`)
+	if replay.Code != http.StatusCreated || replay.Body.String() != first.Body.String() {
+		t.Errorf("replay: %d %s, want %d %s", replay.Code, replay.Body, first.Code, first.Body)
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Errorf("replay is not marked as replayed")
+	}
+
+	different := postWithKey(router, "scan-1", `{"productId":1,"quantity":50}`)
+	if different.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different payload under the same key: status %d, want 422", different.Code)
+	}
+
+	if other := postWithKey(router, "scan-2", `{"productId":1,"quantity":50}`); other.Code != http.StatusCreated {
+		t.Errorf("new key: status %d, want 201", other.Code)
+	}
+	if calls != 2 {
+		t.Errorf("handler ran %d times, want 2", calls)
+	}
+}
+
+// TestIdempotencyMiddlewareInProgress refuses a retry while the first
+// request is still running, and lets it through once a transient failure
+// released the key.
+func TestIdempotencyMiddlewareInProgress(t *testing.T) {
+	started, finish := make(chan struct{}), make(chan struct{})
+	calls := 0
+	router := newIdempotentRouter(func(c *gin.Context) {
+		calls++
+		if calls == 1 {
+			close(started)
+			<-finish
+			c.JSON(http.StatusConflict, gin.H{"error": "stock was changed concurrently, please retry"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{})
+	})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- postWithKey(router, "scan-1", `{}`) }()
+	<-started
+	if retry := postWithKey(router, "scan-1", `{}`); retry.Code != http.StatusConflict || calls != 1 {
+		t.Errorf("retry in flight: status %d after %d calls, want 409 without running", retry.Code, calls)
+	}
+	close(finish)
+	if first := <-done; first.Code != http.StatusConflict {
+		t.Fatalf("first request: status %d, want 409", first.Code)
+	}
+
+	if retry := postWithKey(router, "scan-1", `{}`); retry.Code != http.StatusCreated || calls != 2 {
+		t.Errorf("retry after a conflict: status %d after %d calls, want 201 from a second run", retry.Code, calls)
+	}
+}
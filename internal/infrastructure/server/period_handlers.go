@@ -0,0 +1,75 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PeriodRequest struct {
+	Year  int    `json:"year" binding:"required"`
+	Month int    `json:"month" binding:"required"`
+	Notes string `json:"notes"`
+}
+
+func (s *Server) handleGetClosedPeriods(c *gin.Context) {
+	periods, err := s.periodService.GetClosedPeriods(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, periods)
+}
+
+func (s *Server) handleClosePeriod(c *gin.Context) {
+	var req PeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	period, err := s.periodService.ClosePeriod(c.Request.Context(), req.Year, req.Month, req.Notes, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, period)
+}
+
+func (s *Server) handleReopenPeriod(c *gin.Context) {
+	var req PeriodRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := s.periodService.ReopenPeriod(c.Request.Context(), req.Year, req.Month); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Period reopened successfully"})
+}
+
+func (s *Server) handleGetSnapshots(c *gin.Context) {
+	day := time.Now()
+	if value := c.Query("date"); value != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "date must be YYYY-MM-DD"})
+			return
+		}
+		day = parsed
+	}
+
+	snapshots, err := s.periodService.GetSnapshots(c.Request.Context(), day, parseOptionalUint(c.Query("locationId")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, snapshots)
+}
@@ -0,0 +1,241 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/usecases"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PurchaseOrderLineRequest struct {
+	ProductID uint            `json:"productId" binding:"required"`
+	Quantity  models.Quantity `json:"quantity" binding:"required"`
+	UnitCost  models.Money    `json:"unitCost"`
+}
+
+type PurchaseOrderRequest struct {
+	SupplierID uint                       `json:"supplierId" binding:"required"`
+	LocationID uint                       `json:"locationId" binding:"required"`
+	Reference  string                     `json:"reference"`
+	Notes      string                     `json:"notes"`
+	ExpectedAt *time.Time                 `json:"expectedAt"`
+	Lines      []PurchaseOrderLineRequest `json:"lines" binding:"required,dive"`
+}
+
+func (req *PurchaseOrderRequest) toPurchaseOrder() models.PurchaseOrder {
+	order := models.PurchaseOrder{
+		SupplierID: req.SupplierID,
+		LocationID: req.LocationID,
+		Reference:  req.Reference,
+		Notes:      req.Notes,
+		ExpectedAt: req.ExpectedAt,
+	}
+	for _, line := range req.Lines {
+		order.Lines = append(order.Lines, models.PurchaseOrderLine{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+			UnitCost:  line.UnitCost,
+		})
+	}
+	return order
+}
+
+type PurchaseReceiptRequest struct {
+	Lines []struct {
+		LineID     uint            `json:"lineId" binding:"required"`
+		Quantity   models.Quantity `json:"quantity" binding:"required"`
+		LotNumber  string          `json:"lotNumber"`
+		ExpiryDate *time.Time      `json:"expiryDate"`
+		Serials    []string        `json:"serials"`
+	} `json:"lines" binding:"dive"`
+}
+
+// Supplier handlers
+func (s *Server) handleGetSuppliers(c *gin.Context) {
+	suppliers, err := s.purchaseService.GetSuppliers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, suppliers)
+}
+
+func (s *Server) handleGetSupplier(c *gin.Context) {
+	supplier, err := s.purchaseService.GetSupplier(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+func (s *Server) handleCreateSupplier(c *gin.Context) {
+	var supplier models.Supplier
+	if err := c.ShouldBindJSON(&supplier); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := s.purchaseService.CreateSupplier(c.Request.Context(), &supplier)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, supplier)
+}
+
+func (s *Server) handleUpdateSupplier(c *gin.Context) {
+	var supplier models.Supplier
+	if err := c.ShouldBindJSON(&supplier); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set the ID from the URL parameter
+	supplier.ID = uint(parseUint(c.Param("id")))
+
+	err := s.purchaseService.UpdateSupplier(c.Request.Context(), &supplier)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, supplier)
+}
+
+func (s *Server) handleDeleteSupplier(c *gin.Context) {
+	err := s.purchaseService.DeleteSupplier(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Supplier deleted successfully"})
+}
+
+// Purchase order handlers
+func (s *Server) handleGetPurchaseOrders(c *gin.Context) {
+	var status *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+
+	orders, err := s.purchaseService.GetPurchaseOrders(c.Request.Context(), status, parseOptionalUint(c.Query("supplierId")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+func (s *Server) handleGetPurchaseOrder(c *gin.Context) {
+	order, err := s.purchaseService.GetPurchaseOrder(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleCreatePurchaseOrder(c *gin.Context) {
+	var req PurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order := req.toPurchaseOrder()
+	err := s.purchaseService.CreatePurchaseOrder(c.Request.Context(), &order, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+func (s *Server) handleUpdatePurchaseOrder(c *gin.Context) {
+	var req PurchaseOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order := req.toPurchaseOrder()
+	order.ID = uint(parseUint(c.Param("id")))
+
+	err := s.purchaseService.UpdatePurchaseOrder(c.Request.Context(), &order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleDeletePurchaseOrder(c *gin.Context) {
+	err := s.purchaseService.DeletePurchaseOrder(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Purchase order deleted successfully"})
+}
+
+func (s *Server) handleApprovePurchaseOrder(c *gin.Context) {
+	order, err := s.purchaseService.ApprovePurchaseOrder(c.Request.Context(), uint(parseUint(c.Param("id"))), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleReceivePurchaseOrder(c *gin.Context) {
+	// An empty body receives everything outstanding
+	var req PurchaseReceiptRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	lines := make([]usecases.PurchaseReceiptLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		lines = append(lines, usecases.PurchaseReceiptLine{
+			LineID:     line.LineID,
+			Quantity:   line.Quantity,
+			LotNumber:  line.LotNumber,
+			ExpiryDate: line.ExpiryDate,
+			Serials:    line.Serials,
+		})
+	}
+
+	order, err := s.purchaseService.ReceivePurchaseOrder(c.Request.Context(), uint(parseUint(c.Param("id"))), lines, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleClosePurchaseOrder(c *gin.Context) {
+	order, err := s.purchaseService.ClosePurchaseOrder(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
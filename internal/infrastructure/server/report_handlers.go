@@ -0,0 +1,24 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) handleGetValuation(c *gin.Context) {
+	filter := models.ValuationFilter{
+		LocationID:  parseOptionalUint(c.Query("locationId")),
+		WarehouseID: parseOptionalUint(c.Query("warehouseId")),
+		CategoryID:  parseOptionalUint(c.Query("categoryId")),
+	}
+
+	report, err := s.valuationService.GetValuation(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
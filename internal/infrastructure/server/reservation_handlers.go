@@ -0,0 +1,76 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReservationRequest struct {
+	ProductID  uint            `json:"productId" binding:"required"`
+	LocationID uint            `json:"locationId" binding:"required"`
+	Quantity   models.Quantity `json:"quantity" binding:"required"`
+	Reference  string          `json:"reference"`
+	ExpiresAt  time.Time       `json:"expiresAt" binding:"required"`
+}
+
+func (s *Server) handleCreateReservation(c *gin.Context) {
+	var req ReservationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reservation := models.Reservation{
+		ProductID:  req.ProductID,
+		LocationID: req.LocationID,
+		Quantity:   req.Quantity,
+		Reference:  req.Reference,
+		ExpiresAt:  req.ExpiresAt,
+	}
+
+	err := s.reservationService.CreateReservation(c.Request.Context(), &reservation, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+func (s *Server) handleGetReservations(c *gin.Context) {
+	var status *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+
+	reservations, err := s.reservationService.GetReservations(c.Request.Context(), parseOptionalUint(c.Query("productId")), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reservations)
+}
+
+func (s *Server) handleGetReservation(c *gin.Context) {
+	reservation, err := s.reservationService.GetReservation(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+func (s *Server) handleReleaseReservation(c *gin.Context) {
+	err := s.reservationService.ReleaseReservation(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Reservation released successfully"})
+}
@@ -0,0 +1,159 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/usecases"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReturnRequest struct {
+	SalesOrderID      *uint  `json:"salesOrderId"`
+	MovementID        *uint  `json:"movementId"`
+	CustomerReference string `json:"customerReference"`
+	Reason            string `json:"reason"`
+	LocationID        uint   `json:"locationId" binding:"required"`
+	Lines             []struct {
+		ProductID uint            `json:"productId" binding:"required"`
+		Quantity  models.Quantity `json:"quantity" binding:"required"`
+	} `json:"lines" binding:"required,dive"`
+}
+
+type ReturnReceiptRequest struct {
+	Lines []struct {
+		LineID   uint            `json:"lineId" binding:"required"`
+		Quantity models.Quantity `json:"quantity" binding:"required"`
+	} `json:"lines" binding:"dive"`
+}
+
+type ReturnInspectionRequest struct {
+	Lines []struct {
+		LineID           uint            `json:"lineId" binding:"required"`
+		RestockQuantity  models.Quantity `json:"restockQuantity"`
+		WriteOffQuantity models.Quantity `json:"writeOffQuantity"`
+		Notes            string          `json:"notes"`
+		LotNumber        string          `json:"lotNumber"`
+		ExpiryDate       *time.Time      `json:"expiryDate"`
+		Serials          []string        `json:"serials"`
+	} `json:"lines" binding:"required,dive"`
+}
+
+func (s *Server) handleGetReturns(c *gin.Context) {
+	var status *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+
+	returns, err := s.returnService.GetReturns(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, returns)
+}
+
+func (s *Server) handleGetReturn(c *gin.Context) {
+	rma, err := s.returnService.GetReturn(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rma)
+}
+
+func (s *Server) handleCreateReturn(c *gin.Context) {
+	var req ReturnRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rma := models.ReturnAuthorization{
+		SalesOrderID:      req.SalesOrderID,
+		MovementID:        req.MovementID,
+		CustomerReference: req.CustomerReference,
+		Reason:            req.Reason,
+		LocationID:        req.LocationID,
+	}
+	for _, line := range req.Lines {
+		rma.Lines = append(rma.Lines, models.ReturnLine{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+		})
+	}
+
+	err := s.returnService.CreateReturn(c.Request.Context(), &rma, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, rma)
+}
+
+func (s *Server) handleReceiveReturn(c *gin.Context) {
+	// An empty body receives everything still expected
+	var req ReturnReceiptRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	receipts := make(map[uint]models.Quantity, len(req.Lines))
+	for _, line := range req.Lines {
+		receipts[line.LineID] += line.Quantity
+	}
+
+	rma, err := s.returnService.ReceiveReturn(c.Request.Context(), uint(parseUint(c.Param("id"))), receipts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rma)
+}
+
+func (s *Server) handleInspectReturn(c *gin.Context) {
+	var req ReturnInspectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	lines := make([]usecases.ReturnInspectionLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		lines = append(lines, usecases.ReturnInspectionLine{
+			LineID:           line.LineID,
+			RestockQuantity:  line.RestockQuantity,
+			WriteOffQuantity: line.WriteOffQuantity,
+			Notes:            line.Notes,
+			LotNumber:        line.LotNumber,
+			ExpiryDate:       line.ExpiryDate,
+			Serials:          line.Serials,
+		})
+	}
+
+	rma, err := s.returnService.InspectReturn(c.Request.Context(), uint(parseUint(c.Param("id"))), lines, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rma)
+}
+
+func (s *Server) handleCancelReturn(c *gin.Context) {
+	rma, err := s.returnService.CancelReturn(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, rma)
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+	"stock-management/internal/domain/usecases"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SalesOrderLineRequest struct {
+	ProductID uint            `json:"productId" binding:"required"`
+	Quantity  models.Quantity `json:"quantity" binding:"required"`
+}
+
+type SalesOrderRequest struct {
+	CustomerReference string                  `json:"customerReference" binding:"required"`
+	CustomerName      string                  `json:"customerName"`
+	WarehouseID       *uint                   `json:"warehouseId"`
+	Notes             string                  `json:"notes"`
+	Lines             []SalesOrderLineRequest `json:"lines" binding:"required,dive"`
+}
+
+func (req *SalesOrderRequest) toSalesOrder() models.SalesOrder {
+	order := models.SalesOrder{
+		CustomerReference: req.CustomerReference,
+		CustomerName:      req.CustomerName,
+		WarehouseID:       req.WarehouseID,
+		Notes:             req.Notes,
+	}
+	for _, line := range req.Lines {
+		order.Lines = append(order.Lines, models.SalesOrderLine{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+		})
+	}
+	return order
+}
+
+type ShipmentRequest struct {
+	Lines []struct {
+		LineID     uint            `json:"lineId" binding:"required"`
+		LocationID uint            `json:"locationId"`
+		Quantity   models.Quantity `json:"quantity" binding:"required"`
+		Serials    []string        `json:"serials"`
+	} `json:"lines" binding:"dive"`
+}
+
+func (s *Server) handleGetSalesOrders(c *gin.Context) {
+	var status, customerReference *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+	if value := c.Query("customerReference"); value != "" {
+		customerReference = &value
+	}
+
+	orders, err := s.salesService.GetSalesOrders(c.Request.Context(), status, customerReference)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, orders)
+}
+
+func (s *Server) handleGetSalesOrder(c *gin.Context) {
+	order, err := s.salesService.GetSalesOrder(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleGetPickLists(c *gin.Context) {
+	pickLists, err := s.salesService.GetPickLists(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, pickLists)
+}
+
+func (s *Server) handleCreateSalesOrder(c *gin.Context) {
+	var req SalesOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order := req.toSalesOrder()
+	err := s.salesService.CreateSalesOrder(c.Request.Context(), &order, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+func (s *Server) handleUpdateSalesOrder(c *gin.Context) {
+	var req SalesOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	order := req.toSalesOrder()
+	order.ID = uint(parseUint(c.Param("id")))
+
+	err := s.salesService.UpdateSalesOrder(c.Request.Context(), &order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleDeleteSalesOrder(c *gin.Context) {
+	err := s.salesService.DeleteSalesOrder(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sales order deleted successfully"})
+}
+
+func (s *Server) handleConfirmSalesOrder(c *gin.Context) {
+	order, err := s.salesService.ConfirmSalesOrder(c.Request.Context(), uint(parseUint(c.Param("id"))), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleReserveSalesOrder(c *gin.Context) {
+	order, err := s.salesService.ReserveSalesOrder(c.Request.Context(), uint(parseUint(c.Param("id"))), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleShipSalesOrder(c *gin.Context) {
+	// An empty body ships everything outstanding
+	var req ShipmentRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	lines := make([]usecases.SalesShipmentLine, 0, len(req.Lines))
+	for _, line := range req.Lines {
+		lines = append(lines, usecases.SalesShipmentLine{
+			LineID:     line.LineID,
+			LocationID: line.LocationID,
+			Quantity:   line.Quantity,
+			Serials:    line.Serials,
+		})
+	}
+
+	order, err := s.salesService.ShipSalesOrder(c.Request.Context(), uint(parseUint(c.Param("id"))), lines, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+func (s *Server) handleCancelSalesOrder(c *gin.Context) {
+	order, err := s.salesService.CancelSalesOrder(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"stock-management/internal/domain/models"
 	"stock-management/internal/domain/services"
 	"stock-management/internal/domain/usecases"
 	"time"
@@ -11,20 +12,68 @@ import (
 )
 
 type Server struct {
-	db           *gorm.DB
-	router       *gin.Engine
-	authService  *usecases.AuthService
-	stockService *usecases.StockService
-	jwtService   services.JWTService
+	db                 *gorm.DB
+	router             *gin.Engine
+	authService        *usecases.AuthService
+	stockService       *usecases.StockService
+	transferService    *usecases.TransferService
+	idempotencyService *usecases.IdempotencyService
+	reservationService *usecases.ReservationService
+	alertService       *usecases.AlertService
+	purchaseService    *usecases.PurchaseService
+	salesService       *usecases.SalesService
+	returnService      *usecases.ReturnService
+	countService       *usecases.CountService
+	adjustmentService  *usecases.AdjustmentService
+	valuationService   *usecases.ValuationService
+	periodService      *usecases.PeriodService
+	variantService     *usecases.VariantService
+	kitService         *usecases.KitService
+	barcodeService     *usecases.BarcodeService
+	jwtService         services.JWTService
 }
 
-func NewServer(db *gorm.DB, authService *usecases.AuthService, stockService *usecases.StockService, jwtService services.JWTService) *Server {
+// Services holds the use cases the handlers call into.
+type Services struct {
+	Auth        *usecases.AuthService
+	Stock       *usecases.StockService
+	Transfer    *usecases.TransferService
+	Idempotency *usecases.IdempotencyService
+	Reservation *usecases.ReservationService
+	Alert       *usecases.AlertService
+	Purchase    *usecases.PurchaseService
+	Sales       *usecases.SalesService
+	Return      *usecases.ReturnService
+	Count       *usecases.CountService
+	Adjustment  *usecases.AdjustmentService
+	Valuation   *usecases.ValuationService
+	Period      *usecases.PeriodService
+	Variant     *usecases.VariantService
+	Kit         *usecases.KitService
+	Barcode     *usecases.BarcodeService
+}
+
+func NewServer(db *gorm.DB, jwtService services.JWTService, svc Services) *Server {
 	server := &Server{
-		db:           db,
-		router:       gin.Default(),
-		authService:  authService,
-		stockService: stockService,
-		jwtService:   jwtService,
+		db:                 db,
+		router:             gin.Default(),
+		authService:        svc.Auth,
+		stockService:       svc.Stock,
+		transferService:    svc.Transfer,
+		idempotencyService: svc.Idempotency,
+		reservationService: svc.Reservation,
+		alertService:       svc.Alert,
+		purchaseService:    svc.Purchase,
+		salesService:       svc.Sales,
+		returnService:      svc.Return,
+		countService:       svc.Count,
+		adjustmentService:  svc.Adjustment,
+		valuationService:   svc.Valuation,
+		periodService:      svc.Period,
+		variantService:     svc.Variant,
+		kitService:         svc.Kit,
+		barcodeService:     svc.Barcode,
+		jwtService:         jwtService,
 	}
 
 	server.setupCORS()
@@ -36,8 +85,8 @@ func (s *Server) setupCORS() {
 	s.router.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"http://localhost:4200"}, // Angular default port
 		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Length", "Content-Type", "Authorization", "Idempotency-Key"},
+		ExposeHeaders:    []string{"Content-Length", "Idempotent-Replayed"},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
@@ -49,38 +98,229 @@ func (s *Server) setupRoutes() {
 	{
 		auth.POST("/login", s.handleLogin)
 		auth.POST("/register", s.handleRegister)
+		auth.POST("/refresh", s.handleRefresh)
 		auth.POST("/logout", s.handleLogout)
 	}
 
+	// User administration routes
+	users := s.router.Group("/api/users")
+	users.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		users.PUT("/:id/role", RequireRole(models.RoleAdmin), s.handleSetUserRole)
+	}
+
 	// Product routes
 	products := s.router.Group("/api/products")
-	products.Use(AuthMiddleware(s.jwtService))
+	products.Use(AuthMiddleware(s.jwtService, s.authService))
 	{
-		products.GET("", s.handleGetProducts)
-		products.POST("", s.handleCreateProduct)
-		products.PUT("/:id", s.handleUpdateProduct)
-		products.DELETE("/:id", s.handleDeleteProduct)
+		products.GET("", RequireRole(models.RoleViewer), s.handleGetProducts)
+		products.GET("/lookup", RequireRole(models.RoleViewer), s.handleLookupBarcode)
+		products.POST("/labels", RequireRole(models.RoleClerk), s.handlePrintLabels)
+		products.GET("/:id/lots", RequireRole(models.RoleViewer), s.handleGetProductLots)
+		products.GET("/:id/serials", RequireRole(models.RoleViewer), s.handleGetProductSerials)
+		products.GET("/:id/serials/:serial/history", RequireRole(models.RoleViewer), s.handleGetSerialHistory)
+		products.GET("/:id/units", RequireRole(models.RoleViewer), s.handleGetProductUnits)
+		products.POST("/:id/units", RequireRole(models.RoleManager), s.handleSetProductUnit)
+		products.DELETE("/:id/units/:unitId", RequireRole(models.RoleManager), s.handleDeleteProductUnit)
+		products.GET("/:id/attributes", RequireRole(models.RoleViewer), s.handleGetProductAttributes)
+		products.POST("/:id/attributes", RequireRole(models.RoleManager), s.handleCreateProductAttribute)
+		products.POST("/:id/attributes/:attributeId/values", RequireRole(models.RoleManager), s.handleAddAttributeValue)
+		products.GET("/:id/variants", RequireRole(models.RoleViewer), s.handleGetVariants)
+		products.POST("/:id/variants", RequireRole(models.RoleManager), s.handleGenerateVariants)
+		products.GET("/:id/components", RequireRole(models.RoleViewer), s.handleGetKit)
+		products.PUT("/:id/components", RequireRole(models.RoleManager), s.handleSetKitComponents)
+		products.POST("/:id/assemble", RequireRole(models.RoleClerk), IdempotencyMiddleware(s.idempotencyService), s.handleAssembleKit)
+		products.POST("/:id/disassemble", RequireRole(models.RoleClerk), IdempotencyMiddleware(s.idempotencyService), s.handleDisassembleKit)
+		products.GET("/:id/barcodes", RequireRole(models.RoleViewer), s.handleGetBarcodes)
+		products.POST("/:id/barcodes", RequireRole(models.RoleManager), s.handleAddBarcode)
+		products.DELETE("/:id/barcodes/:barcodeId", RequireRole(models.RoleManager), s.handleDeleteBarcode)
+		products.POST("", RequireRole(models.RoleManager), s.handleCreateProduct)
+		products.PUT("/:id", RequireRole(models.RoleManager), s.handleUpdateProduct)
+		products.DELETE("/:id", RequireRole(models.RoleAdmin), s.handleDeleteProduct)
 	}
 
 	// Category routes
 	categories := s.router.Group("/api/categories")
-	categories.Use(AuthMiddleware(s.jwtService))
+	categories.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		categories.GET("", RequireRole(models.RoleViewer), s.handleGetCategories)
+		categories.POST("", RequireRole(models.RoleManager), s.handleCreateCategory)
+		categories.PUT("/:id", RequireRole(models.RoleManager), s.handleUpdateCategory)
+		categories.DELETE("/:id", RequireRole(models.RoleAdmin), s.handleDeleteCategory)
+	}
+
+	// Reason code routes
+	reasonCodes := s.router.Group("/api/reason-codes")
+	reasonCodes.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		reasonCodes.GET("", RequireRole(models.RoleViewer), s.handleGetReasonCodes)
+		reasonCodes.POST("", RequireRole(models.RoleManager), s.handleCreateReasonCode)
+		reasonCodes.PUT("/:id", RequireRole(models.RoleManager), s.handleUpdateReasonCode)
+	}
+
+	// Unit of measure routes
+	units := s.router.Group("/api/units")
+	units.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		units.GET("", RequireRole(models.RoleViewer), s.handleGetUnits)
+		units.POST("", RequireRole(models.RoleManager), s.handleCreateUnit)
+		units.PUT("/:id", RequireRole(models.RoleManager), s.handleUpdateUnit)
+	}
+
+	// Warehouse routes
+	warehouses := s.router.Group("/api/warehouses")
+	warehouses.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		warehouses.GET("", RequireRole(models.RoleViewer), s.handleGetWarehouses)
+		warehouses.POST("", RequireRole(models.RoleManager), s.handleCreateWarehouse)
+		warehouses.PUT("/:id", RequireRole(models.RoleManager), s.handleUpdateWarehouse)
+		warehouses.DELETE("/:id", RequireRole(models.RoleAdmin), s.handleDeleteWarehouse)
+	}
+
+	// Location routes
+	locations := s.router.Group("/api/locations")
+	locations.Use(AuthMiddleware(s.jwtService, s.authService))
 	{
-		categories.GET("", s.handleGetCategories)
-		categories.POST("", s.handleCreateCategory)
-		categories.PUT("/:id", s.handleUpdateCategory)
-		categories.DELETE("/:id", s.handleDeleteCategory)
+		locations.GET("", RequireRole(models.RoleViewer), s.handleGetLocations)
+		locations.POST("", RequireRole(models.RoleManager), s.handleCreateLocation)
+		locations.PUT("/:id", RequireRole(models.RoleManager), s.handleUpdateLocation)
+		locations.DELETE("/:id", RequireRole(models.RoleAdmin), s.handleDeleteLocation)
 	}
 
 	// Stock routes
 	stock := s.router.Group("/api/stock")
-	stock.Use(AuthMiddleware(s.jwtService))
+	stock.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		stock.POST("/import", RequireRole(models.RoleClerk), IdempotencyMiddleware(s.idempotencyService), s.handleImportStock)
+		stock.POST("/export", RequireRole(models.RoleClerk), IdempotencyMiddleware(s.idempotencyService), s.handleExportStock)
+		stock.GET("/current", RequireRole(models.RoleViewer), s.handleGetCurrentStock)
+		stock.POST("/movements", RequireRole(models.RoleViewer), s.handleGetStockMovements)
+		stock.POST("/movements/:id/reverse", RequireRole(models.RoleManager), s.handleReverseMovement)
+		stock.GET("/summary", RequireRole(models.RoleViewer), s.handleGetStockSummary)
+		stock.GET("/as-of", RequireRole(models.RoleViewer), s.handleGetStockAsOf)
+		stock.GET("/snapshots", RequireRole(models.RoleViewer), s.handleGetSnapshots)
+		stock.GET("/alerts", RequireRole(models.RoleViewer), s.handleGetLowStockAlerts)
+		stock.GET("/alerts/events", RequireRole(models.RoleViewer), s.handleGetAlertEvents)
+		stock.GET("/adjustments", RequireRole(models.RoleViewer), s.handleGetAdjustments)
+		stock.GET("/adjustments/:id", RequireRole(models.RoleViewer), s.handleGetAdjustment)
+		stock.POST("/adjustments", RequireRole(models.RoleClerk), IdempotencyMiddleware(s.idempotencyService), s.handleRequestAdjustment)
+		stock.POST("/adjustments/:id/approve", RequireRole(models.RoleManager), s.handleApproveAdjustment)
+		stock.POST("/adjustments/:id/reject", RequireRole(models.RoleManager), s.handleRejectAdjustment)
+	}
+
+	// Stock count routes
+	counts := s.router.Group("/api/counts")
+	counts.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		counts.GET("", RequireRole(models.RoleViewer), s.handleGetCountSessions)
+		counts.GET("/:id", RequireRole(models.RoleViewer), s.handleGetCountSession)
+		counts.GET("/:id/variances", RequireRole(models.RoleViewer), s.handleGetCountVariances)
+		counts.POST("", RequireRole(models.RoleManager), s.handleOpenCountSession)
+		counts.POST("/:id/entries", RequireRole(models.RoleClerk), s.handleRecordCounts)
+		counts.POST("/:id/review", RequireRole(models.RoleManager), s.handleReviewCountSession)
+		counts.POST("/:id/reopen", RequireRole(models.RoleManager), s.handleReopenCountSession)
+		counts.POST("/:id/approve", RequireRole(models.RoleManager), s.handleApproveCountLines)
+		counts.POST("/:id/post", RequireRole(models.RoleManager), s.handlePostCountSession)
+		counts.POST("/:id/cancel", RequireRole(models.RoleManager), s.handleCancelCountSession)
+	}
+
+	// Reorder rule routes
+	reorderRules := s.router.Group("/api/reorder-rules")
+	reorderRules.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		reorderRules.GET("", RequireRole(models.RoleViewer), s.handleGetReorderRules)
+		reorderRules.POST("", RequireRole(models.RoleManager), s.handleSaveReorderRule)
+		reorderRules.DELETE("/:id", RequireRole(models.RoleManager), s.handleDeleteReorderRule)
+	}
+
+	// Reservation routes
+	reservations := s.router.Group("/api/reservations")
+	reservations.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		reservations.GET("", RequireRole(models.RoleViewer), s.handleGetReservations)
+		reservations.GET("/:id", RequireRole(models.RoleViewer), s.handleGetReservation)
+		reservations.POST("", RequireRole(models.RoleClerk), s.handleCreateReservation)
+		reservations.POST("/:id/release", RequireRole(models.RoleClerk), s.handleReleaseReservation)
+	}
+
+	// Supplier routes
+	suppliers := s.router.Group("/api/suppliers")
+	suppliers.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		suppliers.GET("", RequireRole(models.RoleViewer), s.handleGetSuppliers)
+		suppliers.GET("/:id", RequireRole(models.RoleViewer), s.handleGetSupplier)
+		suppliers.POST("", RequireRole(models.RoleManager), s.handleCreateSupplier)
+		suppliers.PUT("/:id", RequireRole(models.RoleManager), s.handleUpdateSupplier)
+		suppliers.DELETE("/:id", RequireRole(models.RoleAdmin), s.handleDeleteSupplier)
+	}
+
+	// Purchase order routes
+	purchaseOrders := s.router.Group("/api/purchase-orders")
+	purchaseOrders.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		purchaseOrders.GET("", RequireRole(models.RoleViewer), s.handleGetPurchaseOrders)
+		purchaseOrders.GET("/:id", RequireRole(models.RoleViewer), s.handleGetPurchaseOrder)
+		purchaseOrders.POST("", RequireRole(models.RoleManager), s.handleCreatePurchaseOrder)
+		purchaseOrders.PUT("/:id", RequireRole(models.RoleManager), s.handleUpdatePurchaseOrder)
+		purchaseOrders.DELETE("/:id", RequireRole(models.RoleAdmin), s.handleDeletePurchaseOrder)
+		purchaseOrders.POST("/:id/approve", RequireRole(models.RoleManager), s.handleApprovePurchaseOrder)
+		purchaseOrders.POST("/:id/receive", RequireRole(models.RoleClerk), s.handleReceivePurchaseOrder)
+		purchaseOrders.POST("/:id/close", RequireRole(models.RoleManager), s.handleClosePurchaseOrder)
+	}
+
+	// Sales order routes
+	salesOrders := s.router.Group("/api/sales-orders")
+	salesOrders.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		salesOrders.GET("", RequireRole(models.RoleViewer), s.handleGetSalesOrders)
+		salesOrders.GET("/:id", RequireRole(models.RoleViewer), s.handleGetSalesOrder)
+		salesOrders.GET("/:id/pick-lists", RequireRole(models.RoleViewer), s.handleGetPickLists)
+		salesOrders.POST("", RequireRole(models.RoleClerk), s.handleCreateSalesOrder)
+		salesOrders.PUT("/:id", RequireRole(models.RoleClerk), s.handleUpdateSalesOrder)
+		salesOrders.DELETE("/:id", RequireRole(models.RoleAdmin), s.handleDeleteSalesOrder)
+		salesOrders.POST("/:id/confirm", RequireRole(models.RoleClerk), s.handleConfirmSalesOrder)
+		salesOrders.POST("/:id/reserve", RequireRole(models.RoleClerk), s.handleReserveSalesOrder)
+		salesOrders.POST("/:id/ship", RequireRole(models.RoleClerk), s.handleShipSalesOrder)
+		salesOrders.POST("/:id/cancel", RequireRole(models.RoleManager), s.handleCancelSalesOrder)
+	}
+
+	// Customer return routes
+	returns := s.router.Group("/api/returns")
+	returns.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		returns.GET("", RequireRole(models.RoleViewer), s.handleGetReturns)
+		returns.GET("/:id", RequireRole(models.RoleViewer), s.handleGetReturn)
+		returns.POST("", RequireRole(models.RoleClerk), s.handleCreateReturn)
+		returns.POST("/:id/receive", RequireRole(models.RoleClerk), s.handleReceiveReturn)
+		returns.POST("/:id/inspect", RequireRole(models.RoleClerk), s.handleInspectReturn)
+		returns.POST("/:id/cancel", RequireRole(models.RoleManager), s.handleCancelReturn)
+	}
+
+	// Transfer routes
+	transfers := s.router.Group("/api/transfers")
+	transfers.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		transfers.GET("", RequireRole(models.RoleViewer), s.handleGetTransfers)
+		transfers.GET("/:id", RequireRole(models.RoleViewer), s.handleGetTransfer)
+		transfers.POST("", RequireRole(models.RoleClerk), s.handleCreateTransfer)
+		transfers.POST("/:id/receive", RequireRole(models.RoleClerk), s.handleReceiveTransfer)
+		transfers.POST("/:id/cancel", RequireRole(models.RoleManager), s.handleCancelTransfer)
+	}
+
+	// Report routes
+	reports := s.router.Group("/api/reports")
+	reports.Use(AuthMiddleware(s.jwtService, s.authService))
+	{
+		reports.GET("/valuation", RequireRole(models.RoleViewer), s.handleGetValuation)
+	}
+
+	// Period close routes
+	periods := s.router.Group("/api/periods")
+	periods.Use(AuthMiddleware(s.jwtService, s.authService))
 	{
-		stock.POST("/import", s.handleImportStock)
-		stock.POST("/export", s.handleExportStock)
-		stock.GET("/current", s.handleGetCurrentStock)
-		stock.POST("/movements", s.handleGetStockMovements)
-		stock.GET("/summary", s.handleGetStockSummary)
+		periods.GET("", RequireRole(models.RoleViewer), s.handleGetClosedPeriods)
+		periods.POST("/close", RequireRole(models.RoleManager), s.handleClosePeriod)
+		periods.POST("/reopen", RequireRole(models.RoleAdmin), s.handleReopenPeriod)
 	}
 }
 
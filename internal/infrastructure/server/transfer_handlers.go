@@ -0,0 +1,120 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TransferLineRequest struct {
+	ProductID uint            `json:"productId" binding:"required"`
+	Quantity  models.Quantity `json:"quantity" binding:"required"`
+	Serials   []string        `json:"serials"`
+}
+
+type TransferRequest struct {
+	SourceLocationID      uint                  `json:"sourceLocationId" binding:"required"`
+	DestinationLocationID uint                  `json:"destinationLocationId" binding:"required"`
+	Notes                 string                `json:"notes"`
+	Lines                 []TransferLineRequest `json:"lines" binding:"required,dive"`
+}
+
+type TransferReceiptRequest struct {
+	Lines []struct {
+		LineID   uint            `json:"lineId" binding:"required"`
+		Quantity models.Quantity `json:"quantity" binding:"required"`
+		Serials  []string        `json:"serials"`
+	} `json:"lines" binding:"dive"`
+}
+
+func (s *Server) handleCreateTransfer(c *gin.Context) {
+	var req TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	transfer := models.StockTransfer{
+		SourceLocationID:      req.SourceLocationID,
+		DestinationLocationID: req.DestinationLocationID,
+		Notes:                 req.Notes,
+	}
+	for _, line := range req.Lines {
+		transfer.Lines = append(transfer.Lines, models.StockTransferLine{
+			ProductID: line.ProductID,
+			Quantity:  line.Quantity,
+			Serials:   line.Serials,
+		})
+	}
+
+	err := s.transferService.CreateTransfer(c.Request.Context(), &transfer, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, transfer)
+}
+
+func (s *Server) handleGetTransfers(c *gin.Context) {
+	var status *string
+	if value := c.Query("status"); value != "" {
+		status = &value
+	}
+
+	transfers, err := s.transferService.GetTransfers(c.Request.Context(), status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, transfers)
+}
+
+func (s *Server) handleGetTransfer(c *gin.Context) {
+	transfer, err := s.transferService.GetTransfer(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+func (s *Server) handleReceiveTransfer(c *gin.Context) {
+	// An empty body receives everything still in transit
+	var req TransferReceiptRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	receipts := make(map[uint]models.TransferReceipt, len(req.Lines))
+	for _, line := range req.Lines {
+		receipt := receipts[line.LineID]
+		receipt.Quantity += line.Quantity
+		receipt.Serials = append(receipt.Serials, line.Serials...)
+		receipts[line.LineID] = receipt
+	}
+
+	transfer, err := s.transferService.ReceiveTransfer(c.Request.Context(), uint(parseUint(c.Param("id"))), receipts, c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
+
+func (s *Server) handleCancelTransfer(c *gin.Context) {
+	transfer, err := s.transferService.CancelTransfer(c.Request.Context(), uint(parseUint(c.Param("id"))), c.GetUint("user_id"))
+	if err != nil {
+		c.JSON(stockErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, transfer)
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProductUnitRequest struct {
+	Unit   string          `json:"unit" binding:"required"`
+	Factor models.Quantity `json:"factor" binding:"required"`
+}
+
+func (s *Server) handleGetUnits(c *gin.Context) {
+	units, err := s.stockService.GetUnits(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, units)
+}
+
+func (s *Server) handleCreateUnit(c *gin.Context) {
+	var unit models.UnitOfMeasure
+	if err := c.ShouldBindJSON(&unit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := s.stockService.CreateUnit(c.Request.Context(), &unit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, unit)
+}
+
+func (s *Server) handleUpdateUnit(c *gin.Context) {
+	var unit models.UnitOfMeasure
+	if err := c.ShouldBindJSON(&unit); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Set the ID from the URL parameter
+	unit.ID = uint(parseUint(c.Param("id")))
+
+	err := s.stockService.UpdateUnit(c.Request.Context(), &unit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, unit)
+}
+
+func (s *Server) handleGetProductUnits(c *gin.Context) {
+	productUnits, err := s.stockService.GetProductUnits(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, productUnits)
+}
+
+func (s *Server) handleSetProductUnit(c *gin.Context) {
+	var req ProductUnitRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	productUnit, err := s.stockService.SetProductUnit(c.Request.Context(), uint(parseUint(c.Param("id"))), req.Unit, req.Factor)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, productUnit)
+}
+
+func (s *Server) handleDeleteProductUnit(c *gin.Context) {
+	err := s.stockService.DeleteProductUnit(c.Request.Context(), uint(parseUint(c.Param("id"))), uint(parseUint(c.Param("unitId"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Product unit deleted successfully"})
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"net/http"
+	"stock-management/internal/domain/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AttributeValueRequest struct {
+	Value    string `json:"value" binding:"required"`
+	Code     string `json:"code"`
+	Position int    `json:"position"`
+}
+
+type ProductAttributeRequest struct {
+	Name     string                  `json:"name" binding:"required"`
+	Position int                     `json:"position"`
+	Values   []AttributeValueRequest `json:"values" binding:"required,min=1,dive"`
+}
+
+func (req *AttributeValueRequest) toAttributeValue() models.ProductAttributeValue {
+	return models.ProductAttributeValue{Value: req.Value, Code: req.Code, Position: req.Position}
+}
+
+func (s *Server) handleGetProductAttributes(c *gin.Context) {
+	attributes, err := s.variantService.GetAttributes(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, attributes)
+}
+
+func (s *Server) handleCreateProductAttribute(c *gin.Context) {
+	var req ProductAttributeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	attribute := models.ProductAttribute{
+		ProductID: uint(parseUint(c.Param("id"))),
+		Name:      req.Name,
+		Position:  req.Position,
+	}
+	for _, value := range req.Values {
+		attribute.Values = append(attribute.Values, value.toAttributeValue())
+	}
+
+	if err := s.variantService.CreateAttribute(c.Request.Context(), &attribute); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, attribute)
+}
+
+func (s *Server) handleAddAttributeValue(c *gin.Context) {
+	var req AttributeValueRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	value := req.toAttributeValue()
+	value.AttributeID = uint(parseUint(c.Param("attributeId")))
+
+	err := s.variantService.AddAttributeValue(c.Request.Context(), uint(parseUint(c.Param("id"))), &value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, value)
+}
+
+func (s *Server) handleGetVariants(c *gin.Context) {
+	variants, err := s.variantService.GetVariants(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, variants)
+}
+
+func (s *Server) handleGenerateVariants(c *gin.Context) {
+	variants, err := s.variantService.GenerateVariants(c.Request.Context(), uint(parseUint(c.Param("id"))))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, variants)
+}